@@ -0,0 +1,168 @@
+// Package ast declares the types used to represent syntax trees for gcfg
+// configuration files.
+//
+// Note that the API for the ast package may change to accommodate new
+// features or implementation changes in gcfg.
+package ast
+
+import (
+	"strings"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+// All node types implement the Node interface.
+type Node interface {
+	Pos() token.Pos // position of first character belonging to the node
+	End() token.Pos // position of first character immediately after the node
+}
+
+// A Comment node represents a single ;-style or #-style comment.
+type Comment struct {
+	Start token.Pos // position of ';' or '#' starting the comment
+	Text  string    // comment text, including the initial ';' or '#'
+}
+
+func (c *Comment) Pos() token.Pos { return c.Start }
+func (c *Comment) End() token.Pos { return token.Pos(int(c.Start) + len(c.Text)) }
+
+// A CommentGroup represents a sequence of comments on consecutive lines, with
+// no other tokens or blank lines between them.
+//
+// Blank records the run of blank lines directly preceding the group, as for
+// Section; it is 0 for a comment following a node on the same line.
+type CommentGroup struct {
+	Blank int        // number of blank lines before the group
+	List  []*Comment // len(List) > 0
+}
+
+func (g *CommentGroup) Pos() token.Pos { return g.List[0].Pos() }
+func (g *CommentGroup) End() token.Pos { return g.List[len(g.List)-1].End() }
+
+// Text returns the text of the comment group, with the comment markers and
+// a single following space (if any) removed from each line, and the lines
+// separated by newlines. It returns the empty string for a nil group.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	lines := make([]string, len(g.List))
+	for i, c := range g.List {
+		s := c.Text[1:]
+		s = strings.TrimPrefix(s, " ")
+		lines[i] = strings.TrimRight(s, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// An Ident node represents a section or variable name.
+type Ident struct {
+	NamePos token.Pos // identifier position
+	Name    string    // identifier name
+}
+
+func (x *Ident) Pos() token.Pos { return x.NamePos }
+func (x *Ident) End() token.Pos { return token.Pos(int(x.NamePos) + len(x.Name)) }
+
+// A BasicLit node represents a subsection name or a variable value, as it
+// appears in the source (that is, quoted and escaped as needed).
+//
+// Value omits the carriage returns of the source, so it may be shorter than
+// the source text; ValueEnd, if valid, records where that text ends.
+type BasicLit struct {
+	ValuePos token.Pos // literal position
+	Value    string    // literal string; e.g. `"sub"` or `value ; not a comment`
+	ValueEnd token.Pos // position immediately after the literal; or NoPos
+}
+
+func (x *BasicLit) Pos() token.Pos { return x.ValuePos }
+func (x *BasicLit) End() token.Pos {
+	if x.ValueEnd.IsValid() {
+		return x.ValueEnd
+	}
+	return token.Pos(int(x.ValuePos) + len(x.Value))
+}
+
+// A Section node represents a section, including its header and variables.
+//
+// Blank records the run of blank lines directly preceding the section (or
+// its Doc comment, if any), so that the visual grouping of the source can be
+// reproduced when printing; the same applies to Variable.
+type Section struct {
+	Blank   int           // number of blank lines before the node (and Doc)
+	Doc     *CommentGroup // associated documentation; or nil
+	Lbrack  token.Pos     // position of "["
+	Name    *Ident        // section name
+	Sub     *BasicLit     // subsection name; or nil
+	Rbrack  token.Pos     // position of "]"
+	Comment *CommentGroup // line comment after the header; or nil
+	Vars    []*Variable   // variables in the section
+}
+
+func (s *Section) Pos() token.Pos { return s.Lbrack }
+func (s *Section) End() token.Pos {
+	if n := len(s.Vars); n > 0 {
+		return s.Vars[n-1].End()
+	}
+	return s.Rbrack + 1
+}
+
+// A Variable node represents a variable definition; e.g. `name = value`, or
+// `name` for a "blank" value.
+type Variable struct {
+	Blank   int           // number of blank lines before the node (and Doc)
+	Doc     *CommentGroup // associated documentation; or nil
+	Name    *Ident        // variable name
+	Lkey    token.Pos     // position of "." or "[" before Key; or NoPos
+	Key     *Ident        // key for name.key or name[key]; or nil
+	Rkey    token.Pos     // position of "]" after Key; or NoPos
+	TokPos  token.Pos     // position of the assignment operator; or NoPos
+	Tok     token.Token   // assignment operator (ASSIGN or ADD); or ILLEGAL
+	Value   *BasicLit     // value; or nil for a "blank" value
+	Comment *CommentGroup // line comment; or nil
+
+	// Segments holds the quoted strings and the unquoted text between
+	// them that make up Value (so that their concatenation is Value), if
+	// requested from the parser; otherwise it is nil.
+	Segments []*BasicLit
+}
+
+func (v *Variable) Pos() token.Pos { return v.Name.Pos() }
+func (v *Variable) End() token.Pos {
+	switch {
+	case v.Value != nil:
+		return v.Value.End()
+	case v.Rkey.IsValid():
+		return v.Rkey + 1
+	case v.Key != nil:
+		return v.Key.End()
+	}
+	return v.Name.End()
+}
+
+// A File node represents a gcfg configuration file.
+//
+// Together with the positions of the nodes, the comments (including those
+// not associated with any node) and the blank line counts, the syntax tree
+// retains the content of the source apart from the whitespace within lines,
+// so that tools such as formatters and editors can rewrite a file without
+// losing anything.
+type File struct {
+	Sections []*Section      // sections in the file
+	Comments []*CommentGroup // list of all comments in the file
+	Blank    int             // number of blank lines at the end of the file
+}
+
+func (f *File) Pos() token.Pos {
+	if len(f.Sections) > 0 {
+		return f.Sections[0].Pos()
+	}
+	return token.NoPos
+}
+
+func (f *File) End() token.Pos {
+	if n := len(f.Sections); n > 0 {
+		return f.Sections[n-1].End()
+	}
+	return token.NoPos
+}
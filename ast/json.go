@@ -0,0 +1,161 @@
+package ast
+
+import (
+	"encoding/json"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+// The JSON representation of a syntax tree, as produced by MarshalJSON. Node
+// positions are resolved through the file set, so that the output can be
+// consumed without access to it. Optional nodes are omitted when nil.
+type (
+	jsonPos struct {
+		Offset int `json:"offset"`
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}
+	jsonComment struct {
+		Pos  jsonPos `json:"pos"`
+		Text string  `json:"text"`
+	}
+	jsonCommentGroup struct {
+		Blank int           `json:"blank,omitempty"`
+		List  []jsonComment `json:"list"`
+		Text  string        `json:"text"`
+	}
+	jsonLit struct {
+		Pos   jsonPos `json:"pos"`
+		Value string  `json:"value"`
+	}
+	jsonVariable struct {
+		Pos      jsonPos           `json:"pos"`
+		End      jsonPos           `json:"end"`
+		Blank    int               `json:"blank,omitempty"`
+		Doc      *jsonCommentGroup `json:"doc,omitempty"`
+		Name     jsonLit           `json:"name"`
+		Key      *jsonLit          `json:"key,omitempty"`
+		Index    bool              `json:"index,omitempty"`
+		Op       string            `json:"op,omitempty"`
+		Value    *jsonLit          `json:"value,omitempty"`
+		Comment  *jsonCommentGroup `json:"comment,omitempty"`
+		Segments []jsonLit         `json:"segments,omitempty"`
+	}
+	jsonSection struct {
+		Pos     jsonPos           `json:"pos"`
+		End     jsonPos           `json:"end"`
+		Blank   int               `json:"blank,omitempty"`
+		Doc     *jsonCommentGroup `json:"doc,omitempty"`
+		Name    jsonLit           `json:"name"`
+		Sub     *jsonLit          `json:"sub,omitempty"`
+		Comment *jsonCommentGroup `json:"comment,omitempty"`
+		Vars    []jsonVariable    `json:"vars"`
+	}
+	jsonFile struct {
+		Filename string             `json:"filename,omitempty"`
+		Sections []jsonSection      `json:"sections"`
+		Comments []jsonCommentGroup `json:"comments,omitempty"`
+		Blank    int                `json:"blank,omitempty"`
+	}
+)
+
+// MarshalJSON returns the JSON encoding of the syntax tree f, with positions
+// (offset, 1-based line and column) resolved through fset. It is meant for
+// tooling such as linters and editors that consume the parse result without
+// reimplementing the grammar; the format is:
+//
+//	{"filename": ..., "sections": [{"pos", "end", "blank", "doc", "name",
+//	  "sub", "comment", "vars": [{"pos", "end", "blank", "doc", "name",
+//	  "key", "index", "op", "value", "comment", "segments"}]}],
+//	  "comments": [...], "blank": ...}
+//
+// where names, keys, values and value segments are objects with "pos" and "value" (the text
+// as it appears in the source), "index" is true for a key in brackets (as in
+// name[key]), and comment groups have "blank", "list" (of objects with "pos"
+// and "text") and "text" (as returned by CommentGroup.Text).
+func MarshalJSON(fset *token.FileSet, f *File) ([]byte, error) {
+	e := jsonEncoder{fset}
+	jf := jsonFile{Sections: []jsonSection{}, Blank: f.Blank}
+	if p := f.Pos(); p.IsValid() {
+		jf.Filename = fset.Position(p).Filename
+	}
+	for _, s := range f.Sections {
+		jf.Sections = append(jf.Sections, e.section(s))
+	}
+	for _, g := range f.Comments {
+		jf.Comments = append(jf.Comments, *e.commentGroup(g))
+	}
+	return json.Marshal(jf)
+}
+
+type jsonEncoder struct {
+	fset *token.FileSet
+}
+
+func (e jsonEncoder) pos(p token.Pos) jsonPos {
+	pp := e.fset.Position(p)
+	return jsonPos{pp.Offset, pp.Line, pp.Column}
+}
+
+func (e jsonEncoder) commentGroup(g *CommentGroup) *jsonCommentGroup {
+	if g == nil {
+		return nil
+	}
+	jg := &jsonCommentGroup{Blank: g.Blank, Text: g.Text()}
+	for _, c := range g.List {
+		jg.List = append(jg.List, jsonComment{e.pos(c.Start), c.Text})
+	}
+	return jg
+}
+
+func (e jsonEncoder) ident(x *Ident) *jsonLit {
+	if x == nil {
+		return nil
+	}
+	return &jsonLit{e.pos(x.NamePos), x.Name}
+}
+
+func (e jsonEncoder) lit(x *BasicLit) *jsonLit {
+	if x == nil {
+		return nil
+	}
+	return &jsonLit{e.pos(x.ValuePos), x.Value}
+}
+
+func (e jsonEncoder) section(s *Section) jsonSection {
+	js := jsonSection{
+		Pos:     e.pos(s.Pos()),
+		End:     e.pos(s.End()),
+		Blank:   s.Blank,
+		Doc:     e.commentGroup(s.Doc),
+		Name:    *e.ident(s.Name),
+		Sub:     e.lit(s.Sub),
+		Comment: e.commentGroup(s.Comment),
+		Vars:    []jsonVariable{},
+	}
+	for _, v := range s.Vars {
+		js.Vars = append(js.Vars, e.variable(v))
+	}
+	return js
+}
+
+func (e jsonEncoder) variable(v *Variable) jsonVariable {
+	jv := jsonVariable{
+		Pos:     e.pos(v.Pos()),
+		End:     e.pos(v.End()),
+		Blank:   v.Blank,
+		Doc:     e.commentGroup(v.Doc),
+		Name:    *e.ident(v.Name),
+		Key:     e.ident(v.Key),
+		Index:   v.Rkey.IsValid(),
+		Value:   e.lit(v.Value),
+		Comment: e.commentGroup(v.Comment),
+	}
+	if v.TokPos.IsValid() {
+		jv.Op = v.Tok.String()
+	}
+	for _, x := range v.Segments {
+		jv.Segments = append(jv.Segments, *e.lit(x))
+	}
+	return jv
+}
@@ -0,0 +1,67 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/parser"
+	"gopkg.in/gcfg.v1/token"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	src := "; doc\n[sect \"sub\"]\n\nname.key = value ; line\nblank[0]\n\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.gcfg", []byte(src), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ast.MarshalJSON(fset, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		Filename string
+		Sections []struct {
+			Doc  struct{ Text string }
+			Name struct{ Value string }
+			Sub  struct{ Value string }
+			Vars []struct {
+				Pos     struct{ Line, Column int }
+				Blank   int
+				Name    struct{ Value string }
+				Key     *struct{ Value string }
+				Index   bool
+				Op      string
+				Value   *struct{ Value string }
+				Comment *struct{ Text string }
+			}
+		}
+		Comments []struct{ Text string }
+		Blank    int
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	if res.Filename != "test.gcfg" || len(res.Sections) != 1 ||
+		len(res.Comments) != 2 || res.Blank != 1 {
+		t.Fatalf("unexpected result %s", b)
+	}
+	s := res.Sections[0]
+	if s.Doc.Text != "doc" || s.Name.Value != "sect" || s.Sub.Value != `"sub"` ||
+		len(s.Vars) != 2 {
+		t.Fatalf("unexpected section %s", b)
+	}
+	v := s.Vars[0]
+	if v.Pos.Line != 4 || v.Pos.Column != 1 || v.Blank != 1 ||
+		v.Name.Value != "name" || v.Key == nil || v.Key.Value != "key" || v.Index ||
+		v.Op != "=" || v.Value == nil || v.Value.Value != "value" ||
+		v.Comment == nil || v.Comment.Text != "line" {
+		t.Errorf("unexpected variable %s", b)
+	}
+	v = s.Vars[1]
+	if v.Name.Value != "blank" || v.Key == nil || v.Key.Value != "0" || !v.Index ||
+		v.Op != "" || v.Value != nil {
+		t.Errorf("unexpected blank variable %s", b)
+	}
+}
@@ -26,6 +26,13 @@
 //      - subsection: '[sec "A"]' -> '[sec "B"]' -> '[sec "A"]' is an error
 //      - multivalued variable: 'multi=a' -> 'other=x' -> 'multi=b' is an error
 //
+// As in git config, the value of a variable is everything after the first
+// '=' up to a comment (starting with an unquoted ';' or '#') or the end of
+// the line, without leading and trailing whitespace; any further '='
+// characters are part of the value, e.g. `query = a=b` sets "query" to "a=b".
+// Double quotes and backslash escapes within the value are processed as in
+// git config, unless reading with the LiteralValues option.
+//
 // Data structure
 //
 // The functions in this package read values into a user-defined struct.
@@ -38,6 +45,8 @@
 // Fields must be exported; to use a section or variable name starting with a
 // letter that is neither upper- or lower-case, prefix the field name with 'X'.
 // (See https://code.google.com/p/go/issues/detail?id=5763#c4 .)
+// Names are matched using Unicode case folding; the NameFolding option of
+// ReadWithOptions selects a different folding (e.g. ASCII only).
 //
 // For sections with subsections, the corresponding field in config must be a
 // map, rather than a struct, with string keys and pointer-to-struct values.
@@ -56,6 +65,20 @@
 // or when a field is not of a suitable type (either a struct or a map with
 // string keys and pointer-to-struct values).
 //
+// Using the Platform option of ReadWithOptions, sections without subsections
+// can have platform-specific subsections (e.g. `[paths "windows"]`), whose
+// values are used only on the matching platform.
+//
+// Tools that only need key-value access can instead use Parse, which reads the
+// data into a File without a config struct, with methods to get and set the
+// values by name, preserving the order of the sections and definitions.
+// UpdateFile (or SetInFile, with keys as used by git config, such as
+// "remote.origin.url") changes a single value in a file in place, and
+// UnsetInFile removes a variable, leaving the rest of the file (including
+// comments and formatting) as it is. Get and Set access the values of a
+// config struct by such keys, for tools that don't know its type, and ToJSON
+// and FromJSON convert gcfg data to and from JSON.
+//
 // Parsing of values
 //
 // The section structs in the config struct may contain single-valued or
@@ -72,31 +95,169 @@
 // the recommended way for parsing user-defined types.
 //
 // For fields of string kind, the value string is assigned to the field, after
-// unquoting and unescaping as needed.
+// unquoting and unescaping as needed (see Unquote, which performs the same
+// processing for use by other tools).
 // For fields of bool kind, the field is set to true if the value is "true",
 // "yes", "on" or "1", and set to false if the value is "false", "no", "off" or
 // "0", ignoring case. In addition, single-valued bool fields can be specified
 // with a "blank" value (variable name without equals sign and value); in such
 // case the value is set to true.
+// A blank value for a single-valued variable of any other type is an error,
+// unless the field has the struct tag option ",implicit=value"; in such case
+// the variable is set as if specified with the given value. For example, with
+// `gcfg:",implicit=debug"` the line "loglevel" is equivalent to
+// "loglevel = debug". The option applies to bool fields too (overriding the
+// implicit true), and to multi-valued variables, where the implicit value is
+// appended instead of resetting the slice.
 //
 // Predefined integer types [u]int(|8|16|32|64) and big.Int are parsed as
 // decimal or hexadecimal (if having '0x' prefix). (This is to prevent
 // unintuitively handling zero-padded numbers as octal.) Other types having
 // [u]int* as the underlying type, such as os.FileMode and uintptr allow
-// decimal, hexadecimal, or octal values.
+// decimal, hexadecimal, or octal values. (For file permissions, the
+// types.FileMode type parses values as octal by default, and also accepts
+// symbolic modes such as "u=rw,go=r".)
 // Parsing mode for integer types can be overridden using the struct tag option
 // ",int=mode" where mode is a combination of the 'd', 'h', and 'o' characters
 // (each standing for decimal, hexadecimal, and octal, respectively.)
 //
-// All other types are parsed using fmt.Sscanf with the "%v" verb.
+// Values of type time.Duration are parsed using time.ParseDuration (e.g.
+// "1m30s"); for compatibility, integer values (nanoseconds) are also accepted.
+// Values of type big.Float are parsed using big.Float.Parse (e.g. "1.5" or
+// "0x1p-2"), with a precision of 64 bits unless the variable already has one.
+// The struct tag options ",min=value" and ",max=value" restrict the range of
+// duration and numeric (integer, floating-point, big.Int and big.Float)
+// variables; e.g. `gcfg:",min=1s,max=10m"`. These types can also be used as
+// the values of maps with the ",dotted" or ",any" struct tag option (see
+// below), with the struct tag options applying to each value; e.g. for quotas
+// or weights by key.
+//
+// Values of type url.URL are parsed using url.Parse. The struct tag option
+// ",schemes=list" restricts the allowed schemes to those in list (separated by
+// '|'; e.g. ",schemes=http|https"), and ",requirehost" rejects URLs without a
+// host.
+//
+// Values of type mail.Address (from the net/mail package) are parsed using
+// mail.ParseAddress. For variables of type []*mail.Address, each value is
+// parsed as a comma-separated list of addresses using mail.ParseAddressList,
+// and all addresses in the list are appended.
+//
+// All other types are parsed using fmt.Sscanf with the "%v" verb, unless
+// disabled using the StrictTypes option of ReadWithOptions.
+//
+// The struct tag option ",transform=list" applies the named transforms in list
+// (separated by '|') in order to each (non-blank) value before it is parsed,
+// to normalize values without defining a wrapper type; e.g.
+// `gcfg:",transform=trim|lower"`. The predefined transforms are "trim",
+// "lower" and "upper"; others can be added using RegisterTransform.
+//
+// The struct tag option ",setter=name" parses the values of a variable using
+// the setter registered as name using RegisterSetter instead of the above,
+// to parse a type differently for individual variables; e.g. an int64 holding
+// a duration in milliseconds.
+//
+// A variable of an interface type can hold one of several implementations,
+// selected by the value of another (string) variable of the section named by
+// the struct tag option ",kind=name". The implementations are registered for
+// each kind using RegisterKind, and the value is parsed into a new value of
+// the implementation as for any other type. For example, with
+//
+//	Backend Storage `gcfg:",kind=type"`
+//
+// and the implementations registered for the kinds "s3" and "local", the
+// lines `type = s3` and `backend = bucket/path` set Backend to the "s3"
+// implementation parsed from "bucket/path". The kind variable must be set
+// (by an earlier line, or in the config struct before reading) before the
+// variable it selects the implementation for.
+//
+// A variable that is not defined in the configuration data can take its value
+// from an environment variable, using the struct tag option ",env=NAME" where
+// NAME is the name of the environment variable. If it is set, its value is
+// parsed as above, as if the variable were defined with that value. (For
+// subsections, this applies only to the subsections that are defined.)
+// Otherwise, a variable whose field still has its zero value after reading
+// is set to the default value declared using the struct tag option
+// ",default=value", parsed in the same way.
+//
+// With the struct tag option ",required", reading fails if the variable is
+// not defined in the data (nor set from an environment variable or to a
+// default value as above), with an error wrapping ErrMissingRequired for each
+// such variable. For a section field, it requires the section (or for
+// sections with subsections, at least one subsection) to be present; the
+// required variables of a section with subsections are checked for each
+// subsection.
+//
+// For validation involving several variables, a section struct (or the whole
+// config struct) can implement the Validator interface: after all values are
+// set, the Validate method of each section and subsection (in the order of
+// the fields, and of the subsection names), and then of the config, is
+// called. An error returned by a section is reported as a ValueError at the
+// section or subsection; an error returned by the config is reported as is.
+//
+// With the struct tag option ",fromfile", the value is the name of a file,
+// and the contents of the file (with any trailing newlines removed) are
+// parsed as above instead. This is useful e.g. for secrets and certificates.
+//
+// With the struct tag option ",relto=config", a relative path value is
+// resolved against the directory of the file being read (if known; that is,
+// when using ReadFileInto or ReadFSInto), so that the configuration doesn't
+// depend on the working directory. When used together with ",fromfile", this
+// applies to the name of the file containing the value.
+//
+// Errors for values that can't be parsed include the variable and the value
+// as it appears in the data; with the struct tag option ",secret", the value
+// and the underlying error (which may include it) are redacted from the error
+// instead. Such values are also redacted when encoding with the Redact
+// option, or using Dump.
+//
+// A map field with string keys and the struct tag option ",dotted" holds an
+// open-ended set of values set using dotted variable names: `labels.team =
+// infra` sets the "team" key of the field for the variable "labels" (e.g.
+// Labels map[string]string). Keys are case sensitive, and consist of letters,
+// digits and hyphens. The map values are parsed as above; a "blank" value for
+// the variable name without a key (e.g. `labels`) resets the map. By default,
+// a key defined more than once is set to the last value; the DuplicateMapKeys
+// option of ReadWithOptions selects a different policy.
+//
+// Similarly, an unnamed slice field with the struct tag option ",indexed" is
+// set using indexed variable names, either `server.0 = a` or `server[0] = a`,
+// with each value stored at the given position in the slice. Each index can
+// only be defined once (unless the slice elements are multi-valued), and all
+// indexes up to the largest one must be defined.
+// A "blank" value for the variable name without an index resets the slice.
+//
+// A map field with string keys and the struct tag option ",any" in a section
+// struct (e.g. Extra map[string]string) holds the variables that don't match
+// any other field, instead of reporting them as unknown; the keys are the
+// variable names (in lower case, including the key of dotted and indexed
+// names), and the map values are parsed as above. This lets older binaries
+// accept configs written for newer ones. When encoding, the entries are
+// written as variables, sorted by name.
+//
+// Likewise, a field of the config struct with the struct tag option ",any"
+// holds the sections that don't match any other field, e.g. for plugins with
+// their own configuration; it must be a map with string keys and values that
+// are maps with string keys (e.g. Plugins map[string]map[string]string). The
+// keys are the section names in lower case, followed by "." and the
+// subsection name for subsections (e.g. `plugin.cache`), and the values hold
+// the variables of each section as above.
 //
 // For multi-valued variables, each individual value is parsed as above and
 // appended to the slice. If the first value is specified as a "blank" value
 // (variable name without equals sign and value), a new slice is allocated;
 // that is any values previously set in the slice will be ignored.
 //
+// When enabled using the AppendOperator option of ReadWithOptions, a variable
+// can also be defined using `name += value`, which appends a value to a
+// multi-valued variable, or appends to the current value of a string variable.
+//
 // The types subpackage for provides helpers for parsing "enum-like" and integer
-// types.
+// types, and the types.Version type for semantic versions. For variables of
+// type types.Version, the struct tag option ",minver=version" requires the
+// value to be at least the given version. Similarly, for variables of type
+// types.HostPort, the struct tag option ",port=number" sets the default port,
+// used for values without a port; and for variables of type types.CIDRList,
+// the struct tag option ",nooverlap" rejects overlapping prefixes.
 //
 // Error handling
 //
@@ -121,6 +282,25 @@
 // These errors are handled at a different (warning) priority and can be
 // filtered out programmatically. To ignore extra data warnings, wrap the
 // gcfg.Read*Into invocation into a call to gcfg.FatalOnly.
+// Alternatively, ReadWithOptions can be used with the WarningHandler option to
+// handle each warning individually (e.g. to log it), or to make it fatal.
+// When reading into several config structs using ReadIntoAll, data is only
+// considered extra if none of them defines it.
+// The UnknownData option of ReadWithOptions selects whether extra data is
+// handled as warnings (the default), ignored, or reported as fatal errors.
+// Regardless of these, unknown variables in a section can be made fatal errors
+// or ignored using the struct tag option ",strict" or ",lenient" on the field
+// for the section; e.g. to reject typos in the application's own section while
+// tolerating arbitrary variables in a free-form one.
+//
+// Data errors wrap one of the sentinel errors ErrSyntax, ErrUnknownSection,
+// ErrUnknownVariable and ErrUnsupportedType, which can be tested for using
+// errors.Is. Errors that refer to a specific place in the configuration data
+// include its position (line and column, and file name if known), and
+// FormatErrors can be used to print them together with the offending lines.
+// The details of the errors can be obtained using errors.As with the types
+// *SyntaxError, *ValueError and *UnknownFieldError, so that callers can react
+// to specific errors without matching error messages.
 //
 // TODO
 //
@@ -133,13 +313,9 @@
 //    - reconsider valid escape sequences
 //      (gitconfig doesn't support \r in value, \t in subsection name, etc.)
 //  - reading / parsing gcfg files
-//    - define internal representation structure
-//    - support multiple inputs (readers, strings, files)
 //    - support declaring encoding (?)
 //    - support varying fields sets for subsections (?)
-//  - writing gcfg files
 //  - error handling
-//    - make error context accessible programmatically?
-//    - limit input size?
+//    - limit total input size (across all values)?
 //
 package gcfg // import "gopkg.in/gcfg.v1"
@@ -0,0 +1,84 @@
+package gcfg
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Embedded returns an Option that makes reading extract the gcfg data
+// embedded in a larger file (such as a script or a template) between two
+// lines holding marker, so that config can be kept next to the artifact it
+// describes; see ExtractEmbedded. Embedded panics if marker is empty.
+func Embedded(marker string) Option {
+	if marker == "" {
+		panic(fmt.Errorf("empty marker for embedded data"))
+	}
+	return func(o *options) {
+		o.embedded = marker
+	}
+}
+
+// ExtractEmbedded returns the gcfg data embedded in src between the first two
+// lines holding marker (e.g. "---gcfg---"). The marker lines may start with a
+// prefix of whitespace and punctuation (e.g. "# " in a shell script, or "// "
+// in a Go file), which is then removed from the lines between them; each of
+// these lines must start with the prefix, be empty, or consist of the prefix
+// without trailing whitespace. For example, with the marker "---gcfg---":
+//
+//	#!/bin/sh
+//	# ---gcfg---
+//	# [deploy]
+//	# target = prod
+//	# ---gcfg---
+//	...
+//
+// The lines outside of the embedded data (and the marker lines) are returned
+// as empty lines, so that line numbers in reported errors refer to the
+// original data; column numbers refer to the lines without the prefix.
+func ExtractEmbedded(src []byte, marker string) ([]byte, error) {
+	lines := strings.Split(string(src), "\n")
+	out := make([]string, len(lines))
+	start := -1  // line of the opening marker
+	prefix := "" // prefix of the opening marker
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if p, ok := strings.CutSuffix(line, marker); ok && isMarkerPrefix(p) &&
+			(start < 0 || p == prefix) {
+			//
+			if start >= 0 {
+				return []byte(strings.Join(out, "\n")), nil
+			}
+			start, prefix = i, p
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, prefix):
+			out[i] = line[len(prefix):]
+		case line == "" || line == strings.TrimRight(prefix, " \t"):
+			// empty line
+		default:
+			return nil, fmt.Errorf("line %d: embedded data doesn't start "+
+				"with %q", i+1, prefix)
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no embedded data marked with %q", marker)
+	}
+	return nil, fmt.Errorf("line %d: embedded data marked with %q not "+
+		"terminated", start+1, marker)
+}
+
+// isMarkerPrefix reports whether s may precede the marker of embedded data;
+// that is, whether it consists of whitespace and punctuation only.
+func isMarkerPrefix(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package gcfg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtractEmbedded(t *testing.T) {
+	for _, tt := range []struct {
+		src, exp string
+		err      string
+	}{
+		{"#!/bin/sh\n# ---gcfg---\n# [a]\n#\n#   b = 1\n# ---gcfg---\necho\n",
+			"\n\n[a]\n\n  b = 1\n\n\n", ""},
+		{"---gcfg---\r\n[a]\r\n---gcfg---", "\n[a]\n", ""},
+		{"// ---gcfg---\n// [a]\n// ---gcfg---\n// ---gcfg---\n",
+			"\n[a]\n\n\n", ""},
+		{"x ---gcfg---\n", "", `no embedded data marked with "---gcfg---"`},
+		{"# ---gcfg---\n# [a]\nb = 1\n# ---gcfg---\n", "",
+			`line 3: embedded data doesn't start with "# "`},
+		{"\n# ---gcfg---\n# [a]\n", "",
+			`line 2: embedded data marked with "---gcfg---" not terminated`},
+	} {
+		b, err := ExtractEmbedded([]byte(tt.src), "---gcfg---")
+		switch {
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%q: got error %v, wanted %q", tt.src, err, tt.err)
+		case tt.err == "" && (err != nil || string(b) != tt.exp):
+			t.Errorf("%q: got %q, %v, wanted %q", tt.src, b, err, tt.exp)
+		}
+	}
+}
+
+func TestReadWithOptionsEmbedded(t *testing.T) {
+	src := "#!/bin/sh\n# ---gcfg---\n# [section]\n# name = value\n# int = x\n" +
+		"# ---gcfg---\nexit 0\n"
+	cfg := &cBasic{}
+	err := ReadWithOptions(cfg, strings.NewReader(src), Embedded("---gcfg---"),
+		PartialResults())
+	var ve *ValueError
+	if !errors.As(err, &ve) || ve.Pos.Line != 5 {
+		t.Errorf("got error %v, wanted value error on line 5", err)
+	}
+	if cfg.Section.Name != "value" {
+		t.Errorf("got %+v, wanted name set", cfg.Section)
+	}
+}
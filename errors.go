@@ -1,6 +1,76 @@
 package gcfg
 
-import warnings "gopkg.in/warnings.v0"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gcfg.v1/scanner"
+	"gopkg.in/gcfg.v1/token"
+	warnings "gopkg.in/warnings.v0"
+)
+
+// Sentinel errors wrapped by the errors returned from the Read*Into functions.
+// Use errors.Is to test for them, e.g.:
+//
+//  if errors.Is(err, gcfg.ErrSyntax) {
+//      ...
+//
+// Note that when only warnings (see FatalOnly) are returned, the returned
+// error is a warnings.List; the sentinels are wrapped by the individual
+// errors in the list rather than by the list itself.
+var (
+	// ErrSyntax is wrapped by errors caused by invalid configuration syntax.
+	ErrSyntax = errors.New("syntax error")
+	// ErrUnknownSection is wrapped by (warning) errors for sections or
+	// subsections that don't correspond to any field in the config struct.
+	ErrUnknownSection = errors.New("unknown section")
+	// ErrUnknownVariable is wrapped by (warning) errors for variables that
+	// don't correspond to any field in the section struct.
+	ErrUnknownVariable = errors.New("unknown variable")
+	// ErrInvalidUTF8 is wrapped by (warning) errors for bytes that are not
+	// valid UTF-8 and have been replaced; see LenientUTF8.
+	ErrInvalidUTF8 = errors.New("invalid UTF-8 encoding")
+	// ErrValueTooLong is wrapped by errors for values exceeding the
+	// maximum length; see MaxValueLength.
+	ErrValueTooLong = errors.New("value too long")
+	// ErrUnsupportedType is wrapped by errors for variables whose field type
+	// can't be set by any of the supported methods.
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrMissingRequired is wrapped by errors for sections and variables
+	// with the ",required" struct tag option that are not defined.
+	ErrMissingRequired = errors.New("missing required section or variable")
+	// ErrInconsistentUse is wrapped by (warning) errors for variables that
+	// are defined once in a file and several times in another; see
+	// ReadFilesInto.
+	ErrInconsistentUse = errors.New("variable used inconsistently")
+)
+
+// ErrorList is the type of the fatal error returned when reading continues past
+// fatal errors (see PartialResults); it lists all of them, in the order of
+// their positions in the data (errors without a position come last, in the
+// order they were encountered). errors.Is and errors.As consider all the
+// errors in the list.
+type ErrorList []error
+
+// Error returns the first error in the list, and the number of others.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Unwrap returns the errors in the list.
+func (l ErrorList) Unwrap() []error { return l }
 
 // FatalOnly filters the results of a Read*Into invocation and returns only
 // fatal errors. That is, errors (warnings) indicating data for unknown
@@ -14,26 +84,435 @@ func FatalOnly(err error) error {
 	return warnings.FatalOnly(err)
 }
 
-func isFatal(err error) bool {
-	_, ok := err.(extraData)
-	return !ok
+// FormatErrors writes the errors contained in errs to w, one error per entry,
+// in the style of compiler diagnostics: each error message is followed by the
+// line of src it refers to and a caret marking the offending column. Errors
+// without position information are written as plain messages.
+//
+// errs is typically the error returned by a Read*Into invocation, and src the
+// data that was read; both the warnings and the fatal error are included.
+func FormatErrors(w io.Writer, src []byte, errs error) error {
+	for _, err := range errorList(errs) {
+		if _, e := fmt.Fprintln(w, err); e != nil {
+			return e
+		}
+		pos, ok := errorPos(err)
+		if !ok {
+			continue
+		}
+		line, caret, ok := excerpt(src, pos)
+		if !ok {
+			continue
+		}
+		if _, e := fmt.Fprintf(w, "\t%s\n\t%s^\n", line, caret); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// errorList flattens the error lists that may be returned by a Read*Into
+// invocation into individual errors.
+func errorList(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case warnings.List:
+		var l []error
+		for _, w := range e.Warnings {
+			l = append(l, errorList(w)...)
+		}
+		return append(l, errorList(e.Fatal)...)
+	case ErrorList:
+		var l []error
+		for _, err := range e {
+			l = append(l, errorList(err)...)
+		}
+		return l
+	case *SubsectionError:
+		return errorList(e.Errs)
+	case scanner.ErrorList:
+		l := make([]error, len(e))
+		for i, se := range e {
+			l[i] = se
+		}
+		return l
+	}
+	return []error{err}
+}
+
+// errorPos returns the source position an error refers to, if known.
+func errorPos(err error) (token.Position, bool) {
+	var pos token.Position
+	switch e := err.(type) {
+	case *scanner.Error:
+		pos = e.Pos
+	case scanner.Error:
+		pos = e.Pos
+	case extraData:
+		pos = e.pos
+	case locErr:
+		pos = e.pos
+	case *SyntaxError:
+		pos = e.Pos
+	case *ValueError:
+		pos = e.Pos
+	case *UnknownFieldError:
+		pos = e.Pos
+	case *InconsistentUseError:
+		pos = e.Pos
+	case encodingErr:
+		pos = e.pos
+	case *SubsectionError:
+		return errorPos(e.Errs[0])
+	}
+	return pos, pos.IsValid()
+}
+
+// excerpt returns the source line containing pos, and the whitespace to print
+// before a caret to mark the column of pos on that line. Tabs on the line are
+// preserved in the caret prefix so that the caret lines up with the excerpt.
+func excerpt(src []byte, pos token.Position) (line, caret string, ok bool) {
+	if pos.Offset < 0 || pos.Offset > len(src) {
+		return "", "", false
+	}
+	start := bytes.LastIndexByte(src[:pos.Offset], '\n') + 1
+	end := bytes.IndexByte(src[pos.Offset:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += pos.Offset
+	}
+	b := bytes.TrimRight(src[start:end], "\r")
+	prefix := make([]rune, 0, pos.Offset-start)
+	for _, r := range string(src[start:pos.Offset]) {
+		if r != '\t' {
+			r = ' '
+		}
+		prefix = append(prefix, r)
+	}
+	return string(b), string(prefix), true
+}
+
+// Severity is the severity level of an error encountered while reading.
+type Severity int
+
+// Severity levels; see WarningHandler.
+const (
+	// SeverityInfo is for informational messages; these are not returned
+	// by the Read*Into functions unless escalated by a WarningHandler.
+	SeverityInfo Severity = iota
+	// SeverityWarning is for non-fatal errors, such as data that doesn't
+	// belong to any part of the config structure.
+	SeverityWarning
+	// SeverityError is for fatal errors.
+	SeverityError
+)
+
+var severities = [...]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// String returns the name of the severity level; e.g. "warning".
+func (s Severity) String() string {
+	if 0 <= s && int(s) < len(severities) {
+		return severities[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// severity returns the default severity of err.
+func severity(err error) Severity {
+	switch e := err.(type) {
+	case extraData:
+		switch {
+		case e.strict:
+			return SeverityError
+		case e.lenient:
+			return SeverityInfo
+		}
+		return SeverityWarning
+	case encodingErr:
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+// collector collects the errors encountered while reading, using the severity
+// determined by the warning handler (if any) to decide on fatality.
+type collector struct {
+	*warnings.Collector
+	handler func(err error, sev Severity) Severity
+	fatal   bool // fatality of the error being collected
+
+	warnings int // number of warnings collected
+	skipped  int // number of variable definitions not set
+
+	partial    bool      // collect fatal errors in errs and continue
+	errs       ErrorList // fatal errors collected if partial
+	maxErrors  int       // stop after this many errs; 0 if unlimited
+	skipSyntax bool      // discard syntax errors (already collected)
+
+	// while setting into one of several configs (see setAll), data that
+	// can't be stored is recorded in unclaimed instead of being collected
+	claiming  bool
+	unclaimed *extraData
+
+	platform *platform           // selected platform, if any
+	unknown  UnknownDataMode     // handling of extra data; see UnknownData
+	fold     func(string) string // name folding; nil for Unicode case folding
+	fsys     fs.FS               // file system being read, if not the OS's
+
+	strictTypes bool // don't fall back to fmt.Sscanf; see StrictTypes
+
+	// multi-valued variables set (by address), if replacing their values
+	replaceMulti bool
+	multiSet     map[uintptr]bool
+
+	// keys defined for dotted variables, and the policy for duplicates
+	mapKeys   map[mapKey]bool
+	mapPolicy MapKeyPolicy
+	mapSep    string
+
+	// indexed variables set, in the order of their first definition
+	indexed  []*indexed
+	indexedM map[varKey]*indexed
+}
+
+func newCollector(o *options) *collector {
+	c := &collector{handler: o.warningHandler, platform: o.platform,
+		partial: o.partial, maxErrors: o.maxErrors, mapPolicy: o.mapKeys,
+		mapSep: o.mapKeySep, unknown: o.unknown, fold: o.fold,
+		replaceMulti: o.replaceMulti, fsys: o.fsys,
+		strictTypes: o.strictTypes}
+	c.Collector = warnings.NewCollector(func(error) bool { return c.fatal })
+	c.Collector.FatalWithWarnings = o.partial
+	return c
+}
+
+// Collect collects err as a fatal error or a warning, or discards it,
+// depending on its severity. It returns a non-nil error if reading should
+// stop.
+func (c *collector) Collect(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(extraData); ok && c.claiming {
+		c.unclaimed = &e
+		return nil
+	}
+	switch e := err.(type) {
+	case extraData:
+		if e.variable != nil {
+			c.skipped++
+		}
+	case locErr:
+		if e.variable != nil {
+			c.skipped++
+		}
+	}
+	sev := severity(err)
+	if e, ok := err.(extraData); ok && !e.strict && !e.lenient {
+		switch c.unknown {
+		case UnknownLenient:
+			sev = SeverityInfo
+		case UnknownStrict:
+			sev = SeverityError
+		}
+	}
+	if sev == SeverityWarning && c.handler != nil {
+		sev = c.handler(err, sev)
+	}
+	if sev == SeverityInfo {
+		return nil
+	}
+	if _, ok := err.(*SyntaxError); ok && c.skipSyntax {
+		return nil
+	}
+	c.fatal = sev == SeverityError
+	if c.fatal && c.partial {
+		c.errs = append(c.errs, err)
+		if c.maxErrors > 0 && len(c.errs) >= c.maxErrors {
+			return c.collectErrs()
+		}
+		return nil
+	}
+	if !c.fatal {
+		c.warnings++
+	}
+	return c.Collector.Collect(err)
+}
+
+// Done returns the errors collected, if any; see PartialResults for the
+// errors returned when continuing past fatal errors.
+func (c *collector) Done() error {
+	if len(c.errs) > 0 {
+		return c.collectErrs()
+	}
+	return c.Collector.Done()
+}
+
+// collectErrs collects the fatal errors in errs as a single fatal error, and
+// returns them along with the warnings, if any.
+func (c *collector) collectErrs() error {
+	// sections with subsections are set in a separate pass
+	sort.SliceStable(c.errs, func(i, j int) bool {
+		pi, iok := errorPos(c.errs[i])
+		pj, jok := errorPos(c.errs[j])
+		return iok && (!jok || pi.Offset < pj.Offset)
+	})
+	errs := groupSubsections(c.errs)
+	c.fatal = true
+	err := c.Collector.Collect(errs)
+	if c.warnings == 0 {
+		return errs
+	}
+	return err
+}
+
+// groupSubsections returns the errors in errs, with the errors in each
+// subsection grouped into a SubsectionError at the place of the first one.
+func groupSubsections(errs ErrorList) ErrorList {
+	var res ErrorList
+	groups := map[varKey]*SubsectionError{}
+	for _, err := range errs {
+		var l loc
+		switch e := err.(type) {
+		case locErr:
+			l = e.loc
+		case extraData:
+			l = e.loc
+		}
+		if l.subsection == nil {
+			res = append(res, err)
+			continue
+		}
+		k := newVarKey(l.section, *l.subsection, "")
+		if g := groups[k]; g != nil {
+			g.Errs = append(g.Errs, err)
+			continue
+		}
+		g := &SubsectionError{Section: l.section, Subsection: *l.subsection,
+			Errs: ErrorList{err}}
+		groups[k] = g
+		res = append(res, g)
+	}
+	return res
+}
+
+// header holds the positions of the parts of a section header; e.g. for
+// `[section "subsection"]` the positions of '[', `section`, `"subsection"` and
+// ']', respectively. Positions of missing parts are not valid.
+type header struct {
+	lbrack, name, sub, rbrack token.Position
 }
 
 type loc struct {
+	pos        token.Position // position of the variable or section name
+	hdr        header         // positions in the enclosing section header
 	section    string
 	subsection *string
 	variable   *string
+	value      string // raw value of the variable, if valued
+	valued     bool   // whether the variable has a value (is not blank)
+	secret     bool   // value must not be included in messages
 }
 
 type extraData struct {
 	loc
+	strict, lenient bool // severity overridden by the section's struct tag
 }
 
 type locErr struct {
-	msg string
+	err error
 	loc
 }
 
+// SyntaxError is the type of the errors caused by invalid configuration
+// syntax; it wraps ErrSyntax. Use errors.As to obtain the details, e.g. to
+// point out the tokens that would have been accepted:
+//
+//  var se *gcfg.SyntaxError
+//  if errors.As(err, &se) {
+//      ... se.Pos, se.Got, se.Expected ...
+//
+type SyntaxError struct {
+	Pos      token.Position // position of the offending token
+	Got      token.Token    // offending token; ILLEGAL for scanner errors
+	Expected []token.Token  // tokens accepted at Pos; empty if not applicable
+	Msg      string         // error message, without position
+}
+
+// ValueError is the type of the errors for values that can't be set, such as
+// values that can't be parsed into the type of the variable, or that fail
+// validation; it wraps the underlying error. Use errors.As to obtain the
+// details:
+//
+//	var ve *gcfg.ValueError
+//	if errors.As(err, &ve) {
+//		... ve.Pos, ve.Section, ve.Variable, ve.Err ...
+//	}
+type ValueError struct {
+	Pos        token.Position // position of the variable (or section) name
+	Section    string
+	Subsection *string // nil if the section has no subsection
+	Variable   string  // empty for errors setting a section
+	Value      *string // raw value; nil if blank or secret
+	Err        error   // underlying error
+
+	l loc
+}
+
+// UnknownFieldError is the type of the errors (usually warnings) for sections
+// and variables that don't correspond to any field of the config struct; it
+// wraps ErrUnknownSection or ErrUnknownVariable. Use errors.As to obtain the
+// details, as for ValueError.
+type UnknownFieldError struct {
+	Pos        token.Position // position of the variable (or section) name
+	Section    string
+	Subsection *string // nil if the section has no subsection
+	Variable   string  // empty for unknown sections
+}
+
+// InconsistentUseError is the type of the warnings for variables that are
+// defined once in a file but several times in another one read before it
+// using ReadFilesInto (or vice versa); it wraps ErrInconsistentUse. This
+// usually indicates an error, such as expecting a single value to replace
+// the values of a multi-valued variable, or repeating the definition of a
+// single-valued variable instead of overriding it.
+type InconsistentUseError struct {
+	Pos        token.Position // position of the first definition in the file
+	PrevPos    token.Position // position of the first definition in the earlier file
+	Section    string
+	Subsection *string // nil if the section has no subsection
+	Variable   string
+	Repeated   bool // whether the variable is defined several times at Pos
+}
+
+// SubsectionError is the type of the errors grouping the fatal errors in a
+// subsection when reading continues past fatal errors (see PartialResults),
+// so that e.g. the subsections that failed can be reported or skipped by
+// their key, while the other subsections are used. Use errors.As to obtain
+// the details:
+//
+//	var se *gcfg.SubsectionError
+//	if errors.As(err, &se) {
+//		... se.Section, se.Subsection, se.Errs ...
+//	}
+type SubsectionError struct {
+	Section    string
+	Subsection string
+	Errs       ErrorList // errors in the subsection, sorted by position
+}
+
+type encodingErr struct {
+	pos    token.Position // position of the replacement character
+	offset int            // offset of the invalid byte in the original data
+	b      byte           // invalid byte
+}
+
 func (l loc) String() string {
 	s := "section \"" + l.section + "\""
 	if l.subsection != nil {
@@ -45,13 +524,174 @@ func (l loc) String() string {
 	return s
 }
 
+// prefix returns the position of the location formatted for use at the start
+// of an error message, or the empty string if the position is not known.
+func (l loc) prefix() string {
+	if l.pos.Filename == "" && !l.pos.IsValid() {
+		return ""
+	}
+	return l.pos.String() + ": "
+}
+
 func (e extraData) Error() string {
-	return "can't store data at " + e.loc.String()
+	return e.loc.prefix() + "can't store data at " + e.loc.String()
+}
+
+func (e extraData) Unwrap() error {
+	if e.variable != nil {
+		return ErrUnknownVariable
+	}
+	return ErrUnknownSection
+}
+
+// As sets *target to e as an *UnknownFieldError; see errors.As.
+func (e extraData) As(target interface{}) bool {
+	p, ok := target.(**UnknownFieldError)
+	if !ok {
+		return false
+	}
+	*p = &UnknownFieldError{Pos: e.pos, Section: e.section,
+		Subsection: e.subsection}
+	if e.variable != nil {
+		(*p).Variable = *e.variable
+	}
+	return true
 }
 
 func (e locErr) Error() string {
-	return e.msg + " at " + e.loc.String()
+	msg := e.err.Error()
+	v := e.loc.value
+	switch {
+	case !e.loc.valued || !e.loc.secret && strings.Contains(msg, strconv.Quote(v)):
+		return e.loc.prefix() + msg + " at " + e.loc.String()
+	case !e.loc.secret:
+		return e.loc.prefix() + msg + " at " + e.loc.String() +
+			", value " + strconv.Quote(v)
+	}
+	return e.loc.prefix() + e.cause().Error() + " at " + e.loc.String() +
+		", value " + redacted
+}
+
+func (e locErr) Unwrap() error { return e.cause() }
+
+// cause returns the underlying error, redacted for a secret value.
+func (e locErr) cause() error {
+	if e.loc.secret {
+		if _, ok := e.err.(secretErr); !ok {
+			return secretErr{e.err}
+		}
+	}
+	return e.err
 }
 
+// secretErr is the redacted form of an error for a secret value: as its
+// message (or that of any error it wraps) may include the value, only its
+// identity is kept, for errors.Is.
+type secretErr struct{ err error }
+
+func (e secretErr) Error() string { return "invalid value" }
+
+func (e secretErr) Is(target error) bool { return errors.Is(e.err, target) }
+
+// As sets *target to e as a *ValueError; see errors.As.
+func (e locErr) As(target interface{}) bool {
+	p, ok := target.(**ValueError)
+	if !ok {
+		return false
+	}
+	*p = &ValueError{Pos: e.pos, Section: e.section,
+		Subsection: e.subsection, Err: e.cause(), l: e.loc}
+	if e.variable != nil {
+		(*p).Variable = *e.variable
+	}
+	if e.valued && !e.secret {
+		v := e.value
+		(*p).Value = &v
+	}
+	return true
+}
+
+func (e *ValueError) Error() string {
+	return locErr{err: e.Err, loc: e.l}.Error()
+}
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+func (e *UnknownFieldError) Error() string {
+	return e.extraData().Error()
+}
+
+func (e *UnknownFieldError) Unwrap() error { return e.extraData().Unwrap() }
+
+// extraData returns e as an extraData.
+func (e *UnknownFieldError) extraData() extraData {
+	l := loc{pos: e.Pos, section: e.Section, subsection: e.Subsection}
+	if e.Variable != "" {
+		l.variable = &e.Variable
+	}
+	return extraData{loc: l}
+}
+
+func (e *SyntaxError) Error() string {
+	return loc{pos: e.Pos}.prefix() + e.Msg
+}
+
+func (e *SyntaxError) Unwrap() error { return ErrSyntax }
+
+func (e *InconsistentUseError) Error() string {
+	l := loc{pos: e.Pos, section: e.Section, subsection: e.Subsection,
+		variable: &e.Variable}
+	use, prevUse := "several times", "once"
+	if !e.Repeated {
+		use, prevUse = prevUse, use
+	}
+	return fmt.Sprintf("%svariable defined %s, but %s at %s, at %s",
+		l.prefix(), use, prevUse, e.PrevPos, l)
+}
+
+func (e *InconsistentUseError) Unwrap() error { return ErrInconsistentUse }
+
+// Error returns the first error in the subsection, and the number of others.
+func (e *SubsectionError) Error() string { return e.Errs.Error() }
+
+// Unwrap returns the errors in the subsection.
+func (e *SubsectionError) Unwrap() []error { return e.Errs }
+
+func (e encodingErr) Error() string {
+	return fmt.Sprintf("%sinvalid UTF-8 byte %#02x at offset %d replaced "+
+		"with U+FFFD", loc{pos: e.pos}.prefix(), e.b, e.offset)
+}
+
+func (e encodingErr) Unwrap() error { return ErrInvalidUTF8 }
+
+// mustErr is the error the Must*Into functions panic with; the message lists
+// all errors and warnings returned by the read, one per line.
+type mustErr struct {
+	filename string
+	err      error
+}
+
+func (e mustErr) Error() string {
+	var b strings.Builder
+	b.WriteString("gcfg: failed to read config")
+	if e.filename != "" {
+		b.WriteString(" from " + e.filename)
+	}
+	if l := errorList(e.err); len(l) == 1 {
+		b.WriteString(": " + l[0].Error())
+	} else {
+		b.WriteString(":")
+		for _, err := range l {
+			b.WriteString("\n\t" + err.Error())
+		}
+	}
+	return b.String()
+}
+
+func (e mustErr) Unwrap() error { return e.err }
+
 var _ error = extraData{}
 var _ error = locErr{}
+var _ error = &SyntaxError{}
+var _ error = encodingErr{}
+var _ error = mustErr{}
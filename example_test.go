@@ -3,6 +3,7 @@ package gcfg_test
 import (
 	"fmt"
 	"log"
+	"os"
 )
 
 import "gopkg.in/gcfg.v1"
@@ -130,3 +131,24 @@ func ExampleReadStringInto_unicode() {
 	fmt.Println(cfg.X甲.X乙)
 	// Output: 丙
 }
+
+func ExampleFormatErrors() {
+	cfgStr := `[section]
+name=value
+  color=blue
+size=large`
+	cfg := struct {
+		Section struct {
+			Name string
+		}
+	}{}
+	err := gcfg.ReadStringInto(&cfg, cfgStr)
+	gcfg.FormatErrors(os.Stdout, []byte(cfgStr), err)
+	// Output:
+	// 3:3: can't store data at section "section", variable "color"
+	// 	  color=blue
+	// 	  ^
+	// 4:1: can't store data at section "section", variable "size"
+	// 	size=large
+	// 	^
+}
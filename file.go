@@ -0,0 +1,280 @@
+package gcfg
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/gcfg.v1/parser"
+	"gopkg.in/gcfg.v1/scanner"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// A File is an untyped gcfg document: the sections and variable definitions
+// of gcfg formatted data, in order, with access to the values by name rather
+// than by binding them to a config struct. This suits tools that only need
+// key-value access; e.g. to read or change a few values in any config file.
+//
+// As when reading into a config struct, section and variable names are
+// matched case-insensitively, and subsection names case-sensitively. The
+// subsection "" refers to the section without a subsection. The names of
+// dotted and indexed variables include the (case-sensitive) key (e.g.
+// "labels.team"; see the package documentation), and values are unquoted.
+// Comments are not kept.
+type File struct {
+	sects []*fileSect
+}
+
+// fileSect is a section (header and variables) of a File.
+type fileSect struct {
+	name, sub string
+	vars      []fileVar
+}
+
+// fileVar is a variable definition in a File.
+type fileVar struct {
+	name  string
+	value *string // nil for a "blank" value
+}
+
+// Parse parses the gcfg formatted data src into a File. Syntax errors are
+// returned as *SyntaxError (or an ErrorList of them, if more than one), with
+// the File holding what could be parsed.
+func Parse(src []byte) (*File, error) {
+	fset := token.NewFileSet()
+	af, err := parser.ParseFile(fset, "", skipLeadingUtf8Bom(src), 0)
+	f := &File{}
+	for _, as := range af.Sections {
+		s := &fileSect{name: as.Name.Name}
+		if as.Sub != nil {
+			s.sub = unquote(as.Sub.Value)
+		}
+		for _, av := range as.Vars {
+			v := fileVar{name: av.Name.Name}
+			if av.Key != nil {
+				v.name += "." + av.Key.Name
+			}
+			if av.Value != nil {
+				u := unquote(av.Value.Value)
+				v.value = &u
+			}
+			s.vars = append(s.vars, v)
+		}
+		f.sects = append(f.sects, s)
+	}
+	return f, syntaxErrs(err)
+}
+
+// syntaxErrs returns the errors of a scanner.ErrorList returned by the parser
+// as *SyntaxError (or an ErrorList of them, if more than one).
+func syntaxErrs(err error) error {
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
+	}
+	var errs ErrorList
+	for _, e := range el {
+		errs = append(errs, &SyntaxError{Pos: e.Pos, Msg: e.Msg})
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// match reports whether s is the section and subsection.
+func (s *fileSect) match(section, sub string) bool {
+	return strings.EqualFold(s.name, section) && s.sub == sub
+}
+
+// Sections returns the names of the sections in the File, in order of first
+// appearance, each once (in the case of its first appearance).
+func (f *File) Sections() []string {
+	var names []string
+	for _, s := range f.sects {
+		names = appendName(names, s.name)
+	}
+	return names
+}
+
+// Subsections returns the names of the subsections of section in the File, in
+// order of first appearance, each once; "" stands for the section without a
+// subsection.
+func (f *File) Subsections(section string) []string {
+	var subs []string
+	seen := map[string]bool{}
+	for _, s := range f.sects {
+		if strings.EqualFold(s.name, section) && !seen[s.sub] {
+			subs, seen[s.sub] = append(subs, s.sub), true
+		}
+	}
+	return subs
+}
+
+// Names returns the names of the variables defined in the section and
+// subsection, in order of first definition, each once.
+func (f *File) Names(section, sub string) []string {
+	var names []string
+	for _, s := range f.sects {
+		if s.match(section, sub) {
+			for _, v := range s.vars {
+				names = appendName(names, v.name)
+			}
+		}
+	}
+	return names
+}
+
+// appendName appends name to names unless it is already in it.
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// Get returns the value of the last definition of the variable name in the
+// section and subsection, and whether it is defined at all; a "blank" value
+// is returned as "".
+func (f *File) Get(section, sub, name string) (string, bool) {
+	value, ok := "", false
+	for _, s := range f.sects {
+		if !s.match(section, sub) {
+			continue
+		}
+		for _, v := range s.vars {
+			if varNameEqual(v.name, name) {
+				value, ok = "", true
+				if v.value != nil {
+					value = *v.value
+				}
+			}
+		}
+	}
+	return value, ok
+}
+
+// GetAll returns the values of all the definitions of the variable name in
+// the section and subsection, in order; e.g. for a multi-valued variable.
+// Unlike when reading into a config struct, "blank" values (returned as "")
+// don't reset the values defined before them.
+func (f *File) GetAll(section, sub, name string) []string {
+	var values []string
+	for _, s := range f.sects {
+		if !s.match(section, sub) {
+			continue
+		}
+		for _, v := range s.vars {
+			if varNameEqual(v.name, name) {
+				value := ""
+				if v.value != nil {
+					value = *v.value
+				}
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// Set sets the variable name in the section and subsection to value: the
+// first definition of the variable is changed, and any other definitions are
+// removed. If the variable is not defined, it is added as with Add.
+func (f *File) Set(section, sub, name, value string) {
+	found := false
+	for _, s := range f.sects {
+		if !s.match(section, sub) {
+			continue
+		}
+		vars := s.vars[:0]
+		for _, v := range s.vars {
+			if varNameEqual(v.name, name) {
+				if found {
+					continue
+				}
+				v.value, found = &value, true
+			}
+			vars = append(vars, v)
+		}
+		s.vars = vars
+	}
+	if !found {
+		f.Add(section, sub, name, value)
+	}
+}
+
+// Add adds a definition of the variable name with value at the end of the
+// last occurrence of the section and subsection, which is added at the end
+// of the File if it doesn't exist; e.g. to add a value to a multi-valued
+// variable.
+func (f *File) Add(section, sub, name, value string) {
+	var last *fileSect
+	for _, s := range f.sects {
+		if s.match(section, sub) {
+			last = s
+		}
+	}
+	if last == nil {
+		last = &fileSect{name: section, sub: sub}
+		f.sects = append(f.sects, last)
+	}
+	last.vars = append(last.vars, fileVar{name: name, value: &value})
+}
+
+// Unset removes all the definitions of the variable name in the section and
+// subsection.
+func (f *File) Unset(section, sub, name string) {
+	for _, s := range f.sects {
+		if !s.match(section, sub) {
+			continue
+		}
+		vars := s.vars[:0]
+		for _, v := range s.vars {
+			if !varNameEqual(v.name, name) {
+				vars = append(vars, v)
+			}
+		}
+		s.vars = vars
+	}
+}
+
+// WriteTo writes the File to w in the gcfg format, with values quoted as
+// needed; see Marshal. It returns an error for invalid section and variable
+// names (e.g. as passed to Set).
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for _, s := range f.sects {
+		l := loc{section: s.name}
+		if s.sub != "" {
+			l.subsection = &s.sub
+		}
+		if !isName(s.name) {
+			return 0, locErr{err: errInvalidName, loc: l}
+		}
+		if err := e.writeHeader(s.name, l.subsection, false); err != nil {
+			return 0, err
+		}
+		for _, v := range s.vars {
+			lv := l
+			lv.variable = &v.name
+			if !isVarName(v.name) {
+				return 0, locErr{err: errInvalidName, loc: lv}
+			}
+			var err error
+			if v.value == nil {
+				err = e.encodeBlank(v.name, false)
+			} else {
+				err = e.encodeValue(v.name, reflect.ValueOf(*v.value), tag{},
+					false, lv)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return b.WriteTo(w)
+}
@@ -0,0 +1,114 @@
+package gcfg
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	src := `; comment
+[core]
+name = "a b"
+Multi = 1
+multi = 2
+flag
+[remote "origin"]
+url = x
+[labels]
+labels.team = dev
+[Core]
+multi = 3
+`
+	f, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := f.Sections(), []string{"core", "remote", "labels"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Sections: got %q, expected %q", got, exp)
+	}
+	if got, exp := f.Subsections("REMOTE"), []string{"origin"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Subsections: got %q, expected %q", got, exp)
+	}
+	if got, exp := f.Names("core", ""), []string{"name", "Multi", "flag"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Names: got %q, expected %q", got, exp)
+	}
+	for _, tt := range []struct {
+		sect, sub, name string
+		value           string
+		ok              bool
+	}{
+		{"core", "", "name", "a b", true},
+		{"CORE", "", "multi", "3", true},
+		{"core", "", "flag", "", true},
+		{"core", "", "none", "", false},
+		{"remote", "origin", "url", "x", true},
+		{"remote", "ORIGIN", "url", "", false},
+		{"labels", "", "labels.team", "dev", true},
+	} {
+		if v, ok := f.Get(tt.sect, tt.sub, tt.name); v != tt.value || ok != tt.ok {
+			t.Errorf("Get(%q, %q, %q): got %q, %v, expected %q, %v",
+				tt.sect, tt.sub, tt.name, v, ok, tt.value, tt.ok)
+		}
+	}
+	if got, exp := f.GetAll("core", "", "multi"), []string{"1", "2", "3"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("GetAll: got %q, expected %q", got, exp)
+	}
+
+	f.Set("core", "", "multi", "4")
+	f.Set("core", "", "new", "5")
+	f.Set("remote", "upstream", "url", "y")
+	f.Add("remote", "upstream", "fetch", "z")
+	f.Unset("core", "", "flag")
+	exp := `[core]
+	name = a b
+	Multi = 4
+
+[remote "origin"]
+	url = x
+
+[labels]
+	labels.team = dev
+
+[Core]
+	new = 5
+
+[remote "upstream"]
+	url = y
+	fetch = z
+`
+	var b bytes.Buffer
+	if _, err := f.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != exp {
+		t.Errorf("WriteTo: got\n%s\nexpected\n%s", b.String(), exp)
+	}
+	g, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.GetAll("core", "", "multi"), []string{"4"}) {
+		t.Errorf("GetAll after round trip: got %q", g.GetAll("core", "", "multi"))
+	}
+
+	f.Set("core", "", "bad name", "x")
+	if _, err := f.WriteTo(&b); !errors.Is(err, errInvalidName) {
+		t.Errorf("WriteTo with invalid name: got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	f, err := Parse([]byte("[a]\nb = 1\n[c\nd = 2\n"))
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("got error %v, expected *SyntaxError", err)
+	}
+	if se.Pos.Line != 4 { // where ']' is expected
+		t.Errorf("got line %d, expected 4", se.Pos.Line)
+	}
+	if v, ok := f.Get("a", "", "b"); v != "1" || !ok {
+		t.Errorf("got %q, %v, expected what was parsed", v, ok)
+	}
+}
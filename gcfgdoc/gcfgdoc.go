@@ -0,0 +1,229 @@
+// Package gcfgdoc extracts the documentation of gcfg config structs from their
+// Go source, so that the documentation of a configuration can be maintained as
+// ordinary Go comments on the struct fields. The extracted Schema combines the
+// comments with the names and options from the "gcfg" struct tags, and can be
+// written as reference documentation or as a sample configuration file; e.g.
+// from a program run by go generate.
+//
+// Note that the API for the gcfgdoc package may change to accommodate new
+// features or implementation changes in gcfg.
+package gcfgdoc // import "gopkg.in/gcfg.v1/gcfgdoc"
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Schema describes the sections of a config struct.
+type Schema struct {
+	Name     string     // name of the config struct type
+	Doc      string     // doc comment of the config struct type
+	Sections []*Section // sections, in the order of the fields
+}
+
+// A Section describes a section of a config struct.
+type Section struct {
+	Name        string      // section name
+	Doc         string      // doc comment of the field, or else its type
+	Subsections bool        // whether the section has subsections
+	Vars        []*Variable // variables, in the order of the fields
+}
+
+// A Variable describes a variable of a section.
+type Variable struct {
+	Name    string  // variable name
+	Doc     string  // doc comment (or else line comment) of the field
+	Type    string  // field type, as written in the source
+	Multi   bool    // whether the variable is multi-valued
+	Default *string // default value from the ",default=" tag option; or nil
+	Options string  // struct tag options, without the name; e.g. "secret"
+}
+
+// Extract returns the schema of the config struct named structName in the Go
+// package with import path pkgPath, which is located as by the go command
+// (see go/build).
+func Extract(pkgPath, structName string) (*Schema, error) {
+	p, err := build.Import(pkgPath, "", build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractDir(p.Dir, structName)
+}
+
+// ExtractDir returns the schema of the config struct named structName in the
+// Go package in the directory dir (excluding test files).
+//
+// Section fields must have a struct type, a map type with pointer-to-struct
+// values (for sections with subsections), or a named type declared as either
+// of those in the same package. Embedded fields, unexported fields and fields
+// of types declared in other packages are not supported and are skipped.
+func ExtractDir(dir, structName string) (*Schema, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		x := newExtractor(pkgs[name])
+		if spec := x.specs[structName]; spec != nil {
+			return x.schema(spec)
+		}
+	}
+	return nil, fmt.Errorf("gcfgdoc: type %s not found in %s", structName, dir)
+}
+
+type extractor struct {
+	specs map[string]*ast.TypeSpec
+	docs  map[*ast.TypeSpec]*ast.CommentGroup
+}
+
+func newExtractor(pkg *ast.Package) *extractor {
+	x := &extractor{specs: map[string]*ast.TypeSpec{},
+		docs: map[*ast.TypeSpec]*ast.CommentGroup{}}
+	for _, f := range pkg.Files {
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				spec := s.(*ast.TypeSpec)
+				x.specs[spec.Name.Name] = spec
+				// a doc comment on a single spec declaration is
+				// attached to the declaration
+				if x.docs[spec] = spec.Doc; spec.Doc == nil && !gd.Lparen.IsValid() {
+					x.docs[spec] = gd.Doc
+				}
+			}
+		}
+	}
+	return x
+}
+
+func (x *extractor) schema(spec *ast.TypeSpec) (*Schema, error) {
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("gcfgdoc: type %s is not a struct",
+			spec.Name.Name)
+	}
+	s := &Schema{Name: spec.Name.Name, Doc: x.docs[spec].Text()}
+	for _, f := range st.Fields.List {
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			if sect := x.section(f, n.Name); sect != nil {
+				s.Sections = append(s.Sections, sect)
+			}
+		}
+	}
+	return s, nil
+}
+
+// section returns the section for the field f named fieldName, or nil if the
+// type of the field is not supported.
+func (x *extractor) section(f *ast.Field, fieldName string) *Section {
+	name, _ := fieldTag(f, fieldName)
+	sect := &Section{Name: name, Doc: f.Doc.Text()}
+	typ := f.Type
+	if m, ok := typ.(*ast.MapType); ok {
+		star, ok := m.Value.(*ast.StarExpr)
+		if !ok {
+			return nil
+		}
+		sect.Subsections, typ = true, star.X
+	}
+	var st *ast.StructType
+	switch t := typ.(type) {
+	case *ast.StructType:
+		st = t
+	case *ast.Ident:
+		spec := x.specs[t.Name]
+		if spec == nil {
+			return nil
+		}
+		if sect.Doc == "" {
+			sect.Doc = x.docs[spec].Text()
+		}
+		if m, ok := spec.Type.(*ast.MapType); ok && !sect.Subsections {
+			// named map type for subsections
+			return x.section(&ast.Field{Doc: f.Doc, Names: f.Names,
+				Type: m, Tag: f.Tag}, fieldName)
+		}
+		if st, _ = spec.Type.(*ast.StructType); st == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	for _, vf := range st.Fields.List {
+		for _, n := range vf.Names {
+			if !n.IsExported() {
+				continue
+			}
+			v := &Variable{Doc: vf.Doc.Text(), Type: types.ExprString(vf.Type)}
+			if v.Doc == "" {
+				v.Doc = vf.Comment.Text()
+			}
+			var opts []string
+			v.Name, opts = fieldTag(vf, n.Name)
+			for _, o := range opts {
+				if d, ok := strings.CutPrefix(o, "default="); ok {
+					v.Default = &d
+				}
+			}
+			v.Options = strings.Join(opts, ",")
+			if a, ok := vf.Type.(*ast.ArrayType); ok && a.Len == nil {
+				v.Multi = true
+			}
+			if s, ok := vf.Type.(*ast.StarExpr); ok {
+				if a, ok := s.X.(*ast.ArrayType); ok && a.Len == nil {
+					v.Multi = true
+				}
+			}
+			sect.Vars = append(sect.Vars, v)
+		}
+	}
+	return sect
+}
+
+// fieldTag returns the section or variable name for the field f named
+// fieldName, as gcfg determines it, and the options of its "gcfg" struct tag.
+func fieldTag(f *ast.Field, fieldName string) (string, []string) {
+	var ts string
+	if f.Tag != nil {
+		if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+			ts = reflect.StructTag(s).Get("gcfg")
+		}
+	}
+	s := strings.Split(ts, ",")
+	if s[0] != "" {
+		return s[0], s[1:]
+	}
+	n := fieldName
+	if strings.HasPrefix(n, "X") {
+		r1, _ := utf8.DecodeRuneInString(n[1:])
+		if unicode.IsLetter(r1) && !unicode.IsLower(r1) && !unicode.IsUpper(r1) {
+			n = n[1:]
+		}
+	}
+	return strings.ToLower(strings.Replace(n, "_", "-", -1)), s[1:]
+}
@@ -0,0 +1,76 @@
+package gcfgdoc
+
+import (
+	"bytes"
+	"testing"
+)
+
+const expMarkdown = "# Config\n" +
+	"\nConfig is the configuration of the server.\n" +
+	"\n## [server]\n" +
+	"\nServer holds the listener settings.\n" +
+	"\n- `addr` (string; default `:8080`): Address to listen on.\n" +
+	"- `tls` (bool): TLS enables TLS.\n" +
+	"- `debug` (bool): enables debug logging\n" +
+	"- `hosts` ([]string; multi-valued)\n" +
+	"\n## [backend \"name\"]\n" +
+	"\nBackend is a backend to forward requests to.\n" +
+	"\nBackends are tried in order.\n" +
+	"\n- `url` (string)\n" +
+	"- `retries` (int; default `3`)\n" +
+	"\n## [logging]\n" +
+	"\n- `path` (string)\n"
+
+const expSample = "; Config is the configuration of the server.\n" +
+	"\n; Server holds the listener settings.\n" +
+	"[server]\n" +
+	"; Address to listen on.\n" +
+	"; addr = :8080\n" +
+	"; TLS enables TLS.\n" +
+	"; tls =\n" +
+	"; enables debug logging\n" +
+	"; debug =\n" +
+	"; hosts =\n" +
+	"\n; Backend is a backend to forward requests to.\n" +
+	";\n" +
+	"; Backends are tried in order.\n" +
+	"[backend \"name\"]\n" +
+	"; url =\n" +
+	"; retries = 3\n" +
+	"\n[logging]\n" +
+	"; path =\n"
+
+func TestExtractDir(t *testing.T) {
+	s, err := ExtractDir("testdata/config", "Config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Sections); n != 3 {
+		t.Fatalf("got %d sections, wanted 3", n)
+	}
+	if v := s.Sections[1].Vars[0]; v.Name != "url" || v.Options != "requirehost" {
+		t.Errorf("got variable %+v, wanted url with option requirehost", v)
+	}
+	var b bytes.Buffer
+	if err := s.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != expMarkdown {
+		t.Errorf("got markdown:\n%s\nwanted:\n%s", got, expMarkdown)
+	}
+	b.Reset()
+	if err := s.WriteSample(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != expSample {
+		t.Errorf("got sample:\n%s\nwanted:\n%s", got, expSample)
+	}
+}
+
+func TestExtractDirErrors(t *testing.T) {
+	for _, name := range []string{"Nonexistent", "Level"} {
+		if _, err := ExtractDir("testdata/config", name); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
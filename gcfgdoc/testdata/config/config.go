@@ -0,0 +1,33 @@
+package config
+
+// Config is the configuration of the server.
+type Config struct {
+	// Server holds the listener settings.
+	Server struct {
+		// Address to listen on.
+		Addr string `gcfg:",default=:8080"`
+		// TLS enables TLS.
+		TLS   bool
+		Debug bool // enables debug logging
+		Hosts []string
+		state int
+	}
+	Backend map[string]*Backend
+	Log_Sink Sink `gcfg:"logging"`
+	ignored  Sink
+	Other    int
+}
+
+// Backend is a backend to forward requests to.
+//
+// Backends are tried in order.
+type Backend struct {
+	URL     string `gcfg:"url,requirehost"`
+	Retries int    `gcfg:",default=3"`
+}
+
+type Sink struct {
+	Path string
+}
+
+type Level int
@@ -0,0 +1,97 @@
+package gcfgdoc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes reference documentation for the configuration to w,
+// formatted as Markdown: a heading for each section, followed by its doc
+// comment and a list of its variables with their types, default values and
+// doc comments.
+func (s *Schema) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", s.Name)
+	if s.Doc != "" {
+		fmt.Fprintf(bw, "\n%s", s.Doc)
+	}
+	for _, sect := range s.Sections {
+		fmt.Fprintf(bw, "\n## %s\n", header(sect))
+		if sect.Doc != "" {
+			fmt.Fprintf(bw, "\n%s", sect.Doc)
+		}
+		if len(sect.Vars) > 0 {
+			fmt.Fprintln(bw)
+		}
+		for _, v := range sect.Vars {
+			fmt.Fprintf(bw, "- `%s` (%s", v.Name, v.Type)
+			if v.Multi {
+				fmt.Fprint(bw, "; multi-valued")
+			}
+			if v.Default != nil {
+				fmt.Fprintf(bw, "; default `%s`", *v.Default)
+			}
+			fmt.Fprint(bw, ")")
+			if v.Doc != "" {
+				// continuation lines are indented to stay in the item
+				doc := strings.TrimSuffix(v.Doc, "\n")
+				fmt.Fprintf(bw, ": %s", strings.Replace(doc, "\n", "\n  ", -1))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteSample writes a sample configuration file to w, with the doc comments
+// written as comments, and each variable commented out with its default value
+// (if any); e.g. `; port = 8080`. Sections with subsections are written with
+// a placeholder subsection name.
+func (s *Schema) WriteSample(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if s.Doc != "" {
+		writeComment(bw, s.Doc)
+	}
+	for i, sect := range s.Sections {
+		if i > 0 || s.Doc != "" {
+			fmt.Fprintln(bw)
+		}
+		writeComment(bw, sect.Doc)
+		fmt.Fprintln(bw, header(sect))
+		for _, v := range sect.Vars {
+			writeComment(bw, v.Doc)
+			if v.Default != nil {
+				fmt.Fprintf(bw, "; %s = %s\n", v.Name, *v.Default)
+			} else {
+				fmt.Fprintf(bw, "; %s =\n", v.Name)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// header returns the section header for sect, with a placeholder subsection
+// name if it has subsections.
+func header(sect *Section) string {
+	if sect.Subsections {
+		return fmt.Sprintf("[%s \"name\"]", sect.Name)
+	}
+	return "[" + sect.Name + "]"
+}
+
+// writeComment writes text (as returned by ast.CommentGroup.Text) as comment
+// lines.
+func writeComment(w io.Writer, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if line == "" {
+			fmt.Fprintln(w, ";")
+		} else {
+			fmt.Fprintln(w, "; "+line)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+module gopkg.in/gcfg.v1
+
+go 1.21
+
+require gopkg.in/warnings.v0 v0.1.2
@@ -0,0 +1,117 @@
+package gcfg
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// INIDialect returns an Option that makes reading accept data in the dialect
+// common to INI files (as read by e.g. Python's configparser), so that
+// existing files can be read without converting them first:
+//   - a variable can be defined using `name: value` as well as `name = value`,
+//   - the whole section header is the section name (there are no
+//     subsections),
+//   - values are taken literally (up to the end of the line, without
+//     trailing whitespace); quotes, backslashes, and ';' and '#' are not
+//     special, and interpolation such as `%(name)s` is not performed,
+//   - lines that are indented more than the variable definition preceding
+//     them continue its value, separated by a new line,
+//   - characters in section and variable names that are not allowed in
+//     gcfg names (such as spaces, periods and underscores) correspond to
+//     hyphens; e.g. `[log files]` to the section name "log-files".
+//
+// Comments are only recognized on lines of their own. Line numbers in
+// reported errors refer to the original data, but column numbers refer to
+// the data as converted to the gcfg syntax.
+func INIDialect() Option {
+	return func(o *options) {
+		o.iniDialect = true
+	}
+}
+
+// convertINI converts src from the INI dialect (see INIDialect) to the gcfg
+// syntax, preserving line numbers.
+func convertINI(src []byte) []byte {
+	lines := strings.Split(string(src), "\n")
+	out := make([]string, len(lines))
+	last, indent := -1, 0 // last variable line and its indentation
+	var val []string      // lines of the value of the last variable
+	flush := func() {
+		if last >= 0 {
+			out[last] += " = " + quoteINI(strings.Join(val, "\n"))
+		}
+		last, val = -1, nil
+	}
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		ind := len(line) - len(trimmed)
+		switch {
+		case last >= 0 && trimmed != "" && ind > indent:
+			// continuation of the previous value
+			val = append(val, trimmed)
+			continue
+		case trimmed == "", trimmed[0] == ';', trimmed[0] == '#':
+			// blank lines and comments don't end a value; continuation
+			// lines may follow them
+			out[i] = line
+			continue
+		}
+		flush()
+		if trimmed[0] == '[' {
+			out[i] = line // invalid unless terminated
+			if name, ok := strings.CutSuffix(trimmed[1:], "]"); ok {
+				out[i] = "[" + iniName(strings.TrimSpace(name)) + "]"
+			}
+			continue
+		}
+		name, value, ok := cutINI(trimmed)
+		out[i] = iniName(name)
+		if ok {
+			last, indent, val = i, ind, []string{value}
+		}
+	}
+	flush()
+	return []byte(strings.Join(out, "\n"))
+}
+
+// cutINI splits the variable definition s at the first ':' or '='.
+func cutINI(s string) (name, value string, ok bool) {
+	i := strings.IndexAny(s, ":=")
+	if i < 0 {
+		return s, "", false
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+}
+
+// iniName returns the gcfg name corresponding to the INI name s.
+func iniName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// quoteINI quotes the literal value s for the gcfg syntax.
+func quoteINI(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
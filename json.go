@@ -0,0 +1,101 @@
+package gcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonFile is the JSON representation of gcfg data: the values of each
+// variable by section, subsection ("" for none) and variable name, with nil
+// for "blank" values.
+type jsonFile map[string]map[string]map[string][]*string
+
+// ToJSON reads gcfg formatted data from r and returns it converted to JSON,
+// for interoperability with tools that only handle JSON. The JSON value is an
+// object with a member for each section, itself an object with a member for
+// each subsection ("" for the section without a subsection), which is an
+// object with a member for each variable, whose value is the array of the
+// values of its definitions, in order, with null for a "blank" value:
+//
+//	{"core": {"": {"name": ["value"], "flag": [null]}},
+//	 "remote": {"origin": {"url": ["https://example.com/repo.git"]}}}
+//
+// Section and variable names are lower-cased, as they are case-insensitive;
+// definitions of the same variable in several occurrences of a section are
+// merged. Comments and the order of sections and variables are not kept.
+// Syntax errors are returned as for Parse.
+func ToJSON(r io.Reader) ([]byte, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	jf := jsonFile{}
+	for _, s := range f.sects {
+		name := strings.ToLower(s.name)
+		if jf[name] == nil {
+			jf[name] = map[string]map[string][]*string{}
+		}
+		vars := jf[name][s.sub]
+		if vars == nil {
+			vars = map[string][]*string{}
+			jf[name][s.sub] = vars
+		}
+		for _, v := range s.vars {
+			n, key, ok := strings.Cut(v.name, ".")
+			n = strings.ToLower(n)
+			if ok {
+				n += "." + key
+			}
+			vars[n] = append(vars[n], v.value)
+		}
+	}
+	return json.Marshal(jf)
+}
+
+// FromJSON converts data in the JSON representation returned by ToJSON to
+// gcfg formatted data, with the sections, subsections and variables sorted by
+// name, and values quoted as needed.
+func FromJSON(data []byte) ([]byte, error) {
+	var jf jsonFile
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return nil, err
+	}
+	f := &File{}
+	for _, name := range sortedKeys(jf) {
+		for _, sub := range sortedKeys(jf[name]) {
+			s := &fileSect{name: name, sub: sub}
+			vars := jf[name][sub]
+			for _, n := range sortedKeys(vars) {
+				for _, v := range vars[n] {
+					s.vars = append(s.vars, fileVar{name: n, value: v})
+				}
+			}
+			f.sects = append(f.sects, s)
+		}
+	}
+	var b bytes.Buffer
+	if _, err := f.WriteTo(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sortedKeys returns the keys of m, a map with string keys, in increasing
+// order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
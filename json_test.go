@@ -0,0 +1,62 @@
+package gcfg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	src := `[Core]
+Name = value
+flag
+multi = 1
+[remote "Origin"]
+url = "a ; b"
+[core]
+multi = 2
+labels.Team = x
+[empty]
+`
+	b, err := ToJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"core":{"":{"flag":[null],"labels.Team":["x"],"multi":["1","2"],` +
+		`"name":["value"]}},"empty":{"":{}},"remote":{"Origin":{"url":["a ; b"]}}}`
+	if string(b) != exp {
+		t.Errorf("ToJSON: got\n%s\nexpected\n%s", b, exp)
+	}
+	g, err := FromJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = `[core]
+	flag
+	labels.Team = x
+	multi = 1
+	multi = 2
+	name = value
+
+[empty]
+
+[remote "Origin"]
+	url = "a ; b"
+`
+	if string(g) != exp {
+		t.Errorf("FromJSON: got\n%s\nexpected\n%s", g, exp)
+	}
+	if b2, err := ToJSON(strings.NewReader(string(g))); err != nil ||
+		string(b2) != string(b) {
+		t.Errorf("round trip: got %s, %v", b2, err)
+	}
+
+	if _, err := ToJSON(strings.NewReader("[a\n")); !errors.Is(err, ErrSyntax) {
+		t.Errorf("got error %v, expected syntax error", err)
+	}
+	for _, data := range []string{`[]`, `{"a b":{"":{}}}`, `{"a":{"":{"b":[1]}}}`} {
+		if _, err := FromJSON([]byte(data)); err == nil {
+			t.Errorf("%s: got no error", data)
+		}
+	}
+}
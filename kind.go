@@ -0,0 +1,87 @@
+package gcfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// kinds holds the implementations registered for interface types, by kind;
+// see RegisterKind.
+var kinds = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]func() interface{}
+}{m: map[reflect.Type]map[string]func() interface{}{}}
+
+// RegisterKind registers newValue as the implementation of kind for the
+// interface type iface points to (e.g. (*Storage)(nil)), for variables of that
+// interface type with the ",kind=name" struct tag option. newValue returns a
+// pointer to a new value of the implementation, which the variable value is
+// parsed into as for any other type; the pointer or the value it points to
+// must implement the interface.
+//
+// RegisterKind is typically called from an init function; it panics if iface
+// is not a pointer to an interface type, or if kind is already registered for
+// it.
+func RegisterKind(iface interface{}, kind string, newValue func() interface{}) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Errorf("iface must be a pointer to an interface type: "+
+			"kind %q", kind))
+	}
+	t = t.Elem()
+	kinds.Lock()
+	defer kinds.Unlock()
+	if kinds.m[t] == nil {
+		kinds.m[t] = map[string]func() interface{}{}
+	}
+	if _, ok := kinds.m[t][kind]; ok {
+		panic(fmt.Errorf("kind already registered: type %v, kind %q", t, kind))
+	}
+	kinds.m[t][kind] = newValue
+}
+
+// newKind returns a pointer to a new value of the implementation of kind for
+// the interface type t. It panics if newValue returns a value that doesn't
+// implement t.
+func newKind(t reflect.Type, kind string) (reflect.Value, error) {
+	if kind == "" {
+		return reflect.Value{}, fmt.Errorf("kind of %v not set", t)
+	}
+	kinds.RLock()
+	newValue, ok := kinds.m[t][kind]
+	var known []string
+	if !ok {
+		for k := range kinds.m[t] {
+			known = append(known, fmt.Sprintf("%q", k))
+		}
+	}
+	kinds.RUnlock()
+	if !ok {
+		sort.Strings(known)
+		return reflect.Value{}, fmt.Errorf("unknown kind %q of %v "+
+			"(registered: %s)", kind, t, strings.Join(known, ", "))
+	}
+	pv := reflect.ValueOf(newValue())
+	if pv.Kind() != reflect.Ptr || !pv.Type().Implements(t) &&
+		!pv.Type().Elem().Implements(t) {
+		panic(fmt.Errorf("implementation of kind %q doesn't implement %v: "+
+			"got %v", kind, t, pv.Type()))
+	}
+	return pv, nil
+}
+
+// kindOf returns the value of the variable named by the ",kind=name" struct
+// tag option t.kind in the section vSect. It panics if the variable doesn't
+// exist or is not of string kind.
+func kindOf(vSect reflect.Value, t tag, l loc) string {
+	vKind, _ := fieldFold(vSect, t.kind, nil)
+	if !vKind.IsValid() || vKind.Kind() != reflect.String {
+		panic(fmt.Errorf("kind variable %q must be a string variable of the "+
+			"section: section %q, variable %q", t.kind, l.section,
+			*l.variable))
+	}
+	return vKind.String()
+}
@@ -0,0 +1,350 @@
+// Package langserver provides the building blocks for a language server
+// (implementing the Language Server Protocol, LSP) for gcfg files: document
+// symbols, diagnostics and completion candidates, computed from the source of
+// a document. Positions use the LSP conventions (zero-based lines, and
+// characters counted in UTF-16 code units), so that they can be passed to an
+// editor unchanged; the transport and protocol messages are left to the
+// server.
+//
+// Note that the API for the langserver package may change to accommodate new
+// features or implementation changes in gcfg.
+package langserver
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gcfg "gopkg.in/gcfg.v1"
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/parser"
+	"gopkg.in/gcfg.v1/scanner"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// A Position is a position in a document: a zero-based line, and a
+// zero-based character offset in the line, in UTF-16 code units.
+type Position struct {
+	Line      int
+	Character int
+}
+
+// A Range is the range of text between Start (inclusive) and End (exclusive).
+type Range struct {
+	Start, End Position
+}
+
+// A SymbolKind is the kind of a Symbol.
+type SymbolKind int
+
+const (
+	SymbolSection  SymbolKind = iota + 1 // section (or subsection)
+	SymbolVariable                       // variable definition
+)
+
+// A Symbol is a section or a variable definition in a document.
+type Symbol struct {
+	Name           string     // e.g. `remote "origin"` or "url"
+	Kind           SymbolKind // kind of symbol
+	Range          Range      // the whole section or definition
+	SelectionRange Range      // the name
+	Children       []Symbol   // variables of a section
+}
+
+// A Severity is the severity of a Diagnostic.
+type Severity int
+
+const (
+	SeverityError   Severity = iota + 1 // syntax error
+	SeverityWarning                     // e.g. unknown section or variable
+)
+
+// A Diagnostic is a problem found in a document.
+type Diagnostic struct {
+	Range    Range
+	Severity Severity
+	Message  string
+}
+
+// A CompletionKind is the kind of a Completion.
+type CompletionKind int
+
+const (
+	CompletionSection  CompletionKind = iota + 1 // section name
+	CompletionVariable                           // variable name
+	CompletionValue                              // variable value
+)
+
+// A Completion is a candidate for completing the text at a position.
+type Completion struct {
+	Label  string         // text to insert
+	Kind   CompletionKind // kind of candidate
+	Detail string         // e.g. the Go type of a variable
+}
+
+// A Document is a parsed gcfg document. Its methods don't modify it, so they
+// can be called concurrently; a new Document is parsed after each change.
+type Document struct {
+	src   []byte
+	base  int // base of the positions in the parse tree
+	ast   *ast.File
+	errs  scanner.ErrorList
+	lines []int // offsets of the line starts
+}
+
+// Parse parses the source of a document. Parsing always succeeds; syntax
+// errors are reported by Diagnostics, and the parse tree holds what could be
+// parsed, as the parser recovers at the next line.
+func Parse(src []byte) *Document {
+	fset := token.NewFileSet()
+	d := &Document{src: src, base: fset.Base(), lines: []int{0}}
+	f, err := parser.ParseFile(fset, "", src, 0)
+	d.ast = f
+	if el, ok := err.(scanner.ErrorList); ok {
+		d.errs = el
+	}
+	for i, b := range src {
+		if b == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	return d
+}
+
+// off returns the byte offset of the position p in the parse tree, clamped to
+// the source (as placeholders for missing names may end past it).
+func (d *Document) off(p token.Pos) int {
+	offs := int(p) - d.base
+	if offs > len(d.src) {
+		offs = len(d.src)
+	}
+	return offs
+}
+
+// position returns the Position of the byte offset offs.
+func (d *Document) position(offs int) Position {
+	if offs > len(d.src) {
+		offs = len(d.src)
+	}
+	line := sort.SearchInts(d.lines, offs+1) - 1
+	n := 0
+	for _, r := range string(d.src[d.lines[line]:offs]) {
+		n += runeLen(r)
+	}
+	return Position{Line: line, Character: n}
+}
+
+// runeLen returns the number of UTF-16 code units encoding r.
+func runeLen(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair
+	}
+	return 1
+}
+
+// offset returns the byte offset of the Position p, clamped to the line.
+func (d *Document) offset(p Position) int {
+	if p.Line >= len(d.lines) {
+		return len(d.src)
+	}
+	offs, n := d.lines[p.Line], 0
+	for offs < len(d.src) && d.src[offs] != '\n' && n < p.Character {
+		r, w := utf8.DecodeRune(d.src[offs:])
+		offs, n = offs+w, n+runeLen(r)
+	}
+	return offs
+}
+
+// rng returns the Range of the node n.
+func (d *Document) rng(n ast.Node) Range {
+	return Range{d.position(d.off(n.Pos())), d.position(d.off(n.End()))}
+}
+
+// Symbols returns the sections of the document, with their variable
+// definitions as children, in the order in which they appear.
+func (d *Document) Symbols() []Symbol {
+	var syms []Symbol
+	for _, s := range d.ast.Sections {
+		sym := Symbol{Name: sectionName(s), Kind: SymbolSection,
+			Range: d.rng(s), SelectionRange: d.rng(s.Name)}
+		if s.Sub != nil {
+			sym.SelectionRange.End = d.rng(s.Sub).End
+		}
+		for _, v := range s.Vars {
+			sel := d.rng(v.Name)
+			if v.Key != nil {
+				sel.End = d.rng(v.Key).End
+			}
+			sym.Children = append(sym.Children, Symbol{Name: variableName(v),
+				Kind: SymbolVariable, Range: d.rng(v), SelectionRange: sel})
+		}
+		syms = append(syms, sym)
+	}
+	return syms
+}
+
+// sectionName returns the name of the section s as displayed in symbols.
+func sectionName(s *ast.Section) string {
+	if s.Sub != nil {
+		return s.Name.Name + " " + s.Sub.Value
+	}
+	return s.Name.Name
+}
+
+// variableName returns the name of the variable v (including its key, if
+// any) as displayed in symbols.
+func variableName(v *ast.Variable) string {
+	if v.Key != nil {
+		return v.Name.Name + "." + v.Key.Name
+	}
+	return v.Name.Name
+}
+
+// Diagnostics returns the syntax errors in the document, and if schema is not
+// nil (see gcfg.Schema), warnings for the sections and variables that are not
+// in the schema, sorted by position.
+func (d *Document) Diagnostics(schema []gcfg.FieldInfo) []Diagnostic {
+	var diags []Diagnostic
+	for _, e := range d.errs {
+		start := d.position(e.Pos.Offset)
+		end := e.Pos.Offset
+		for end < len(d.src) && d.src[end] != '\n' && d.src[end] != '\r' {
+			end++
+		}
+		diags = append(diags, Diagnostic{Range{start, d.position(end)},
+			SeverityError, e.Msg})
+	}
+	if schema != nil {
+		for _, s := range d.ast.Sections {
+			fis := sectionFields(schema, s.Name.Name)
+			switch {
+			case s.Name.Name == missing:
+				continue // reported as a syntax error
+			case fis == nil:
+				diags = append(diags, Diagnostic{d.rng(s.Name),
+					SeverityWarning, "unknown section " +
+						quote(s.Name.Name)})
+				continue
+			case s.Sub != nil && !fis[0].Subsections:
+				diags = append(diags, Diagnostic{d.rng(s.Sub),
+					SeverityWarning, "section " + quote(s.Name.Name) +
+						" has no subsections"})
+			}
+			for _, v := range s.Vars {
+				if v.Name.Name != missing && field(fis, v.Name.Name) == nil {
+					diags = append(diags, Diagnostic{d.rng(v.Name),
+						SeverityWarning, "unknown variable " +
+							quote(v.Name.Name) + " in section " +
+							quote(s.Name.Name)})
+				}
+			}
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Range.Start, diags[j].Range.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+	})
+	return diags
+}
+
+// missing is the name of the placeholder for a missing name in the parse
+// tree.
+const missing = "_"
+
+func quote(s string) string { return `"` + s + `"` }
+
+// sectionFields returns the fields of the section in schema; nil if none.
+func sectionFields(schema []gcfg.FieldInfo, section string) []gcfg.FieldInfo {
+	var fis []gcfg.FieldInfo
+	for _, fi := range schema {
+		if strings.EqualFold(fi.Section, section) {
+			fis = append(fis, fi)
+		}
+	}
+	return fis
+}
+
+// field returns the field of the variable among the fields of a section; nil
+// if none.
+func field(fis []gcfg.FieldInfo, variable string) *gcfg.FieldInfo {
+	for i := range fis {
+		if fis[i].Variable != "" && strings.EqualFold(fis[i].Variable, variable) {
+			return &fis[i]
+		}
+	}
+	return nil
+}
+
+// Complete returns the candidates from schema (see gcfg.Schema) for
+// completing the text before the position p, in the order of the schema:
+//   - in a section header, the section names,
+//   - at the start of a definition, the names of the variables of the
+//     enclosing section,
+//   - in the value of a bool variable, "true" and "false".
+//
+// Only the candidates starting with the (case-insensitive) name or value
+// typed before p are returned.
+func (d *Document) Complete(p Position, schema []gcfg.FieldInfo) []Completion {
+	offs := d.offset(p)
+	line := d.lines[d.position(offs).Line]
+	before := string(d.src[line:offs])
+	trimmed := strings.TrimLeftFunc(before, unicode.IsSpace)
+	var cs []Completion
+	add := func(c Completion, prefix string) {
+		for _, o := range cs {
+			if o.Label == c.Label {
+				return
+			}
+		}
+		if len(c.Label) >= len(prefix) &&
+			strings.EqualFold(c.Label[:len(prefix)], prefix) {
+			cs = append(cs, c)
+		}
+	}
+	switch {
+	case strings.HasPrefix(trimmed, "["):
+		prefix := strings.TrimLeftFunc(trimmed[1:], unicode.IsSpace)
+		if strings.ContainsAny(prefix, ` "]`) {
+			return nil // past the section name
+		}
+		for _, fi := range schema {
+			add(Completion{Label: fi.Section, Kind: CompletionSection}, prefix)
+		}
+	case strings.ContainsAny(trimmed, ";#"):
+		return nil // in a comment
+	case strings.Contains(trimmed, "="):
+		name, value, _ := strings.Cut(trimmed, "=")
+		name = strings.TrimSpace(strings.TrimSuffix(name, "+"))
+		fi := field(sectionFields(schema, d.sectionAt(line)), name)
+		if fi == nil || fi.Type != "bool" && fi.Type != "*bool" {
+			return nil
+		}
+		prefix := strings.TrimLeftFunc(value, unicode.IsSpace)
+		for _, v := range []string{"true", "false"} {
+			add(Completion{Label: v, Kind: CompletionValue, Detail: fi.Type},
+				prefix)
+		}
+	default:
+		for _, fi := range sectionFields(schema, d.sectionAt(line)) {
+			if fi.Variable != "" {
+				add(Completion{Label: fi.Variable, Kind: CompletionVariable,
+					Detail: fi.Type}, trimmed)
+			}
+		}
+	}
+	return cs
+}
+
+// sectionAt returns the name of the section enclosing the line starting at
+// offset line; "" if none.
+func (d *Document) sectionAt(line int) string {
+	name := ""
+	for _, s := range d.ast.Sections {
+		if d.off(s.Pos()) >= line {
+			break
+		}
+		name = s.Name.Name
+	}
+	return name
+}
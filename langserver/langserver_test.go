@@ -0,0 +1,110 @@
+package langserver
+
+import (
+	"reflect"
+	"testing"
+
+	gcfg "gopkg.in/gcfg.v1"
+)
+
+type config struct {
+	Server struct {
+		Host    string
+		Port    int
+		Verbose bool
+	}
+	Remote map[string]*struct{ URL string }
+}
+
+var schema = gcfg.Schema(&config{})
+
+const src = `; comment
+[server]
+host = ☃.example.com
+port x
+[remote "origin"]
+url = x
+colour = red
+[unknown]
+`
+
+func TestSymbols(t *testing.T) {
+	syms := Parse([]byte(src)).Symbols()
+	if len(syms) != 3 {
+		t.Fatalf("got %d symbols, wanted 3", len(syms))
+	}
+	s := syms[0]
+	if s.Name != "server" || s.Kind != SymbolSection ||
+		s.Range != (Range{Position{1, 0}, Position{3, 4}}) ||
+		s.SelectionRange != (Range{Position{1, 1}, Position{1, 7}}) {
+		t.Errorf("got section %+v", s)
+	}
+	if len(s.Children) != 2 {
+		t.Fatalf("got %d variables, wanted 2", len(s.Children))
+	}
+	// the snowman is a single UTF-16 code unit, but 3 bytes
+	v := s.Children[0]
+	if v.Name != "host" || v.Kind != SymbolVariable ||
+		v.Range != (Range{Position{2, 0}, Position{2, 20}}) {
+		t.Errorf("got variable %+v", v)
+	}
+	if s := syms[1]; s.Name != `remote "origin"` ||
+		s.SelectionRange != (Range{Position{4, 1}, Position{4, 16}}) {
+		t.Errorf("got section %+v", s)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	d := Parse([]byte(src))
+	diags := d.Diagnostics(nil)
+	if len(diags) != 1 || diags[0].Severity != SeverityError ||
+		diags[0].Range != (Range{Position{3, 5}, Position{3, 6}}) {
+		t.Errorf("got %+v, wanted a syntax error at 3:5", diags)
+	}
+	diags = d.Diagnostics(schema)
+	var msgs []string
+	for _, d := range diags {
+		msgs = append(msgs, d.Message)
+	}
+	exp := []string{"expected '=', found x",
+		`unknown variable "colour" in section "remote"`,
+		`unknown section "unknown"`}
+	if !reflect.DeepEqual(msgs, exp) {
+		t.Errorf("got %q, wanted %q", msgs, exp)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		pos  Position
+		exp  []string
+		kind CompletionKind
+	}{
+		{"[", Position{0, 1}, []string{"server", "remote"}, CompletionSection},
+		{"[Se", Position{0, 3}, []string{"server"}, CompletionSection},
+		{"[server]\n  ", Position{1, 2}, []string{"host", "port", "verbose"},
+			CompletionVariable},
+		{"[server]\nP\n[remote \"a\"]", Position{1, 1}, []string{"port"},
+			CompletionVariable},
+		{"[remote \"a\"]\n", Position{1, 0}, []string{"url"}, CompletionVariable},
+		{"[server]\nverbose = t", Position{1, 11}, []string{"true"},
+			CompletionValue},
+		{"[server]\nhost = ", Position{1, 7}, nil, 0},
+		{"[server]\n; ", Position{1, 2}, nil, 0},
+		{"host", Position{0, 4}, nil, 0},
+	} {
+		cs := Parse([]byte(tt.src)).Complete(tt.pos, schema)
+		var labels []string
+		for _, c := range cs {
+			labels = append(labels, c.Label)
+			if c.Kind != tt.kind {
+				t.Errorf("%q: got kind %v for %q, wanted %v", tt.src, c.Kind,
+					c.Label, tt.kind)
+			}
+		}
+		if !reflect.DeepEqual(labels, tt.exp) {
+			t.Errorf("%q: got %q, wanted %q", tt.src, labels, tt.exp)
+		}
+	}
+}
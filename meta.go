@@ -0,0 +1,67 @@
+package gcfg
+
+import (
+	"sort"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+// Meta records the sections, subsections and variables defined in the data
+// read, and their positions; see MetaInto. This makes it possible to tell a
+// variable explicitly set to its zero value from one that was not provided,
+// without using pointer fields.
+//
+// Section and variable names are matched case-insensitively, and subsection
+// names case-sensitively, as when reading. The variable name "" refers to
+// the section header.
+type Meta struct {
+	defs map[varKey][]token.Position
+}
+
+// MetaInto returns an Option that records the definitions in the data read
+// into *m. Definitions are added to those already recorded in m; in
+// particular, when reading several files with ReadFilesWithOptions, m
+// records the definitions in all of them. Variables set from the environment
+// or to a default value (see the package documentation) are not recorded.
+func MetaInto(m *Meta) Option {
+	return func(o *options) {
+		o.meta = m
+	}
+}
+
+// add records a definition at pos.
+func (m *Meta) add(sect, sub, name string, pos token.Position) {
+	if m.defs == nil {
+		m.defs = map[varKey][]token.Position{}
+	}
+	k := newVarKey(sect, sub, name)
+	m.defs[k] = append(m.defs[k], pos)
+}
+
+// Defined reports whether the variable (or if variable is "", the header) of
+// the section and subsection was defined in the data read. A dotted or
+// indexed variable (see the package documentation) is defined if any of its
+// keys is; e.g. for "labels.team", both "labels" and "labels.team" are.
+func (m *Meta) Defined(section, subsection, variable string) bool {
+	return len(m.defs[newVarKey(section, subsection, variable)]) > 0
+}
+
+// Positions returns the positions of the definitions of the variable (or if
+// variable is "", of the header) of the section and subsection, in the order
+// in which they were read; nil if it was not defined.
+func (m *Meta) Positions(section, subsection, variable string) []token.Position {
+	return m.defs[newVarKey(section, subsection, variable)]
+}
+
+// Subsections returns the sorted names of the subsections of section that
+// were defined in the data read.
+func (m *Meta) Subsections(section string) []string {
+	var subs []string
+	for k := range m.defs {
+		if k.sect == newVarKey(section, "", "").sect && k.sub != "" && k.name == "" {
+			subs = append(subs, k.sub)
+		}
+	}
+	sort.Strings(subs)
+	return subs
+}
@@ -0,0 +1,388 @@
+package gcfg
+
+import (
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+// An Option configures the behavior of ReadWithOptions, ReadStringWithOptions
+// and ReadFileWithOptions. Options make it possible to modify the behavior of
+// reading without changing the signatures of these functions.
+type Option func(*options)
+
+type options struct {
+	warningHandler func(err error, sev Severity) Severity
+	statsHandler   func(Stats)
+	logger         *slog.Logger
+	columns        token.ColumnMode
+	appendSep      *string // separator for '+=' on strings; nil if disabled
+	platform       *platform
+	partial        bool // continue past fatal errors
+	maxErrors      int  // max. fatal errors if partial; 0 if unlimited
+	lenientUTF8    bool // replace invalid UTF-8 with U+FFFD
+	progress       func(Progress)
+	progressEvery  int    // bytes between progress reports
+	maxValueLen    *int   // max. value length; nil for DefaultMaxValueLength
+	iniDialect     bool   // accept the INI dialect; see INIDialect
+	embedded       string // marker of embedded data; see Embedded
+	mapKeys        MapKeyPolicy
+	mapKeySep      string // separator for MapKeysConcat
+	unknown        UnknownDataMode
+	continuations  bool                // continue values on indented lines
+	literalValues  bool                // take values verbatim; see LiteralValues
+	fold           func(string) string // see NameFolding
+	replaceMulti   bool                // see ReplaceMultiValues
+	strictTypes    bool                // see StrictTypes
+	usage          usage               // definitions recorded by ReadFilesInto
+	seen           map[varKey]bool     // variables seen by ReadFilesInto
+	layered        bool                // not the last file of ReadFilesInto
+	meta           *Meta               // see MetaInto
+	fsys           fs.FS               // file system read by ReadFSInto
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WarningHandler returns an Option that sets a handler for non-fatal errors
+// (warnings and informational messages) encountered while reading.
+//
+// The handler is called with each such error and its default severity, and
+// returns the severity to handle it with:
+//   - SeverityError makes the error fatal; reading stops and it is returned,
+//   - SeverityWarning collects the error as a warning (see FatalOnly),
+//   - SeverityInfo discards the error.
+//
+// Returning sev unchanged preserves the default behavior. This makes it
+// possible to e.g. route warnings to a logger (returning SeverityInfo), or to
+// treat them as fatal errors in CI (returning SeverityError). The handler is
+// not called for unknown variables in sections whose field has the ",strict"
+// or ",lenient" struct tag option.
+func WarningHandler(h func(err error, sev Severity) Severity) Option {
+	return func(o *options) {
+		o.warningHandler = h
+	}
+}
+
+// Stats holds statistics about a single read, for monitoring purposes.
+type Stats struct {
+	Bytes     int           // number of bytes read
+	Lines     int           // number of lines scanned
+	Sections  int           // number of section headers
+	Variables int           // number of variable definitions
+	Skipped   int           // number of variable definitions not set
+	Warnings  int           // number of warnings (non-fatal errors)
+	Elapsed   time.Duration // time spent parsing and setting values
+}
+
+// StatsHandler returns an Option that sets a function to be called with the
+// statistics of the read when it finishes, whether successfully or not.
+// Counts reported for a failed read cover the data read up to the failure.
+//
+// Variable definitions are skipped if they don't correspond to any field
+// (whether reported as a warning or not; see WarningHandler), or if their
+// value couldn't be set when reading continues past errors (see
+// PartialResults); the number of variables set is Variables - Skipped.
+func StatsHandler(h func(Stats)) Option {
+	return func(o *options) {
+		o.statsHandler = h
+	}
+}
+
+// StatsInto returns an Option that stores the statistics of the read (see
+// StatsHandler) in *st when it finishes; e.g. for alerting on a config that
+// suddenly has no variables set.
+func StatsInto(st *Stats) Option {
+	return func(o *options) {
+		h := o.statsHandler
+		o.statsHandler = func(s Stats) {
+			*st = s
+			if h != nil {
+				h(s)
+			}
+		}
+	}
+}
+
+// PartialResults returns an Option that makes reading continue past fatal
+// errors, rather than stopping at the first one, so that config is populated
+// with all the values that could be set. This lets interactive tools show
+// what did parse while highlighting the problems.
+//
+// The fatal errors (e.g. for malformed lines, values that can't be parsed, or
+// unknown data with UnknownStrict) are returned together as an ErrorList,
+// sorted by position, so that all the problems in the data can be reported
+// in one go (e.g. using FormatErrors). The errors in each subsection are
+// grouped into a *SubsectionError, keyed by the section and subsection names,
+// so that one bad subsection (e.g. `[host "x"]`) among many can be identified
+// without it hiding the problems in the others. If there are also warnings,
+// the ErrorList is the Fatal error of the returned warnings.List (see
+// FatalOnly).
+// Note that some errors (e.g. syntax errors) may cause subsequent errors on
+// the same line.
+func PartialResults() Option {
+	return func(o *options) {
+		o.partial = true
+	}
+}
+
+// MaxErrors returns an Option that limits the number of fatal errors collected
+// when reading continues past them (see PartialResults): reading stops after
+// n errors, which are returned as usual. This keeps pathological inputs from
+// producing an excessive number of errors. If n <= 0 (the default), the
+// number of errors is not limited.
+func MaxErrors(n int) Option {
+	return func(o *options) {
+		o.maxErrors = n
+	}
+}
+
+// LenientUTF8 returns an Option that makes reading accept data that is not
+// valid UTF-8: each byte that is not part of a valid UTF-8 encoding is
+// replaced with U+FFFD (the Unicode replacement character), and reported with
+// a warning wrapping ErrInvalidUTF8 (see FatalOnly). This lets slightly
+// corrupted legacy files load while still surfacing the damage.
+//
+// The warnings include the offset of the byte in the original data; note that
+// as the replacement character takes up three bytes, positions reported for
+// subsequent errors refer to the data after the replacement.
+func LenientUTF8() Option {
+	return func(o *options) {
+		o.lenientUTF8 = true
+	}
+}
+
+// DefaultMaxValueLength is the maximum length in bytes of any single value
+// (after unquoting) for reads that don't set it using the MaxValueLength
+// option; 0 (the default) means no limit. It applies to all reads in the
+// program, so it should only be set during initialization.
+var DefaultMaxValueLength int
+
+// MaxValueLength returns an Option that limits the length of any single value
+// (after unquoting) to n bytes, overriding DefaultMaxValueLength; if n <= 0,
+// the length is not limited. A longer value is a fatal error wrapping
+// ErrValueTooLong, and is not set. This protects services from accidental or
+// malicious oversized values, such as a multi-megabyte line.
+func MaxValueLength(n int) Option {
+	return func(o *options) {
+		o.maxValueLen = &n
+	}
+}
+
+// maxValueLength returns the maximum value length for the read; 0 if not
+// limited.
+func (o *options) maxValueLength() int {
+	if o.maxValueLen != nil {
+		return *o.maxValueLen
+	}
+	return DefaultMaxValueLength
+}
+
+// Progress holds the progress of a read; see ProgressHandler.
+type Progress struct {
+	Pass  int // pass over the data; 1 or 2
+	Bytes int // number of bytes processed in the current pass
+	Total int // total number of bytes in the data
+	Lines int // number of lines processed in the current pass
+}
+
+// ProgressHandler returns an Option that sets a function to be called
+// periodically with the progress of the read, about every n bytes processed
+// (and at the end of the data), e.g. to show progress for multi-megabyte data,
+// or to log slow reads. If n <= 0, a default of 1 MiB is used.
+//
+// Note that the data is processed in two passes (for sections without and
+// with subsections, respectively); progress is reported for each pass.
+func ProgressHandler(n int, h func(Progress)) Option {
+	if n <= 0 {
+		n = 1 << 20
+	}
+	return func(o *options) {
+		o.progress, o.progressEvery = h, n
+	}
+}
+
+// Logger returns an Option that sets a logger for tracing the read at debug
+// level: each token scanned, each section entered, and each attempt to set a
+// value (with its outcome) is logged. This can be used to diagnose why a value
+// was or wasn't set as expected.
+//
+// Note that values are processed in two passes (for sections without and with
+// subsections, respectively), so most events are logged twice; the
+// "subsectPass" attribute tells the passes apart.
+func Logger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// AppendOperator returns an Option that enables the append operator '+='.
+// For multi-valued variables, `name += value` appends a value, just like
+// `name = value`; for string variables, it appends value to the current value,
+// separated by sep (unless the current value is empty). Using it with other
+// types of variables is an error.
+//
+// This is useful e.g. for extending the values set by an earlier definition or
+// by another file, without repeating them.
+func AppendOperator(sep string) Option {
+	return func(o *options) {
+		o.appendSep = &sep
+	}
+}
+
+// An UnknownDataMode determines how data that doesn't belong to any part of
+// the config structure is handled; see UnknownData.
+type UnknownDataMode int
+
+const (
+	UnknownWarning UnknownDataMode = iota // unknown data is a warning
+	UnknownLenient                        // unknown data is ignored
+	UnknownStrict                         // unknown data is a fatal error
+)
+
+// UnknownData returns an Option that sets how unknown sections, subsections
+// and variables are handled. By default (UnknownWarning), they are warnings
+// (see FatalOnly and WarningHandler); with UnknownLenient, they are silently
+// skipped, and with UnknownStrict, they are fatal errors. The ",strict" and
+// ",lenient" struct tag options of a section field take precedence for the
+// variables in that section.
+func UnknownData(mode UnknownDataMode) Option {
+	return func(o *options) {
+		o.unknown = mode
+	}
+}
+
+// A MapKeyPolicy determines how a key that is defined more than once for a
+// dotted variable is handled; see DuplicateMapKeys.
+type MapKeyPolicy int
+
+const (
+	MapKeysLastWins  MapKeyPolicy = iota // the last value is set
+	MapKeysFirstWins                     // later values are ignored
+	MapKeysError                         // later values are errors
+	MapKeysConcat                        // values are concatenated
+)
+
+// DuplicateMapKeys returns an Option that sets the policy for keys of dotted
+// variables (see the package documentation) that are defined more than once;
+// by default (MapKeysLastWins), each definition overrides the previous one.
+// With MapKeysConcat, string values are concatenated, separated by sep, as if
+// using the append operator (see AppendOperator); for other types of values,
+// it is an error. sep is ignored for other policies.
+//
+// The policy applies to keys defined in the data read; keys set in the map
+// before reading don't count, and resetting the map (using a "blank" value)
+// also resets the definitions. Multi-valued map values are not affected.
+func DuplicateMapKeys(policy MapKeyPolicy, sep string) Option {
+	return func(o *options) {
+		o.mapKeys, o.mapKeySep = policy, sep
+	}
+}
+
+// ReplaceMultiValues returns an Option that makes the first value set for a
+// multi-valued variable in the data replace the values it already has (e.g.
+// from an earlier file; see ReadFilesInto), instead of being appended to them,
+// as if the variable was first reset using a "blank" value. Subsequent values
+// in the same data are appended as usual. Variables with the ",dotted" or
+// ",indexed" struct tag option are not affected.
+func ReplaceMultiValues() Option {
+	return func(o *options) {
+		o.replaceMulti = true
+	}
+}
+
+// StrictTypes returns an Option that disables parsing values using fmt.Sscanf
+// with the "%v" verb, the fallback for types that are not otherwise
+// supported; see the package documentation. Only the types with a dedicated
+// setter (see SupportedTypes), of string, bool and integer kinds, and
+// implementing encoding.TextUnmarshaler, as well as variables with the
+// ",setter=name" struct tag option, are then accepted; setting a variable of
+// another type (e.g. float64, or a type implementing only fmt.Scanner) is an
+// error wrapping ErrUnsupportedType. Using StrictTypes in tests turns
+// accidental reliance on the fallback into an error.
+func StrictTypes() Option {
+	return func(o *options) {
+		o.strictTypes = true
+	}
+}
+
+// IndentedContinuations returns an Option that makes a value continue on each
+// following line that is indented more than the line of the variable (as in
+// Python's configparser), unless the line is blank or a comment; the lines of
+// the value are joined with new lines, with their indentation removed:
+//
+//	[section]
+//	description = first line
+//	  second line
+//
+// sets the "description" variable to "first line\nsecond line". This eases
+// migrating configuration files written for that dialect; see also INIDialect.
+func IndentedContinuations() Option {
+	return func(o *options) {
+		o.continuations = true
+	}
+}
+
+// LiteralValues returns an Option that makes reading take the value of each
+// variable verbatim: everything after the first '=' up to a comment (starting
+// with ';' or '#') or the end of the line, without leading and trailing
+// whitespace. Double quotes and backslashes have no special meaning in it, so
+// that e.g.
+//
+//	[section]
+//	query = a=b&c="d"
+//	path = C:\dir
+//
+// sets the "query" variable to `a=b&c="d"` and the "path" variable to `C:\dir`.
+// Values can then contain neither ';' nor '#', nor span several lines (other
+// than with IndentedContinuations).
+//
+// By default, '=' in values is also taken literally, as in git config, but
+// double quotes and escape sequences are processed; see the package
+// documentation.
+func LiteralValues() Option {
+	return func(o *options) {
+		o.literalValues = true
+	}
+}
+
+// NameFolding returns an Option that sets how the section and variable names
+// in the data are matched with the struct fields: names match if fold maps
+// them to the same string. By default, names match if they are equal under
+// Unicode case folding (see strings.EqualFold), under which e.g. the Kelvin
+// sign 'K' matches 'k', but the Turkish dotted capital 'İ' doesn't match 'i'.
+// Using FoldASCII, only ASCII letters are matched case-insensitively, so that
+// matching is predictable for names in any script.
+func NameFolding(fold func(string) string) Option {
+	return func(o *options) {
+		o.fold = fold
+	}
+}
+
+// FoldASCII returns s with ASCII letters mapped to lower case, leaving other
+// characters unchanged; see NameFolding.
+func FoldASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+// Columns returns an Option that sets how column numbers are computed in the
+// positions reported in errors; e.g. to have them match the columns displayed
+// by an editor for lines containing tabs or multi-byte characters.
+func Columns(mode token.ColumnMode) Option {
+	return func(o *options) {
+		o.columns = mode
+	}
+}
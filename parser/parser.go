@@ -0,0 +1,400 @@
+// Package parser implements a parser for gcfg configuration files, producing
+// syntax trees (see package ast) that retain comments and positions.
+//
+// Note that the API for the parser package may change to accommodate new
+// features or implementation changes in gcfg.
+package parser
+
+import (
+	"strings"
+
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/scanner"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// A Mode value is a set of flags (or 0). They control the amount of source
+// code parsed and other optional parser functionality.
+type Mode uint
+
+const (
+	ParseComments Mode = 1 << iota // parse comments and add them to the AST
+	ParseSegments                  // record the segments of values in the AST
+)
+
+// ParseFile parses the source of a single gcfg file and returns the
+// corresponding ast.File node. The source is added to fset as a file named
+// filename (which is only used for positions, and may be empty).
+//
+// If the source couldn't be parsed, the returned AST is incomplete, and the
+// error is a scanner.ErrorList sorted by position.
+//
+// With the ParseComments mode, comments are associated with the nodes they
+// document: a group of comments on the lines directly preceding a section
+// header or a variable (with no blank line in between) becomes the Doc of
+// that node, and a comment following it on the same line becomes its
+// Comment. All comments, associated or not, are listed in File.Comments.
+// Blank lines are counted in the Blank fields of the nodes and comment groups
+// they precede, and of the File for those at the end, so that together with
+// the positions (e.g. of the "." or "[" of a key), the source can be
+// reproduced from the tree.
+//
+// With the ParseSegments mode, the segments of each variable value (quoted
+// strings, and the unquoted text between them) are recorded with their
+// positions in Variable.Segments; e.g. for `name = "val" "ue"`, the segments
+// are `"val"`, ` ` and `"ue"`.
+func ParseFile(fset *token.FileSet, filename string, src []byte,
+	mode Mode) (*ast.File, error) {
+	//
+	var p parser
+	p.init(fset, filename, src, mode)
+	f := p.parseFile()
+	p.errors.Sort()
+	return f, p.errors.Err()
+}
+
+type parser struct {
+	file    *token.File
+	src     []byte
+	errors  scanner.ErrorList
+	scanner scanner.Scanner
+	mode    Mode
+
+	// next token
+	pos token.Pos
+	tok token.Token
+	lit string
+
+	prevLine int // line of the end of the previous non-comment token
+
+	// comments
+	comments    []*ast.CommentGroup
+	leadComment *ast.CommentGroup // last lead comment
+	lineComment *ast.CommentGroup // last line comment
+}
+
+func (p *parser) init(fset *token.FileSet, filename string, src []byte,
+	mode Mode) {
+	//
+	p.file = fset.AddFile(filename, fset.Base(), len(src))
+	p.src = src
+	eh := func(pos token.Position, msg string) { p.errors.Add(pos, msg) }
+	sm := scanner.ScanComments
+	if mode&ParseSegments != 0 {
+		sm |= scanner.ScanSegments
+	}
+	p.scanner.Init(p.file, src, eh, sm)
+	p.mode = mode
+	p.next()
+}
+
+func (p *parser) line(pos token.Pos) int {
+	return p.file.Line(pos)
+}
+
+// endLine returns the line of the end of the current token.
+func (p *parser) endLine() int {
+	return p.line(p.pos) + strings.Count(p.lit, "\n")
+}
+
+// next0 advances to the next token, skipping newlines; statements are
+// terminated based on line numbers instead.
+func (p *parser) next0() {
+	for {
+		p.pos, p.tok, p.lit = p.scanner.Scan()
+		if p.tok != token.EOL {
+			return
+		}
+	}
+}
+
+// consumeComment consumes a comment and returns it and the line on which it
+// ends.
+func (p *parser) consumeComment() (comment *ast.Comment, endline int) {
+	endline = p.line(p.pos)
+	comment = &ast.Comment{Start: p.pos, Text: p.lit}
+	p.next0()
+	return
+}
+
+// consumeCommentGroup consumes a group of adjacent comments, adds it to the
+// parser's comments list, and returns it. A non-comment token or n empty
+// lines terminate a comment group.
+func (p *parser) consumeCommentGroup(n int) (comments *ast.CommentGroup,
+	endline int) {
+	//
+	var list []*ast.Comment
+	endline = p.line(p.pos)
+	for p.tok == token.COMMENT && p.line(p.pos) <= endline+n {
+		var comment *ast.Comment
+		comment, endline = p.consumeComment()
+		list = append(list, comment)
+	}
+	comments = &ast.CommentGroup{List: list}
+	if n > 0 { // not a line comment
+		comments.Blank = p.blankBefore(comments.Pos(), nil)
+	}
+	p.comments = append(p.comments, comments)
+	return
+}
+
+// next advances to the next non-comment token. In the process, it collects
+// any comment groups encountered, and remembers the last lead and line
+// comments.
+//
+// A lead comment is a comment group that ends on the line directly preceding
+// the next non-comment token. A line comment is a comment group that follows
+// a non-comment token on the same line.
+func (p *parser) next() {
+	p.leadComment = nil
+	p.lineComment = nil
+	if p.tok != token.COMMENT && p.pos.IsValid() {
+		p.prevLine = p.endLine()
+	}
+	p.next0()
+	if p.tok != token.COMMENT {
+		return
+	}
+	var comment *ast.CommentGroup
+	var endline int
+	if p.prevLine > 0 && p.line(p.pos) == p.prevLine {
+		// the comment is on the same line as the previous token; it
+		// cannot be a lead comment but may be a line comment
+		comment, endline = p.consumeCommentGroup(0)
+		p.lineComment = comment
+	}
+	// consume successor comments, if any
+	endline = -1
+	for p.tok == token.COMMENT {
+		comment, endline = p.consumeCommentGroup(1)
+	}
+	if endline+1 == p.line(p.pos) {
+		// the next token is following on the line immediately after the
+		// comment group, thus the last comment group is a lead comment
+		p.leadComment = comment
+	}
+}
+
+// blankBefore returns the number of blank (or whitespace-only) lines directly
+// preceding the line containing the node at pos, or its doc comment if any.
+func (p *parser) blankBefore(pos token.Pos, doc *ast.CommentGroup) int {
+	if doc != nil {
+		pos = doc.Pos()
+	}
+	off := p.file.Offset(pos)
+	// start of the line containing pos
+	for off > 0 && p.src[off-1] != '\n' {
+		off--
+	}
+	n := 0
+	for off > 0 {
+		// off is at the start of a line; examine the previous one
+		end := off - 1
+		start := end
+		for start > 0 && p.src[start-1] != '\n' {
+			start--
+		}
+		if len(strings.TrimSpace(string(p.src[start:end]))) > 0 {
+			break
+		}
+		n++
+		off = start
+	}
+	return n
+}
+
+// blankAtEnd returns the number of blank (or whitespace-only) lines at the end
+// of the source, after its last non-blank line.
+func (p *parser) blankAtEnd() int {
+	if len(p.src) == 0 || p.src[len(p.src)-1] != '\n' {
+		return 0 // the last line is not blank, or incomplete
+	}
+	return p.blankBefore(p.file.Pos(len(p.src)), nil)
+}
+
+func (p *parser) error(pos token.Pos, msg string) {
+	p.errors.Add(p.file.Position(pos), msg)
+}
+
+func (p *parser) errorExpected(pos token.Pos, msg string) {
+	msg = "expected " + msg
+	if pos == p.pos {
+		// the error happened at the current position
+		if p.tok.IsLiteral() {
+			msg += ", found " + p.lit
+		} else {
+			msg += ", found '" + p.tok.String() + "'"
+		}
+	}
+	p.error(pos, msg)
+}
+
+func (p *parser) expect(tok token.Token) token.Pos {
+	pos := p.pos
+	if p.tok != tok {
+		p.errorExpected(pos, "'"+tok.String()+"'")
+	}
+	p.next() // make progress
+	return pos
+}
+
+// atLineEnd reports whether the current token is on a line after the
+// previous one (or EOF); that is, whether the previous statement has ended.
+func (p *parser) atLineEnd() bool {
+	return p.tok == token.EOF || p.line(p.pos) > p.prevLine ||
+		p.lineComment != nil
+}
+
+// skipLine skips tokens up to the end of the current line, for recovering
+// from errors.
+func (p *parser) skipLine() {
+	for !p.atLineEnd() {
+		p.next()
+	}
+}
+
+func (p *parser) parseIdent() *ast.Ident {
+	pos, name := p.pos, "_"
+	if p.tok == token.IDENT {
+		name = p.lit
+		p.next()
+	} else {
+		p.expect(token.IDENT) // use expect() error handling
+	}
+	return &ast.Ident{NamePos: pos, Name: name}
+}
+
+// basicLit returns the current STRING token as a literal, with the end of its
+// source text; the scanner may strip carriage returns from the literal, so
+// that is found by matching the literal against the source.
+func (p *parser) basicLit() *ast.BasicLit {
+	off := p.file.Offset(p.pos)
+	for i := 0; i < len(p.lit) && off < len(p.src); off++ {
+		if p.src[off] == p.lit[i] {
+			i++
+		}
+	}
+	return &ast.BasicLit{ValuePos: p.pos, Value: p.lit,
+		ValueEnd: p.file.Pos(off)}
+}
+
+func (p *parser) parseSection() *ast.Section {
+	s := &ast.Section{Doc: p.leadComment, Lbrack: p.pos}
+	s.Blank = p.blankBefore(s.Lbrack, s.Doc)
+	p.next()
+	s.Name = p.parseIdent()
+	if p.tok == token.STRING {
+		s.Sub = p.basicLit()
+		p.next()
+	}
+	s.Rbrack = p.pos
+	if p.tok != token.RBRACK {
+		p.errorExpected(p.pos, "']'")
+		p.skipLine()
+		return s
+	}
+	p.next()
+	s.Comment = p.lineComment
+	if !p.atLineEnd() {
+		p.errorExpected(p.pos, "EOL, EOF, or comment")
+		p.skipLine()
+	}
+	return s
+}
+
+func (p *parser) parseVariable() *ast.Variable {
+	v := &ast.Variable{Doc: p.leadComment}
+	v.Blank = p.blankBefore(p.pos, v.Doc)
+	v.Name = p.parseIdent()
+	if !p.atLineEnd() && (p.tok == token.PERIOD || p.tok == token.LBRACK) {
+		open := p.tok
+		v.Lkey = p.pos
+		p.next()
+		v.Key = p.parseIdent()
+		if open == token.LBRACK {
+			v.Rkey = p.expect(token.RBRACK)
+		}
+	}
+	if p.atLineEnd() {
+		v.Comment = p.lineComment
+		return v
+	}
+	if p.tok != token.ASSIGN && p.tok != token.ADD {
+		p.errorExpected(p.pos, "'='")
+		p.skipLine()
+		return v
+	}
+	v.TokPos, v.Tok = p.pos, p.tok
+	p.next()
+	if p.tok != token.STRING || p.atLineEnd() && p.lineComment == nil &&
+		p.line(p.pos) > p.prevLine {
+		p.errorExpected(p.pos, "value")
+		p.skipLine()
+		return v
+	}
+	v.Value = p.basicLit()
+	p.next()
+	if p.mode&ParseSegments != 0 {
+		v.Segments = []*ast.BasicLit{{ValuePos: v.Value.ValuePos,
+			Value: v.Value.Value, ValueEnd: v.Value.ValueEnd}}
+		// the scanner only returns consecutive strings for segments;
+		// concatenate in a builder to keep many segments linear
+		var b strings.Builder
+		b.WriteString(v.Value.Value)
+		for p.tok == token.STRING {
+			lit := p.basicLit()
+			v.Segments = append(v.Segments, lit)
+			v.Value.ValueEnd = lit.ValueEnd
+			b.WriteString(p.lit)
+			p.next()
+		}
+		v.Value.Value = b.String()
+	}
+	v.Comment = p.lineComment
+	if !p.atLineEnd() {
+		p.errorExpected(p.pos, "EOL, EOF, or comment")
+		p.skipLine()
+	}
+	return v
+}
+
+func (p *parser) parseFile() *ast.File {
+	f := &ast.File{}
+	var s *ast.Section
+	for p.tok != token.EOF {
+		switch p.tok {
+		case token.LBRACK:
+			s = p.parseSection()
+			f.Sections = append(f.Sections, s)
+		case token.IDENT:
+			if s == nil {
+				p.error(p.pos, "expected section header")
+				p.next()
+				p.skipLine()
+				continue
+			}
+			s.Vars = append(s.Vars, p.parseVariable())
+		default:
+			p.errorExpected(p.pos, "section header or variable declaration")
+			p.next()
+			p.skipLine()
+		}
+	}
+	f.Blank = p.blankAtEnd()
+	if p.mode&ParseComments != 0 {
+		f.Comments = p.comments
+	} else {
+		clearComments(f)
+	}
+	return f
+}
+
+// clearComments removes the comments associated with the nodes in f.
+func clearComments(f *ast.File) {
+	for _, s := range f.Sections {
+		s.Doc, s.Comment = nil, nil
+		for _, v := range s.Vars {
+			v.Doc, v.Comment = nil, nil
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+const commentSrc = `; file header
+
+; section doc
+; continued
+[section "sub"] ; section line
+; var doc
+name = value ; var line
+
+# detached
+
+other.key = value
+blank
+idx[0] += more # idx line
+`
+
+func TestParseFileComments(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "test.gcfg", []byte(commentSrc), ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Sections) != 1 {
+		t.Fatalf("got %d sections, wanted 1", len(f.Sections))
+	}
+	s := f.Sections[0]
+	if s.Name.Name != "section" || s.Sub == nil || s.Sub.Value != `"sub"` {
+		t.Errorf("section header: got %q %v", s.Name.Name, s.Sub)
+	}
+	if got, exp := s.Doc.Text(), "section doc\ncontinued"; got != exp {
+		t.Errorf("section Doc: got %q, wanted %q", got, exp)
+	}
+	if got, exp := s.Comment.Text(), "section line"; got != exp {
+		t.Errorf("section Comment: got %q, wanted %q", got, exp)
+	}
+	if len(s.Vars) != 4 {
+		t.Fatalf("got %d variables, wanted 4", len(s.Vars))
+	}
+	for i, tt := range []struct {
+		name, key, value string
+		tok              token.Token
+		doc, comment     string
+	}{
+		{"name", "", "value", token.ASSIGN, "var doc", "var line"},
+		{"other", "key", "value", token.ASSIGN, "", ""},
+		{"blank", "", "", token.ILLEGAL, "", ""},
+		{"idx", "0", "more", token.ADD, "", "idx line"},
+	} {
+		v := s.Vars[i]
+		var key, value string
+		if v.Key != nil {
+			key = v.Key.Name
+		}
+		if v.Value != nil {
+			value = v.Value.Value
+		}
+		if v.Name.Name != tt.name || key != tt.key || value != tt.value ||
+			v.Tok != tt.tok {
+			t.Errorf("var %d: got %q %q %v %q, wanted %q %q %v %q", i,
+				v.Name.Name, key, v.Tok, value, tt.name, tt.key, tt.tok, tt.value)
+		}
+		if got := v.Doc.Text(); got != tt.doc {
+			t.Errorf("var %d Doc: got %q, wanted %q", i, got, tt.doc)
+		}
+		if got := v.Comment.Text(); got != tt.comment {
+			t.Errorf("var %d Comment: got %q, wanted %q", i, got, tt.comment)
+		}
+	}
+	if n := len(f.Comments); n != 7 {
+		t.Errorf("got %d comment groups, wanted 7", n)
+	}
+}
+
+func TestParseFileNoComments(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "", []byte(commentSrc), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := f.Sections[0]
+	if f.Comments != nil || s.Doc != nil || s.Comment != nil ||
+		s.Vars[0].Doc != nil || s.Vars[0].Comment != nil {
+		t.Errorf("got comments without ParseComments")
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	for _, src := range []string{
+		"name = value\n",
+		"[section\n",
+		"[section]\n=value\n",
+		"[section] name\n",
+	} {
+		fset := token.NewFileSet()
+		if _, err := ParseFile(fset, "", []byte(src), 0); err == nil {
+			t.Errorf("%q: got no error", src)
+		}
+	}
+}
+
+func TestParseFileBlankLines(t *testing.T) {
+	src := "[a]\nx = 1\ny = 2\n\nz = 3\n\n\n; doc\nw\n\n[b]\n \t\nv = 4\n"
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "", []byte(src), ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, s := range f.Sections {
+		got = append(got, s.Blank)
+		for _, v := range s.Vars {
+			got = append(got, v.Blank)
+		}
+	}
+	exp := []int{0, 0, 0, 1, 2, 1, 1}
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("got blank lines %v, wanted %v", got, exp)
+	}
+	src = "\n\n# a\n[a]\nx = 1 ; line\n\n# b\n\n# c\ny\n"
+	f, err = ParseFile(fset, "", []byte(src), ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, g := range f.Comments {
+		got = append(got, g.Blank)
+	}
+	exp = []int{2, 0, 1, 1}
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("got blank lines before comments %v, wanted %v", got, exp)
+	}
+	for src, exp := range map[string]int{
+		"":                 0,
+		"[a]":              0,
+		"[a]\n":            0,
+		"[a]\n\n \n":       2,
+		"[a]\n\n; end\n\n": 1,
+		"[a]\n\n; end":     0,
+	} {
+		f, err := ParseFile(token.NewFileSet(), "", []byte(src), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Blank != exp {
+			t.Errorf("%q: got %d blank lines at the end, wanted %d", src,
+				f.Blank, exp)
+		}
+	}
+}
+
+func TestParseFileKeys(t *testing.T) {
+	src := "[a]\nx.k = 1\ny[ 0 ]\nz\n"
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "", []byte(src), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := func(p token.Pos) int {
+		if !p.IsValid() {
+			return -1
+		}
+		return fset.Position(p).Offset
+	}
+	for i, exp := range [][3]int{{5, -1, 11}, {13, 17, 18}, {-1, -1, 20}} {
+		v := f.Sections[0].Vars[i]
+		got := [3]int{offset(v.Lkey), offset(v.Rkey), offset(v.End())}
+		if got != exp {
+			t.Errorf("%s: got Lkey, Rkey, End offsets %v, wanted %v",
+				v.Name.Name, got, exp)
+		}
+	}
+}
+
+func TestParseFileSegments(t *testing.T) {
+	src := "[a]\nx = \"val\" \"ue\" ; comment\ny = plain\nz = a\"b\"\\\nc\n"
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "", []byte(src), ParseComments|ParseSegments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range [][]string{
+		{`"val"`, ` `, `"ue"`},
+		{`plain`},
+		{`a`, `"b"`, "\\\nc"},
+	} {
+		v := f.Sections[0].Vars[i]
+		var got []string
+		value := ""
+		for _, x := range v.Segments {
+			got = append(got, x.Value)
+			value += x.Value
+			off := fset.Position(x.Pos()).Offset
+			if end := off + len(x.Value); src[off:end] != x.Value {
+				t.Errorf("%s: got segment %q at %d, source has %q",
+					v.Name.Name, x.Value, off, src[off:end])
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(exp) || v.Value.Value != value {
+			t.Errorf("%s: got segments %q and value %q, wanted %q",
+				v.Name.Name, got, v.Value.Value, exp)
+		}
+	}
+	if c := f.Sections[0].Vars[0].Comment; c == nil || c.Text() != "comment" {
+		t.Errorf("got line comment %v, wanted %q", c, "comment")
+	}
+	f, _ = ParseFile(token.NewFileSet(), "", []byte(src), 0)
+	if v := f.Sections[0].Vars[0]; v.Segments != nil ||
+		v.Value.Value != `"val" "ue"` {
+		t.Errorf("got segments %v, value %q without ParseSegments",
+			v.Segments, v.Value.Value)
+	}
+}
+
+// BenchmarkParseSegments parses a value of many segments of increasing sizes;
+// as parsing is linear, the throughput shouldn't decrease with the size.
+func BenchmarkParseSegments(b *testing.B) {
+	for _, n := range []int{1e3, 1e4, 1e5} {
+		src := []byte("[section]\nname=" + strings.Repeat(`"x" `, n))
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			for i := 0; i < b.N; i++ {
+				_, err := ParseFile(token.NewFileSet(), "", src, ParseSegments)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
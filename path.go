@@ -0,0 +1,104 @@
+package gcfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+var errSubsectionUndefined = errors.New("subsection not defined")
+
+var errMultiValued = errors.New("variable is multi-valued")
+
+// Get returns the value of the variable path in config (a struct or a pointer
+// to a struct), formatted as when writing it (see Marshal) but not quoted,
+// for tools that access values by path without knowing the shape of config.
+// The path is as used by git config: "section.name", or
+// "section.subsection.name" for a section with subsections (which may contain
+// dots); e.g. "remote.origin.url". As the variable name is the part after the
+// last dot, dotted and indexed variables can't be accessed this way.
+//
+// The value of a nil pointer or interface variable is "". Get returns an error
+// wrapping ErrUnknownSection or ErrUnknownVariable if config has no field for
+// the section or variable, and an error if the subsection is not defined or
+// the variable is multi-valued.
+func Get(config interface{}, path string) (string, error) {
+	section, sub, name, err := splitKey(path)
+	if err != nil {
+		return "", err
+	}
+	vCfg := reflect.ValueOf(config)
+	if vCfg.Kind() == reflect.Ptr {
+		vCfg = vCfg.Elem()
+	}
+	if vCfg.Kind() != reflect.Struct {
+		panic(fmt.Errorf("config must be a struct or a pointer to a struct"))
+	}
+	if !vCfg.CanAddr() {
+		// fields are only matched if settable
+		v := reflect.New(vCfg.Type()).Elem()
+		v.Set(vCfg)
+		vCfg = v
+	}
+	l := loc{section: section}
+	if sub != "" {
+		l.subsection = &sub
+	}
+	vSect, _ := fieldFold(vCfg, section, nil)
+	switch {
+	case !vSect.IsValid() || vSect.Kind() == reflect.Struct && sub != "":
+		return "", extraData{loc: l}
+	case vSect.Kind() == reflect.Map:
+		l.subsection = &sub
+		pv := vSect.MapIndex(reflect.ValueOf(sub))
+		if !pv.IsValid() || pv.IsNil() {
+			return "", locErr{err: errSubsectionUndefined, loc: l}
+		}
+		vSect = pv.Elem()
+	}
+	l.variable = &name
+	vVar, t := fieldFold(vSect, name, nil)
+	if !vVar.IsValid() || t.any {
+		return "", extraData{loc: l}
+	}
+	if isMultiVal(vVar.Type()) {
+		return "", locErr{err: errMultiValued, loc: l}
+	}
+	if vVar.Kind() == reflect.Interface ||
+		vVar.Type().Name() == "" && vVar.Kind() == reflect.Ptr {
+		//
+		if vVar.IsNil() {
+			return "", nil
+		}
+		vVar = vVar.Elem()
+	}
+	s, err := NewEncoder(nil).formatValue(vVar, t)
+	if err != nil {
+		return "", locErr{err: err, loc: l}
+	}
+	return unquote(s), nil
+}
+
+// Set sets the variable path (as for Get) in config (a pointer to a struct) to
+// value, as if the definition `name = value` was read in the section (so that
+// e.g. the value is appended to a multi-valued variable), creating the
+// subsection if needed. Set returns the same errors as when reading, with
+// unknown sections and variables being fatal errors.
+func Set(config interface{}, path, value string) error {
+	section, sub, name, err := splitKey(path)
+	if err != nil {
+		return err
+	}
+	c := newCollector(&options{unknown: UnknownStrict})
+	// only the pass for the kind of section field (struct or map) sets it
+	for _, subsectPass := range []bool{false, true} {
+		err := set(c, config, section, sub, name, false, value, nil,
+			subsectPass, header{}, token.Position{})
+		if err != nil {
+			return err
+		}
+	}
+	return c.Done()
+}
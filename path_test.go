@@ -0,0 +1,88 @@
+package gcfg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetSet(t *testing.T) {
+	type config struct {
+		Core struct {
+			Name    string
+			Port    int
+			Timeout time.Duration
+			Debug   *bool
+			Multi   []string
+		}
+		Remote map[string]*struct {
+			URL  string
+			Port int `gcfg:",min=1"`
+		}
+	}
+	cfg := &config{}
+	for _, tt := range []struct{ path, value string }{
+		{"core.name", "a ; b"},
+		{"CORE.port", "8080"},
+		{"core.timeout", "1m30s"},
+		{"core.debug", "true"},
+		{"core.multi", "x"},
+		{"core.multi", "y"},
+		{"remote.my.origin.url", "https://example.com"},
+	} {
+		if err := Set(cfg, tt.path, tt.value); err != nil {
+			t.Errorf("Set(%q): %v", tt.path, err)
+		}
+	}
+	if !reflect.DeepEqual(cfg.Core.Multi, []string{"x", "y"}) ||
+		cfg.Remote["my.origin"] == nil {
+		t.Errorf("got %+v", cfg)
+	}
+	for _, tt := range []struct{ path, exp string }{
+		{"core.name", "a ; b"},
+		{"core.port", "8080"},
+		{"core.timeout", "1m30s"},
+		{"core.debug", "true"},
+		{"remote.my.origin.url", "https://example.com"},
+		{"remote.my.origin.port", "0"},
+	} {
+		if got, err := Get(*cfg, tt.path); err != nil || got != tt.exp {
+			t.Errorf("Get(%q): got %q, %v, expected %q", tt.path, got, err,
+				tt.exp)
+		}
+	}
+
+	for _, tt := range []struct {
+		path string
+		err  error
+	}{
+		{"none.name", ErrUnknownSection},
+		{"core.none", ErrUnknownVariable},
+		{"core.sub.name", ErrUnknownSection},
+		{"remote.none.url", errSubsectionUndefined},
+		{"core.multi", errMultiValued},
+	} {
+		if _, err := Get(cfg, tt.path); !errors.Is(err, tt.err) {
+			t.Errorf("Get(%q): got error %v, expected %v", tt.path, err, tt.err)
+		}
+	}
+	for _, tt := range []struct {
+		path, value string
+		err         error
+	}{
+		{"none.name", "x", ErrUnknownSection},
+		{"core.none", "x", ErrUnknownVariable},
+		{"core.port", "x", nil},
+		{"remote.my.origin.port", "0", nil},
+	} {
+		err := Set(cfg, tt.path, tt.value)
+		if err == nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("Set(%q, %q): got error %v, expected %v", tt.path,
+				tt.value, err, tt.err)
+		}
+	}
+	if _, err := Get(cfg, "core"); err == nil {
+		t.Errorf("got no error for invalid path")
+	}
+}
@@ -0,0 +1,64 @@
+package gcfg
+
+import "strings"
+
+// knownOS and knownArch list the GOOS and GOARCH values recognized in the
+// names of platform-specific subsections.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "illumos": true, "ios": true, "js": true,
+		"linux": true, "netbsd": true, "openbsd": true, "plan9": true,
+		"solaris": true, "wasip1": true, "windows": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true,
+		"loong64": true, "mips": true, "mips64": true, "mips64le": true,
+		"mipsle": true, "ppc64": true, "ppc64le": true, "riscv64": true,
+		"s390x": true, "wasm": true,
+	}
+)
+
+// platform is the platform selected using the Platform option.
+type platform struct {
+	goos, goarch string
+}
+
+// Platform returns an Option that enables platform-specific values, selected
+// for the platform with the given GOOS and GOARCH values (typically
+// runtime.GOOS and runtime.GOARCH).
+//
+// With this option, a section that has no subsections in the config struct
+// can have subsections named after a GOOS value, a GOARCH value, or both
+// separated by a slash; e.g. `[paths "windows"]`, `[paths "arm64"]` or
+// `[paths "linux/amd64"]`. Values in subsections that match the selected
+// platform are set into the section, as if they were defined in the section
+// itself; other platform-specific subsections are ignored. As values are set
+// in the order they appear in, platform-specific subsections should follow
+// the section they override values of:
+//
+//	[paths]
+//	cache = /var/cache/app
+//
+//	[paths "windows"]
+//	cache = "C:\\ProgramData\\app"
+func Platform(goos, goarch string) Option {
+	return func(o *options) {
+		o.platform = &platform{goos, goarch}
+	}
+}
+
+// match reports whether the subsection name sub is platform-specific (known),
+// and if so, whether it matches the platform p.
+func (p *platform) match(sub string) (match, known bool) {
+	os, arch, hasArch := strings.Cut(sub, "/")
+	switch {
+	case hasArch:
+		return os == p.goos && arch == p.goarch, knownOS[os] && knownArch[arch]
+	case knownOS[sub]:
+		return sub == p.goos, true
+	case knownArch[sub]:
+		return sub == p.goarch, true
+	}
+	return false, false
+}
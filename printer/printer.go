@@ -0,0 +1,300 @@
+// Package printer implements printing of syntax trees (see package ast) of
+// gcfg configuration files, so that tools can edit a file programmatically
+// without destroying its hand-written layout.
+//
+// Note that the API for the printer package may change to accommodate new
+// features or implementation changes in gcfg.
+package printer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// A Config controls the output of Fprint.
+type Config struct {
+	Indent string // indentation of variables; "\t" if empty
+	Src    []byte // source the syntax tree was parsed from; or nil
+}
+
+// Fprint prints the syntax tree f, whose positions are relative to fset, to
+// output, using the default Config. See Config.Fprint.
+func Fprint(output io.Writer, fset *token.FileSet, f *ast.File) error {
+	return (&Config{}).Fprint(output, fset, f)
+}
+
+// Fprint prints the syntax tree f, whose positions are relative to fset, to
+// output. Each section header, variable definition and comment is printed on
+// a line of its own, preceded by the number of blank lines recorded in the
+// Blank fields of the nodes and comment groups, and comments that are not
+// associated with any node (see parser.ParseComments) are printed in the
+// order of their positions; so the layout of the file is kept, also for nodes
+// that are added without positions.
+//
+// If cfg.Src is set, lines whose nodes are unchanged (that is, whose text is
+// still found at their positions in cfg.Src) are copied from it verbatim,
+// including their whitespace (and the lack of a final newline at the end of
+// cfg.Src), so that parsing and printing a file reproduces it; other lines
+// are printed in the canonical form, as Marshal does.
+func (cfg *Config) Fprint(output io.Writer, fset *token.FileSet,
+	f *ast.File) error {
+	//
+	p := printer{Config: *cfg, fset: fset, attached: map[*ast.CommentGroup]bool{}}
+	if p.Indent == "" {
+		p.Indent = "\t"
+	}
+	for _, s := range f.Sections {
+		p.attached[s.Doc], p.attached[s.Comment] = true, true
+		for _, v := range s.Vars {
+			p.attached[v.Doc], p.attached[v.Comment] = true, true
+		}
+	}
+	p.comments = f.Comments
+	for _, s := range f.Sections {
+		p.section(s)
+		for _, v := range s.Vars {
+			p.variable(v)
+		}
+	}
+	p.floating(token.NoPos, true)
+	p.blank(f.Blank, p.endPos(f))
+	_, err := output.Write(p.out.Bytes())
+	return err
+}
+
+type printer struct {
+	Config
+	fset     *token.FileSet
+	attached map[*ast.CommentGroup]bool // comments associated with nodes
+	comments []*ast.CommentGroup        // comments not yet printed
+	inSect   bool                       // whether a section has been printed
+	last     token.Pos                  // end of the last node printed; or NoPos
+	out      bytes.Buffer
+}
+
+// A tok is the text of a token at a position, for checking against the
+// source.
+type tok struct {
+	pos  token.Pos
+	text string
+}
+
+// offset returns the offset in the source of pos; -1 if it's not in it.
+func (p *printer) offset(pos token.Pos) int {
+	if p.Src == nil || !pos.IsValid() {
+		return -1
+	}
+	offs := p.fset.Position(pos).Offset
+	if offs > len(p.Src) {
+		return -1
+	}
+	return offs
+}
+
+// endPos returns the position of the end of the source of f; NoPos if not
+// known.
+func (p *printer) endPos(f *ast.File) token.Pos {
+	for _, pos := range []token.Pos{f.Pos(), p.last} {
+		if file := p.fset.File(pos); file != nil && pos.IsValid() {
+			return file.Pos(file.Size())
+		}
+	}
+	return token.NoPos
+}
+
+// source returns the line (or lines, for continued values) of the source
+// holding the tokens toks, with its newline (if any; the last line of the
+// source may lack one), if the tokens are unchanged and only whitespace
+// surrounds them.
+func (p *printer) source(toks []tok) (string, bool) {
+	start, end := -1, -1
+	for _, t := range toks {
+		offs := p.offset(t.pos)
+		if offs < 0 || offs+len(t.text) > len(p.Src) ||
+			string(p.Src[offs:offs+len(t.text)]) != t.text {
+			return "", false
+		}
+		prev := end
+		if start < 0 {
+			start = lineStart(p.Src, offs)
+			prev = start
+		}
+		if offs < prev || !isSpace(p.Src[prev:offs]) {
+			return "", false
+		}
+		end = offs + len(t.text)
+	}
+	if start < 0 {
+		return "", false
+	}
+	lend := end + bytes.IndexByte(p.Src[end:], '\n') + 1
+	if lend <= end {
+		lend = len(p.Src)
+	}
+	if !isSpace(bytes.TrimSuffix(p.Src[end:lend], []byte("\n"))) {
+		return "", false
+	}
+	return string(p.Src[start:lend]), true
+}
+
+// isSpace reports whether b is only whitespace (excluding newlines).
+func isSpace(b []byte) bool {
+	return len(bytes.Trim(b, " \t\r\f\v")) == 0
+}
+
+// lineStart returns the offset of the start of the line containing offs.
+func lineStart(src []byte, offs int) int {
+	return bytes.LastIndexByte(src[:offs], '\n') + 1
+}
+
+// line prints a line: from the source if toks are unchanged in it, or else
+// as canonical.
+func (p *printer) line(toks []tok, canonical string) {
+	p.terminate()
+	if s, ok := p.source(toks); ok {
+		p.out.WriteString(s)
+	} else {
+		p.out.WriteString(canonical + "\n")
+	}
+	if t := toks[len(toks)-1]; t.pos.IsValid() {
+		p.last = t.pos + token.Pos(len(t.text))
+	}
+}
+
+// terminate ends the last line printed with a newline, if it was copied from
+// the end of a source without a final newline.
+func (p *printer) terminate() {
+	if b := p.out.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		p.out.WriteByte('\n')
+	}
+}
+
+// blank prints n blank lines before the line at pos: from the source, if it
+// has n whitespace-only lines there.
+func (p *printer) blank(n int, pos token.Pos) {
+	if n == 0 {
+		return
+	}
+	p.terminate()
+	if offs := p.offset(pos); offs >= 0 {
+		end := lineStart(p.Src, offs)
+		start := end
+		for i := 0; i < n && start > 0; i++ {
+			start = lineStart(p.Src, start-1)
+		}
+		b := p.Src[start:end]
+		if bytes.Count(b, []byte("\n")) == n && len(bytes.TrimSpace(b)) == 0 {
+			p.out.Write(b)
+			return
+		}
+	}
+	p.out.WriteString(strings.Repeat("\n", n))
+}
+
+// floating prints the comments not associated with any node before pos (or
+// all the remaining ones, if all is set), with the blank lines before them.
+func (p *printer) floating(pos token.Pos, all bool) {
+	if !pos.IsValid() && !all {
+		return // a new node; keep the comments for the next one
+	}
+	for len(p.comments) > 0 {
+		g := p.comments[0]
+		if !all && g.Pos() >= pos {
+			return
+		}
+		p.comments = p.comments[1:]
+		if p.attached[g] {
+			continue
+		}
+		p.blank(g.Blank, g.Pos())
+		p.commentGroup(g)
+	}
+}
+
+// commentGroup prints the comments of g on lines of their own.
+func (p *printer) commentGroup(g *ast.CommentGroup) {
+	if g == nil {
+		return
+	}
+	indent := ""
+	if p.inSect {
+		indent = p.Indent
+	}
+	for _, c := range g.List {
+		p.line([]tok{{c.Start, c.Text}}, indent+c.Text)
+	}
+}
+
+// lineComment returns the tokens and the canonical text of the line comment g
+// following a node.
+func lineComment(g *ast.CommentGroup) ([]tok, string) {
+	if g == nil {
+		return nil, ""
+	}
+	var toks []tok
+	var texts []string
+	for _, c := range g.List {
+		toks = append(toks, tok{c.Start, c.Text})
+		texts = append(texts, c.Text)
+	}
+	return toks, " " + strings.Join(texts, " ")
+}
+
+func (p *printer) section(s *ast.Section) {
+	p.floating(s.Pos(), false)
+	if s.Doc != nil {
+		p.blank(s.Blank, s.Doc.Pos())
+	} else {
+		p.blank(s.Blank, s.Pos())
+	}
+	p.inSect = false
+	p.commentGroup(s.Doc)
+	toks := []tok{{s.Lbrack, "["}, {s.Name.NamePos, s.Name.Name}}
+	text := "[" + s.Name.Name
+	if s.Sub != nil {
+		toks = append(toks, tok{s.Sub.ValuePos, s.Sub.Value})
+		text += " " + s.Sub.Value
+	}
+	toks = append(toks, tok{s.Rbrack, "]"})
+	text += "]"
+	ctoks, ctext := lineComment(s.Comment)
+	p.line(append(toks, ctoks...), text+ctext)
+	p.inSect = true
+}
+
+func (p *printer) variable(v *ast.Variable) {
+	p.floating(v.Pos(), false)
+	if v.Doc != nil {
+		p.blank(v.Blank, v.Doc.Pos())
+	} else {
+		p.blank(v.Blank, v.Pos())
+	}
+	p.commentGroup(v.Doc)
+	toks := []tok{{v.Name.NamePos, v.Name.Name}}
+	text := v.Name.Name
+	if v.Key != nil {
+		if v.Rkey.IsValid() {
+			toks = append(toks, tok{v.Lkey, "["}, tok{v.Key.NamePos, v.Key.Name},
+				tok{v.Rkey, "]"})
+			text += "[" + v.Key.Name + "]"
+		} else {
+			toks = append(toks, tok{v.Lkey, "."}, tok{v.Key.NamePos, v.Key.Name})
+			text += "." + v.Key.Name
+		}
+	}
+	if v.Value != nil {
+		op := token.ASSIGN
+		if v.Tok == token.ADD {
+			op = token.ADD
+		}
+		toks = append(toks, tok{v.TokPos, op.String()},
+			tok{v.Value.ValuePos, v.Value.Value})
+		text += " " + op.String() + " " + v.Value.Value
+	}
+	ctoks, ctext := lineComment(v.Comment)
+	p.line(append(toks, ctoks...), p.Indent+text+ctext)
+}
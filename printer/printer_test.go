@@ -0,0 +1,157 @@
+package printer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/parser"
+	"gopkg.in/gcfg.v1/token"
+)
+
+const src = `; file header
+
+; section doc
+[section "sub"]   ; section line
+  ; var doc
+  name   =  value ; var line
+	other.key += "quoted ; value" # other line
+
+  # detached
+  cont = a \
+    b
+idx[ 0 ]` + "\r\n" + `blank  ` + `
+
+[empty]
+
+; trailing
+
+
+`
+
+func parse(t *testing.T, src []byte) (*token.FileSet, *ast.File) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fset, f
+}
+
+func TestFprintRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../testdata/*.gcfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string][]byte{
+		"src":            []byte(src),
+		"leading blank":  []byte("\n\n# d\n\n[a]\n"),
+		"no final EOL":   []byte("[a]\nx = 1"),
+		"no final EOL 2": []byte("[a]\nx = 1\n; end"),
+	}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[fn] = b
+	}
+	for name, src := range sources {
+		fset, f := parse(t, src)
+		var b bytes.Buffer
+		if err := (&Config{Src: src}).Fprint(&b, fset, f); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != string(src) {
+			t.Errorf("%s: got\n%q\nexpected\n%q", name, b.String(), src)
+		}
+	}
+}
+
+func TestFprintNoFinalNewline(t *testing.T) {
+	src := []byte("[a]\nx = 1")
+	fset, f := parse(t, src)
+	f.Sections[0].Vars = append(f.Sections[0].Vars, &ast.Variable{
+		Name: &ast.Ident{Name: "y"}, Tok: token.ASSIGN,
+		Value: &ast.BasicLit{Value: "2"}})
+	var b bytes.Buffer
+	if err := (&Config{Src: src}).Fprint(&b, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[a]\nx = 1\n\ty = 2\n"; b.String() != exp {
+		t.Errorf("got\n%q\nexpected\n%q", b.String(), exp)
+	}
+}
+
+func TestFprintEdits(t *testing.T) {
+	fset, f := parse(t, []byte(src))
+	s := f.Sections[0]
+	s.Vars[0].Value.Value = "changed"
+	s.Vars = append(s.Vars[:2], s.Vars[3:]...) // remove cont
+	s.Vars = append(s.Vars, &ast.Variable{Name: &ast.Ident{Name: "added"},
+		Tok: token.ASSIGN, Value: &ast.BasicLit{Value: "1"}})
+	f.Sections = append(f.Sections, &ast.Section{Blank: 1,
+		Name: &ast.Ident{Name: "new"}, Vars: []*ast.Variable{
+			{Name: &ast.Ident{Name: "x"}, Key: &ast.Ident{Name: "k"}}}})
+	exp := `; file header
+
+; section doc
+[section "sub"]   ; section line
+  ; var doc
+	name = changed ; var line
+	other.key += "quoted ; value" # other line
+
+  # detached
+idx[ 0 ]` + "\r\n" + `blank  ` + `
+	added = 1
+
+[empty]
+
+[new]
+	x.k
+
+; trailing
+
+
+`
+	var b bytes.Buffer
+	if err := (&Config{Src: []byte(src)}).Fprint(&b, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != exp {
+		t.Errorf("got\n%s\nexpected\n%s", b.String(), exp)
+	}
+}
+
+func TestFprintCanonical(t *testing.T) {
+	fset, f := parse(t, []byte(src))
+	exp := `; file header
+
+; section doc
+[section "sub"] ; section line
+	; var doc
+	name = value ; var line
+	other.key += "quoted ; value" # other line
+
+	# detached
+	cont = a \
+    b
+	idx[0]
+	blank
+
+[empty]
+
+	; trailing
+
+
+`
+	var b bytes.Buffer
+	if err := Fprint(&b, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != exp {
+		t.Errorf("got\n%s\nexpected\n%s", b.String(), exp)
+	}
+}
@@ -4,66 +4,165 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/gcfg.v1/scanner"
 	"gopkg.in/gcfg.v1/token"
-	"gopkg.in/warnings.v0"
+	warnings "gopkg.in/warnings.v0"
 )
 
 var unescape = map[rune]rune{'\\': '\\', '"': '"', 'n': '\n', 't': '\t'}
 var utf8Bom = []byte("\ufeff")
 
-// no error: invalid literals should be caught by scanner
-func unquote(s string) string {
-	u, q, esc := make([]rune, 0, len(s)), false, false
-	for _, c := range s {
-		if esc {
-			uc, ok := unescape[c]
-			switch {
-			case ok:
-				u = append(u, uc)
-				fallthrough
-			case !q && c == '\n':
-				esc = false
+// Unquote returns the value represented by s, a variable value as it appears
+// in gcfg formatted data (after the equals sign, without leading and trailing
+// whitespace), interpreting it exactly as the Read*Into functions do: quoted
+// segments are concatenated with the unquoted text around them, the escape
+// sequences \\, \", \n and \t are processed within quotes, and \" and line
+// continuations (backslash followed by new line) outside of them. As in the
+// data, carriage returns are ignored. Unquote returns a *SyntaxError (with
+// Pos.Offset set to the byte offset in s) if s is not a valid value. Quoted
+// subsection names are unquoted the same way.
+func Unquote(s string) (string, error) {
+	if !strings.ContainsAny(s, "\"\\\r") {
+		return s, nil // nothing to unquote; avoid copying
+	}
+	if n := len(s); n >= 2 && s[0] == '"' && s[n-1] == '"' &&
+		!strings.ContainsAny(s[1:n-1], "\"\\\r\n") {
+		//
+		return s[1 : n-1], nil // a single quoted string without escapes
+	}
+	var u strings.Builder
+	u.Grow(len(s))
+	q, esc, qOffs := false, false, 0
+	for i, c := range s {
+		switch {
+		case c == '\r':
+			continue
+		case esc:
+			esc = false
+			if uc, ok := unescape[c]; ok && (q || c == '"') {
+				u.WriteRune(uc)
 				continue
 			}
-			panic("invalid escape sequence")
-		}
-		switch c {
-		case '"':
-			q = !q
-		case '\\':
+			if !q && c == '\n' { // line continuation
+				continue
+			}
+			if q {
+				return u.String(), unquoteErr(i-1,
+					"unknown escape sequence")
+			}
+			return u.String(), unquoteErr(i-1, "unquoted '\\' must be "+
+				"followed by new line or double quote")
+		case c == '"':
+			q, qOffs = !q, i
+		case c == '\\':
 			esc = true
+		case q && c == '\n':
+			return u.String(), unquoteErr(qOffs, "string not terminated")
 		default:
-			u = append(u, c)
+			u.WriteRune(c)
 		}
 	}
+	if esc {
+		return u.String(), unquoteErr(strings.LastIndexByte(s, '\\'),
+			"unquoted '\\' must be followed by new line or double quote")
+	}
 	if q {
-		panic("missing end quote")
+		return u.String(), unquoteErr(qOffs, "string not terminated")
 	}
-	if esc {
-		panic("invalid escape sequence")
+	return u.String(), nil
+}
+
+func unquoteErr(offset int, msg string) error {
+	return &SyntaxError{Pos: token.Position{Offset: offset},
+		Msg: fmt.Sprintf("%s at offset %d", msg, offset)}
+}
+
+// unquote is Unquote for literals returned by the scanner; invalid literals
+// are reported by the scanner, so the error is not of interest.
+func unquote(s string) string {
+	u, _ := Unquote(s)
+	return u
+}
+
+// collectScanErrs collects the errors reported by the scanner as syntax errors
+// and resets errs.
+func collectScanErrs(c *collector, errs *scanner.ErrorList) error {
+	defer errs.Reset()
+	for _, e := range *errs {
+		if err := c.Collect(&SyntaxError{Pos: e.Pos, Msg: e.Msg}); err != nil {
+			return err
+		}
 	}
-	return string(u)
+	return nil
 }
 
-func readIntoPass(c *warnings.Collector, config interface{}, fset *token.FileSet,
-	file *token.File, src []byte, subsectPass bool) error {
+func readIntoPass(c *collector, o *options, config interface{},
+	fset *token.FileSet, file *token.File, src []byte, subsectPass bool,
+	st *Stats, seen map[varKey]bool) error {
 	//
 	var s scanner.Scanner
 	var errs scanner.ErrorList
-	s.Init(file, src, func(p token.Position, m string) { errs.Add(p, m) }, 0)
+	var mode scanner.Mode
+	if o.continuations {
+		mode |= scanner.ScanContinuations
+	}
+	if o.literalValues {
+		mode |= scanner.ScanLiteralValues
+	}
+	s.Init(file, src, func(p token.Position, m string) { errs.Add(p, m) }, mode)
+	// calls are guarded by tracing to avoid evaluating (and allocating)
+	// the arguments when not logging
+	tracing := o.logger != nil
+	trace := func(msg string, args ...interface{}) {
+		o.logger.Debug(msg, append(args, "subsectPass", subsectPass)...)
+	}
+	pass := 1
+	if subsectPass {
+		pass = 2
+	}
+	next := o.progressEvery // offset at which to report progress next
+	scan := func() (token.Pos, token.Token, string) {
+		pos, tok, lit := s.Scan()
+		if o.progress != nil {
+			off := len(src)
+			if tok != token.EOF {
+				off = file.Offset(pos)
+			}
+			if off >= next {
+				if next = (off/o.progressEvery + 1) * o.progressEvery; next > len(src) {
+					next = len(src)
+				}
+				if off == len(src) {
+					next = len(src) + 1 // reported the end
+				}
+				o.progress(Progress{Pass: pass, Bytes: off,
+					Total: len(src), Lines: file.Line(pos)})
+			}
+		}
+		if tracing {
+			trace("gcfg: scanned token", "pos", fset.Position(pos).String(),
+				"tok", tok.String(), "lit", lit)
+		}
+		return pos, tok, lit
+	}
 	sect, sectsub := "", ""
-	pos, tok, lit := s.Scan()
-	errfn := func(msg string) error {
-		return fmt.Errorf("%s: %s", fset.Position(pos), msg)
+	var hdr header
+	pos, tok, lit := scan()
+	errfn := func(msg string, expected ...token.Token) error {
+		return &SyntaxError{Pos: fset.Position(pos), Got: tok,
+			Expected: expected, Msg: msg}
 	}
 	for {
 		if errs.Len() > 0 {
-			if err := c.Collect(errs.Err()); err != nil {
+			if err := collectScanErrs(c, &errs); err != nil {
 				return err
 			}
 		}
@@ -71,154 +170,375 @@ func readIntoPass(c *warnings.Collector, config interface{}, fset *token.FileSet
 		case token.EOF:
 			return nil
 		case token.EOL, token.COMMENT:
-			pos, tok, lit = s.Scan()
+			pos, tok, lit = scan()
 		case token.LBRACK:
-			pos, tok, lit = s.Scan()
+			hdr = header{lbrack: fset.Position(pos)}
+			if st != nil {
+				st.Sections++
+			}
+			pos, tok, lit = scan()
 			if errs.Len() > 0 {
-				if err := c.Collect(errs.Err()); err != nil {
+				if err := collectScanErrs(c, &errs); err != nil {
 					return err
 				}
 			}
 			if tok != token.IDENT {
-				if err := c.Collect(errfn("expected section name")); err != nil {
+				if err := c.Collect(errfn("expected section name", token.IDENT)); err != nil {
 					return err
 				}
 			}
 			sect, sectsub = lit, ""
-			pos, tok, lit = s.Scan()
+			hdr.name = fset.Position(pos)
+			pos, tok, lit = scan()
 			if errs.Len() > 0 {
-				if err := c.Collect(errs.Err()); err != nil {
+				if err := collectScanErrs(c, &errs); err != nil {
 					return err
 				}
 			}
 			if tok == token.STRING {
 				sectsub = unquote(lit)
+				hdr.sub = fset.Position(pos)
 				if sectsub == "" {
 					if err := c.Collect(errfn("empty subsection name")); err != nil {
 						return err
 					}
 				}
-				pos, tok, lit = s.Scan()
+				pos, tok, lit = scan()
 				if errs.Len() > 0 {
-					if err := c.Collect(errs.Err()); err != nil {
+					if err := collectScanErrs(c, &errs); err != nil {
 						return err
 					}
 				}
 			}
 			if tok != token.RBRACK {
 				if sectsub == "" {
-					if err := c.Collect(errfn("expected subsection name or right bracket")); err != nil {
+					if err := c.Collect(errfn("expected subsection name or right bracket",
+						token.STRING, token.RBRACK)); err != nil {
 						return err
 					}
 				}
-				if err := c.Collect(errfn("expected right bracket")); err != nil {
+				if err := c.Collect(errfn("expected right bracket", token.RBRACK)); err != nil {
 					return err
 				}
+			} else {
+				hdr.rbrack = fset.Position(pos)
 			}
-			pos, tok, lit = s.Scan()
+			pos, tok, lit = scan()
 			if tok != token.EOL && tok != token.EOF && tok != token.COMMENT {
-				if err := c.Collect(errfn("expected EOL, EOF, or comment")); err != nil {
+				if err := c.Collect(errfn("expected EOL, EOF, or comment",
+					token.EOL, token.EOF, token.COMMENT)); err != nil {
 					return err
 				}
 			}
+			if seen != nil {
+				// the empty name records the section
+				seen[newVarKey(sect, sectsub, "")] = true
+				if o.meta != nil {
+					o.meta.add(sect, sectsub, "", hdr.lbrack)
+				}
+			}
 			// If a section/subsection header was found, ensure a
 			// container object is created, even if there are no
 			// variables further down.
-			err := c.Collect(set(c, config, sect, sectsub, "", true, "", subsectPass))
+			if tracing {
+				trace("gcfg: entering section", "section", sect,
+					"subsection", sectsub, "pos", hdr.lbrack.String(),
+					"end", hdr.rbrack.String())
+			}
+			err := set(c, config, sect, sectsub, "", true, "", nil,
+				subsectPass, hdr, hdr.name)
+			if tracing {
+				trace("gcfg: set", "section", sect, "subsection", sectsub,
+					"err", err)
+			}
+			// errors are already collected by set
 			if err != nil {
 				return err
 			}
 		case token.IDENT:
 			if sect == "" {
-				if err := c.Collect(errfn("expected section header")); err != nil {
+				if err := c.Collect(errfn("expected section header", token.LBRACK)); err != nil {
 					return err
 				}
 			}
-			n := lit
-			pos, tok, lit = s.Scan()
+			n, npos, base := lit, pos, lit
+			if st != nil {
+				st.Variables++
+			}
+			pos, tok, lit = scan()
 			if errs.Len() > 0 {
-				return errs.Err()
+				if err := collectScanErrs(c, &errs); err != nil {
+					return err
+				}
+			}
+			if tok == token.PERIOD || tok == token.LBRACK {
+				// name.key or name[index]
+				open := tok
+				pos, tok, lit = scan()
+				if errs.Len() > 0 {
+					if err := collectScanErrs(c, &errs); err != nil {
+						return err
+					}
+				}
+				if tok != token.IDENT {
+					if err := c.Collect(errfn("expected key", token.IDENT)); err != nil {
+						return err
+					}
+				}
+				n += "." + lit
+				pos, tok, lit = scan()
+				if errs.Len() > 0 {
+					if err := collectScanErrs(c, &errs); err != nil {
+						return err
+					}
+				}
+				if open == token.LBRACK {
+					if tok != token.RBRACK {
+						if err := c.Collect(errfn("expected right bracket", token.RBRACK)); err != nil {
+							return err
+						}
+					}
+					pos, tok, lit = scan()
+					if errs.Len() > 0 {
+						if err := collectScanErrs(c, &errs); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			if seen != nil {
+				seen[newVarKey(sect, sectsub, n)] = true
+				// dotted and indexed variables are also seen by name
+				seen[newVarKey(sect, sectsub, base)] = true
+				if o.meta != nil {
+					o.meta.add(sect, sectsub, n, fset.Position(npos))
+					if n != base {
+						o.meta.add(sect, sectsub, base, fset.Position(npos))
+					}
+				}
 			}
 			blank, v := tok == token.EOF || tok == token.EOL || tok == token.COMMENT, ""
+			if seen != nil && o.usage != nil && !blank {
+				k := newVarKey(sect, sectsub, n)
+				o.usage[k] = append(o.usage[k], fset.Position(npos))
+			}
+			var appendSep *string
 			if !blank {
-				if tok != token.ASSIGN {
-					if err := c.Collect(errfn("expected '='")); err != nil {
+				switch {
+				case tok == token.ADD && o.appendSep == nil:
+					if err := c.Collect(errfn("'+=' not enabled", token.ASSIGN)); err != nil {
+						return err
+					}
+				case tok == token.ADD:
+					appendSep = o.appendSep
+				case tok != token.ASSIGN:
+					exp := []token.Token{token.ASSIGN}
+					if o.appendSep != nil {
+						exp = append(exp, token.ADD)
+					}
+					if err := c.Collect(errfn("expected '='", exp...)); err != nil {
 						return err
 					}
 				}
-				pos, tok, lit = s.Scan()
+				pos, tok, lit = scan()
 				if errs.Len() > 0 {
-					if err := c.Collect(errs.Err()); err != nil {
+					if err := collectScanErrs(c, &errs); err != nil {
 						return err
 					}
 				}
 				if tok != token.STRING {
-					if err := c.Collect(errfn("expected value")); err != nil {
+					if err := c.Collect(errfn("expected value", token.STRING)); err != nil {
 						return err
 					}
 				}
-				v = unquote(lit)
-				pos, tok, lit = s.Scan()
+				if v = lit; !o.literalValues {
+					v = unquote(lit)
+				}
+				pos, tok, lit = scan()
 				if errs.Len() > 0 {
-					if err := c.Collect(errs.Err()); err != nil {
+					if err := collectScanErrs(c, &errs); err != nil {
 						return err
 					}
 				}
 				if tok != token.EOL && tok != token.EOF && tok != token.COMMENT {
-					if err := c.Collect(errfn("expected EOL, EOF, or comment")); err != nil {
+					if err := c.Collect(errfn("expected EOL, EOF, or comment",
+						token.EOL, token.EOF, token.COMMENT)); err != nil {
 						return err
 					}
 				}
+				if max := o.maxValueLength(); max > 0 && len(v) > max {
+					if subsectPass { // reported in the first pass
+						continue
+					}
+					// copies, so that n and sectsub don't escape
+					n, sub := n, sectsub
+					l := loc{pos: fset.Position(npos), hdr: hdr,
+						section: sect, variable: &n}
+					if sub != "" {
+						l.subsection = &sub
+					}
+					err := locErr{loc: l, err: fmt.Errorf("%w (%d bytes; "+
+						"maximum is %d)", ErrValueTooLong, len(v), max)}
+					if err := c.Collect(err); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			err := set(c, config, sect, sectsub, n, blank, v, appendSep,
+				subsectPass, hdr, fset.Position(npos))
+			if tracing {
+				trace("gcfg: set", "section", sect, "subsection", sectsub,
+					"variable", n, "blank", blank, "value", v,
+					"append", appendSep != nil, "err", err)
+			}
+			if err != nil && c.partial {
+				err = c.Collect(err)
 			}
-			err := set(c, config, sect, sectsub, n, blank, v, subsectPass)
 			if err != nil {
 				return err
 			}
 		default:
 			if sect == "" {
-				if err := c.Collect(errfn("expected section header")); err != nil {
+				if err := c.Collect(errfn("expected section header", token.LBRACK)); err != nil {
 					return err
 				}
 			}
-			if err := c.Collect(errfn("expected section header or variable declaration")); err != nil {
+			if err := c.Collect(errfn("expected section header or variable declaration",
+				token.LBRACK, token.IDENT)); err != nil {
 				return err
 			}
+			// skip the rest of the line
+			for tok != token.EOL && tok != token.EOF {
+				pos, tok, lit = scan()
+			}
 		}
 	}
 }
 
-func readInto(config interface{}, fset *token.FileSet, file *token.File,
-	src []byte) error {
+func readInto(config interface{}, filename string, src []byte,
+	o *options) error {
 	//
-	c := warnings.NewCollector(isFatal)
-	err := readIntoPass(c, config, fset, file, src, false)
-	if err != nil {
+	c := newCollector(o)
+	if o.embedded != "" {
+		var err error
+		if src, err = ExtractEmbedded(src, o.embedded); err != nil {
+			return err
+		}
+	}
+	var invalid []encodingErr
+	if o.lenientUTF8 {
+		src, invalid = replaceInvalidUTF8(src)
+	}
+	if o.iniDialect {
+		src = convertINI(src)
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile(filename, fset.Base(), len(src))
+	if o.columns != (token.ColumnMode{}) {
+		file.SetColumnMode(o.columns, src)
+	}
+	if len(invalid) > 0 {
+		file.SetLinesForContent(src)
+		for _, e := range invalid {
+			e.pos = fset.Position(file.Pos(e.pos.Offset))
+			if err := c.Collect(e); err != nil {
+				return err
+			}
+		}
+	}
+	seen := o.seen // shared across files by ReadFilesInto
+	if seen == nil {
+		seen = map[varKey]bool{}
+	}
+	if o.statsHandler != nil {
+		st, start := Stats{Bytes: len(src)}, time.Now()
+		defer func() {
+			st.Lines = file.LineCount()
+			st.Skipped = c.skipped
+			st.Warnings = c.warnings
+			st.Elapsed = time.Since(start)
+			o.statsHandler(st)
+		}()
+		if err := readIntoPass(c, o, config, fset, file, src, false, &st, seen); err != nil {
+			return err
+		}
+	} else if err := readIntoPass(c, o, config, fset, file, src, false, nil, seen); err != nil {
 		return err
 	}
-	err = readIntoPass(c, config, fset, file, src, true)
+	c.skipSyntax = true // reported in the first pass
+	err := readIntoPass(c, o, config, fset, file, src, true, nil, nil)
 	if err != nil {
 		return err
 	}
+	if err := setFallbacks(c, config, file.Name(), seen); err != nil {
+		return err
+	}
+	// layered files are only checked as a whole, after the last one
+	if !o.layered {
+		if err := checkRequired(c, config, file.Name(), seen); err != nil {
+			return err
+		}
+	}
+	if err := checkIndexes(c); err != nil {
+		return err
+	}
+	if !o.layered {
+		if err := validate(c, config, file.Name()); err != nil {
+			return err
+		}
+	}
 	return c.Done()
 }
 
 // ReadInto reads gcfg formatted data from reader and sets the values into the
 // corresponding fields in config.
 func ReadInto(config interface{}, reader io.Reader) error {
+	return ReadWithOptions(config, reader)
+}
+
+// ReadWithOptions reads gcfg formatted data from reader and sets the values
+// into the corresponding fields in config, with the behavior modified by opts.
+func ReadWithOptions(config interface{}, reader io.Reader, opts ...Option) error {
 	src, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return err
 	}
-	fset := token.NewFileSet()
-	file := fset.AddFile("", fset.Base(), len(src))
-	return readInto(config, fset, file, src)
+	return readInto(config, "", src, newOptions(opts))
+}
+
+// ReadIntoAll reads gcfg formatted data from reader and sets the values into
+// the corresponding fields in each of cfgs, which must all be pointers to
+// structs. Each config claims the sections (and variables) it defines, and
+// data is only reported as unknown if no config defines it. This makes it
+// possible to split the configuration of a modular program across packages,
+// each with its own config struct. Values defined in multiple configs are
+// set in all of them.
+func ReadIntoAll(reader io.Reader, cfgs ...interface{}) error {
+	src, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return readInto(configs(cfgs), "", src, &options{})
 }
 
 // ReadStringInto reads gcfg formatted data from str and sets the values into
 // the corresponding fields in config.
 func ReadStringInto(config interface{}, str string) error {
-	r := strings.NewReader(str)
-	return ReadInto(config, r)
+	return ReadStringWithOptions(config, str)
+}
+
+// ReadStringWithOptions is like ReadStringInto, with the behavior modified by
+// opts.
+func ReadStringWithOptions(config interface{}, str string, opts ...Option) error {
+	return readInto(config, "", []byte(str), newOptions(opts))
+}
+
+// ReadBytesInto reads gcfg formatted data from src and sets the values into
+// the corresponding fields in config. It is convenient for data embedded in
+// the program using go:embed. src is not modified or retained.
+func ReadBytesInto(config interface{}, src []byte) error {
+	return readInto(config, "", src, &options{})
 }
 
 // ReadFileInto reads gcfg formatted data from the file filename and sets the
@@ -227,6 +547,14 @@ func ReadStringInto(config interface{}, str string) error {
 // For compatibility with files created on Windows, the ReadFileInto skips a
 // single leading UTF8 BOM sequence if it exists.
 func ReadFileInto(config interface{}, filename string) error {
+	return ReadFileWithOptions(config, filename)
+}
+
+// ReadFileWithOptions is like ReadFileInto, with the behavior modified by
+// opts. The file name is used in the positions of reported errors.
+func ReadFileWithOptions(config interface{}, filename string,
+	opts ...Option) error {
+	//
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -240,9 +568,165 @@ func ReadFileInto(config interface{}, filename string) error {
 	// Skips a single leading UTF8 BOM sequence if it exists.
 	src = skipLeadingUtf8Bom(src)
 
-	fset := token.NewFileSet()
-	file := fset.AddFile(filename, fset.Base(), len(src))
-	return readInto(config, fset, file, src)
+	return readInto(config, filename, src, newOptions(opts))
+}
+
+// ReadFSInto reads gcfg formatted data from the file name in the file system
+// fsys (e.g. an embed.FS) and sets the values into the corresponding fields
+// in config, as ReadFileInto does for files in the host's file system. Values
+// of variables with the ",fromfile" struct tag option name files in fsys too,
+// and ",relto=config" resolves them against the directory of name in fsys.
+func ReadFSInto(config interface{}, fsys fs.FS, name string) error {
+	return ReadFSWithOptions(config, fsys, name)
+}
+
+// ReadFSWithOptions is like ReadFSInto, with the behavior modified by opts.
+func ReadFSWithOptions(config interface{}, fsys fs.FS, name string,
+	opts ...Option) error {
+	//
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	o := newOptions(opts)
+	o.fsys = fsys
+	return readInto(config, name, skipLeadingUtf8Bom(src), o)
+}
+
+// ReadFilesInto reads gcfg formatted data from each of the files filenames in
+// order and sets the values into the corresponding fields in config, so that
+// configuration can be layered as with git's system, global and local files:
+// values in later files override those set by earlier ones, and values of
+// multi-valued variables are appended to those set by earlier ones (see
+// ReplaceMultiValues for replacing them instead). A variable can also be
+// reset by a later file using a "blank" value, as usual. Sections and
+// variables with the ",required" struct tag option need only be defined in
+// one of the files, and Validate methods (see the package documentation) are
+// only called after reading the last file.
+//
+// A variable that is defined once in a file and several times in a later one
+// (or vice versa) results in a warning of type *InconsistentUseError, as this
+// usually indicates an error (see WarningHandler for handling it differently).
+//
+// Reading stops at the first file that can't be read or that results in a
+// fatal error; the warnings for all the files read are returned together with
+// the fatal error, if any (see FatalOnly).
+func ReadFilesInto(config interface{}, filenames ...string) error {
+	return ReadFilesWithOptions(config, filenames)
+}
+
+// ReadFilesWithOptions is like ReadFilesInto, with the behavior modified by
+// opts, which apply to each file.
+func ReadFilesWithOptions(config interface{}, filenames []string,
+	opts ...Option) error {
+	//
+	handler := newOptions(opts).warningHandler
+	var l warnings.List
+	prev := usage{} // definitions in the last file defining each variable
+	seen := map[varKey]bool{}
+	for i, filename := range filenames {
+		u := usage{}
+		last := i == len(filenames)-1
+		record := func(o *options) { o.usage, o.seen, o.layered = u, seen, !last }
+		err := ReadFileWithOptions(config, filename, append(opts[:len(opts):len(opts)], record)...)
+		l.Warnings = append(l.Warnings, warnings.WarningsOnly(err)...)
+		if l.Fatal = FatalOnly(err); l.Fatal != nil {
+			break
+		}
+		for _, err := range prev.inconsistent(u) {
+			sev := SeverityWarning
+			if handler != nil {
+				sev = handler(err, sev)
+			}
+			if sev == SeverityError {
+				l.Fatal = err
+				break
+			}
+			if sev == SeverityWarning {
+				l.Warnings = append(l.Warnings, err)
+			}
+		}
+		if l.Fatal != nil {
+			break
+		}
+		for k, pos := range u {
+			prev[k] = pos
+		}
+	}
+	if l.Fatal == nil && len(l.Warnings) == 0 {
+		return nil
+	}
+	return l
+}
+
+// usage records the positions of the (non-blank) definitions of the
+// variables in a file; see ReadFilesInto.
+type usage map[varKey][]token.Position
+
+// inconsistent returns the warnings for the variables defined once in u and
+// several times in prev, or vice versa, sorted by position.
+func (prev usage) inconsistent(u usage) []error {
+	var errs []error
+	for k, pos := range u {
+		ppos, ok := prev[k]
+		if !ok || len(pos) > 1 == (len(ppos) > 1) {
+			continue
+		}
+		err := &InconsistentUseError{Pos: pos[0], PrevPos: ppos[0],
+			Section: k.sect, Variable: k.name, Repeated: len(pos) > 1}
+		if k.sub != "" {
+			sub := k.sub
+			err.Subsection = &sub
+		}
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].(*InconsistentUseError).Pos.Offset <
+			errs[j].(*InconsistentUseError).Pos.Offset
+	})
+	return errs
+}
+
+// replaceInvalidUTF8 returns src with each byte that is not part of a valid
+// UTF-8 encoding replaced with U+FFFD, and the errors (warnings) for the
+// replacements; the positions in the errors only have offsets set, which
+// refer to the returned data.
+func replaceInvalidUTF8(src []byte) ([]byte, []encodingErr) {
+	if utf8.Valid(src) {
+		return src, nil
+	}
+	var errs []encodingErr
+	b := make([]byte, 0, len(src)+8)
+	for i := 0; i < len(src); {
+		r, w := utf8.DecodeRune(src[i:])
+		if r == utf8.RuneError && w == 1 {
+			errs = append(errs, encodingErr{pos: token.Position{Offset: len(b)},
+				offset: i, b: src[i]})
+			b = append(b, string(utf8.RuneError)...)
+		} else {
+			b = append(b, src[i:i+w]...)
+		}
+		i += w
+	}
+	return b, errs
+}
+
+// MustReadStringInto is like ReadStringInto but panics if reading fails (or
+// results in warnings; see FatalOnly). It is intended for tests and for
+// initializing package-level variables from constant data.
+func MustReadStringInto(config interface{}, str string) {
+	if err := ReadStringInto(config, str); err != nil {
+		panic(mustErr{err: err})
+	}
+}
+
+// MustReadFileInto is like ReadFileInto but panics if reading fails (or
+// results in warnings; see FatalOnly). It is intended for tests and for
+// initializing package-level variables.
+func MustReadFileInto(config interface{}, filename string) {
+	if err := ReadFileInto(config, filename); err != nil {
+		panic(mustErr{filename: filename, err: err})
+	}
 }
 
 func skipLeadingUtf8Bom(src []byte) []byte {
@@ -3,11 +3,24 @@ package gcfg
 import (
 	"bytes"
 	"encoding"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"math/big"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"gopkg.in/gcfg.v1/token"
+	"gopkg.in/gcfg.v1/types"
+	"gopkg.in/warnings.v0"
 )
 
 const (
@@ -320,6 +333,20 @@ func testReadInto(t *testing.T, id string, tt readtest, res interface{}) {
 	}
 }
 
+func TestReadBytesInto(t *testing.T) {
+	src := []byte("[section]\nname=value")
+	res := &cBasic{}
+	if err := ReadBytesInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	if res.Section.Name != "value" {
+		t.Errorf("got %q, wanted %q", res.Section.Name, "value")
+	}
+	if err := ReadBytesInto(res, []byte("[section]\nname")); err == nil {
+		t.Errorf("got no error for blank string value")
+	}
+}
+
 func TestReadFileInto(t *testing.T) {
 	res := &struct{ Section struct{ Name string } }{}
 	err := ReadFileInto(res, "testdata/gcfg_test.gcfg")
@@ -331,6 +358,30 @@ func TestReadFileInto(t *testing.T) {
 	}
 }
 
+func TestReadFileWithOptions(t *testing.T) {
+	res := &struct{ Section struct{ Other string } }{}
+	err := ReadFileWithOptions(res, "testdata/gcfg_test.gcfg",
+		WarningHandler(func(err error, sev Severity) Severity {
+			return SeverityError
+		}))
+	if err == nil || FatalOnly(err) == nil ||
+		!strings.HasPrefix(err.Error(), "testdata/gcfg_test.gcfg:3:1: ") {
+		t.Errorf("got error %v, wanted fatal error at "+
+			"testdata/gcfg_test.gcfg:3:1", err)
+	}
+}
+
+func TestReadStringWithOptions(t *testing.T) {
+	var st Stats
+	err := ReadStringWithOptions(&cBasic{}, "[section]\nname=value", StatsInto(&st))
+	if err != nil {
+		t.Error(err)
+	}
+	if st.Sections != 1 || st.Variables != 1 {
+		t.Errorf("got stats %+v, wanted 1 section and 1 variable", st)
+	}
+}
+
 func TestReadFileIntoUnicode(t *testing.T) {
 	res := &struct{ X甲 struct{ X乙 string } }{}
 	err := ReadFileInto(res, "testdata/gcfg_unicode_test.gcfg")
@@ -401,6 +452,29 @@ var panictests = []struct {
 	{"top", struct{}{}, "[section]\nname=value"},
 	{"section", &struct{ Section string }{}, "[section]\nname=value"},
 	{"subsection", &struct{ Section map[string]string }{}, "[section \"subsection\"]\nname=value"},
+	{"transform", &struct {
+		Section struct {
+			Name string `gcfg:",transform=nonexistent"`
+		}
+	}{}, "[section]\nname=value"},
+	{"default", &struct {
+		Section struct {
+			Int int `gcfg:",default=x"`
+		}
+	}{}, "[section]"},
+	{"setter", &struct {
+		Section struct {
+			Name string `gcfg:",setter=nonexistent"`
+		}
+	}{}, "[section]\nname=value"},
+	{"any", &struct {
+		Section struct {
+			Extra string `gcfg:",any"`
+		}
+	}{}, "[section]\nname=value"},
+	{"any section", &struct {
+		Extra map[string]string `gcfg:",any"`
+	}{}, "[section]\nname=value"},
 }
 
 func testPanic(t *testing.T, id string, config interface{}, gcfg string) {
@@ -443,3 +517,1982 @@ func TestUtf8Boms(t *testing.T) {
 		testUtf8Bom(t, tt.id, tt.in, tt.out)
 	}
 }
+
+var errorpostests = []struct {
+	gcfg string
+	pos  string
+}{
+	{"[section]\nname=value\nint=x", "3:1"},
+	{"[section]\n\n  nonexistent=value", "3:3"},
+	{"\n[nonexistent]\nname=value", "2:2"},
+	{"[section \"sub\"]\nname=value", "1:10"},
+	{"[section \"sub\"]\n\tname=value", "2:2"},
+}
+
+func TestReadWithOptionsColumns(t *testing.T) {
+	err := ReadWithOptions(&cBasic{}, strings.NewReader("[section]\n\tint=x"),
+		Columns(token.ColumnMode{TabWidth: 8}))
+	if err == nil || !strings.HasPrefix(err.Error(), "2:9: ") {
+		t.Errorf("got error %v, wanted error at 2:9", err)
+	}
+}
+
+func TestReadStringIntoErrorPosition(t *testing.T) {
+	for i, tt := range errorpostests {
+		err := ReadStringInto(&cBasic{}, tt.gcfg)
+		switch {
+		case err == nil:
+			t.Errorf("%d fail: got ok; wanted error", i)
+		case !strings.Contains(err.Error(), tt.pos+": "):
+			t.Errorf("%d fail: error message doesn't contain position %q: %v",
+				i, tt.pos, err)
+		default:
+			t.Logf("%d pass: %v", i, err)
+		}
+	}
+}
+
+var sentinelerrtests = []struct {
+	config interface{}
+	gcfg   string
+	err    error
+}{
+	{&cBasic{}, "[section]\nname=\"value", ErrSyntax},
+	{&cBasic{}, "name=value", ErrSyntax},
+	{&cBasic{}, "[section]\n=", ErrSyntax},
+	{&cBasic{}, "[nonexistent]\nname=value", ErrUnknownSection},
+	{&cBasic{}, "[section \"sub\"]\nname=value", ErrUnknownSection},
+	{&cBasic{}, "[section]\nnonexistent=value", ErrUnknownVariable},
+	{&struct{ Section struct{ C chan int } }{}, "[section]\nc", ErrUnsupportedType},
+}
+
+func TestReadStringIntoSentinelErrors(t *testing.T) {
+	for i, tt := range sentinelerrtests {
+		err := ReadStringInto(tt.config, tt.gcfg)
+		if l, ok := err.(warnings.List); ok && l.Fatal == nil {
+			err = l.Warnings[0]
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%d fail: got error %v, wanted %v", i, err, tt.err)
+			continue
+		}
+		t.Logf("%d pass: %v", i, err)
+	}
+}
+
+var syntaxerrtests = []struct {
+	gcfg     string
+	pos      string
+	got      token.Token
+	expected []token.Token
+}{
+	{"[section]\nname=\"value", "2:6", token.ILLEGAL, nil},
+	{"name=value", "1:1", token.IDENT, []token.Token{token.LBRACK}},
+	{"[section]\n=", "2:1", token.ASSIGN, []token.Token{token.LBRACK, token.IDENT}},
+	{"[section]\nname value", "2:6", token.IDENT, []token.Token{token.ASSIGN}},
+	{"[section x]", "1:10", token.IDENT, []token.Token{token.STRING, token.RBRACK}},
+	{"[section] x", "1:11", token.IDENT, []token.Token{token.EOL, token.EOF, token.COMMENT}},
+}
+
+func TestReadStringIntoSyntaxError(t *testing.T) {
+	for i, tt := range syntaxerrtests {
+		err := ReadStringInto(&cBasic{}, tt.gcfg)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%d fail: got error %v, wanted SyntaxError", i, err)
+			continue
+		}
+		pos := fmt.Sprintf("%d:%d", se.Pos.Line, se.Pos.Column)
+		if pos != tt.pos || se.Got != tt.got ||
+			!reflect.DeepEqual(se.Expected, tt.expected) {
+			t.Errorf("%d fail: got %s %v %v, wanted %s %v %v", i, pos,
+				se.Got, se.Expected, tt.pos, tt.got, tt.expected)
+		}
+	}
+}
+
+func TestReadStringIntoTypedErrors(t *testing.T) {
+	type config struct {
+		Section struct {
+			Int    int
+			Secret int `gcfg:",secret"`
+		}
+		Sub map[string]*struct{ Name string }
+	}
+	src := "[section]\nint = x\n[sub \"a\"]\nother = y"
+	err := ReadStringWithOptions(&config{}, src, PartialResults())
+	var ve *ValueError
+	if !errors.As(FatalOnly(err), &ve) {
+		t.Fatalf("got error %v, wanted ValueError", err)
+	}
+	if ve.Pos.Line != 2 || ve.Section != "section" || ve.Subsection != nil ||
+		ve.Variable != "int" || ve.Value == nil || *ve.Value != "x" ||
+		ve.Err == nil {
+		t.Errorf("got %+v", ve)
+	}
+	if ve.Error() != FatalOnly(err).Error() {
+		t.Errorf("got message %q, wanted %q", ve, FatalOnly(err))
+	}
+	var ue *UnknownFieldError
+	if w := warnings.WarningsOnly(err); len(w) != 1 || !errors.As(w[0], &ue) {
+		t.Fatalf("got error %v, wanted UnknownFieldError", err)
+	}
+	if ue.Pos.Line != 4 || ue.Section != "sub" || ue.Subsection == nil ||
+		*ue.Subsection != "a" || ue.Variable != "other" ||
+		!errors.Is(ue, ErrUnknownVariable) {
+		t.Errorf("got %+v", ue)
+	}
+	err = ReadStringInto(&config{}, "[section]\nsecret = x")
+	if !errors.As(err, &ve) || ve.Value != nil ||
+		strings.Contains(ve.Error(), "x at") {
+		t.Errorf("got %v, wanted ValueError with the value redacted", err)
+	}
+	for e := ve.Err; e != nil; e = errors.Unwrap(e) {
+		if strings.Contains(e.Error(), "x") {
+			t.Errorf("got underlying error %q, wanted it redacted", e)
+		}
+	}
+}
+
+var unquotetests = []struct {
+	in   string
+	out  string
+	offs int // offset of the error, or -1 if ok
+}{
+	{`value`, "value", -1},
+	{`"quoted"`, "quoted", -1},
+	{`a "b c" d`, "a b c d", -1},
+	{`"a""b"`, "ab", -1},
+	{`"\\\"\n\t"`, "\\\"\n\t", -1},
+	{`a \"b\"`, `a "b"`, -1},
+	{"a\\\nb", "ab", -1},
+	{"a\\\r\nb", "ab", -1},
+	{"a\r\nb", "a\nb", -1},
+	{`"a\x"`, "", 2},
+	{`a\n`, "", 1},
+	{`a \`, "", 2},
+	{`a "b`, "", 2},
+	{"\"a\nb\"", "", 0},
+}
+
+func TestUnquote(t *testing.T) {
+	for _, tt := range unquotetests {
+		out, err := Unquote(tt.in)
+		var se *SyntaxError
+		switch {
+		case tt.offs < 0 && (err != nil || out != tt.out):
+			t.Errorf("%q: got %q, %v, wanted %q", tt.in, out, err, tt.out)
+		case tt.offs >= 0 && !errors.As(err, &se):
+			t.Errorf("%q: got error %v, wanted SyntaxError", tt.in, err)
+		case tt.offs >= 0 && se.Pos.Offset != tt.offs:
+			t.Errorf("%q: got error at offset %d, wanted %d", tt.in,
+				se.Pos.Offset, tt.offs)
+		}
+		if tt.offs >= 0 || strings.ContainsAny(tt.in, "\r\n") {
+			continue
+		}
+		// values are read the same way
+		res := &cBasic{}
+		if err := ReadStringInto(res, "[section]\nname="+tt.in); err != nil ||
+			res.Section.Name != out {
+			t.Errorf("%q: read %q, %v, wanted %q", tt.in, res.Section.Name,
+				err, out)
+		}
+	}
+}
+
+func TestReadWithOptionsPartialResults(t *testing.T) {
+	type sect struct {
+		Name string
+		Int  int
+	}
+	cfg := &struct{ A, B, C sect }{}
+	src := "[a]\nname=a\n= bad\n[b]\nint\nname=b\n[c]\nname=c\n[nonexistent]\n"
+	err := ReadWithOptions(cfg, strings.NewReader(src), PartialResults())
+	var l ErrorList
+	if !errors.As(FatalOnly(err), &l) || len(l) != 2 {
+		t.Fatalf("got error %v, wanted ErrorList of length 2", err)
+	}
+	if !errors.Is(l[0], ErrSyntax) {
+		t.Errorf("got error %v, wanted %v", l[0], ErrSyntax)
+	}
+	if !errors.Is(l[1], errBlankUnsupported) {
+		t.Errorf("got error %v, wanted %v", l[1], errBlankUnsupported)
+	}
+	if w := warnings.WarningsOnly(err); len(w) != 1 ||
+		!errors.Is(w[0], ErrUnknownSection) {
+		t.Errorf("got warnings %v, wanted %v", w, ErrUnknownSection)
+	}
+	if cfg.A.Name != "a" || cfg.B.Name != "b" || cfg.C.Name != "c" {
+		t.Errorf("got %+v, wanted values set around the errors", cfg)
+	}
+}
+
+func TestReadWithOptionsPartialResultsOrder(t *testing.T) {
+	cfg := &struct {
+		Sub map[string]*struct{ Int int }
+		A   struct{ Int int }
+	}{}
+	src := "[sub \"x\"]\nint=a\n[a]\nint=b\n[sub \"y\"]\nint=c\n[a]\nfoo=d\n"
+	err := ReadWithOptions(cfg, strings.NewReader(src), PartialResults(),
+		UnknownData(UnknownStrict))
+	var l ErrorList
+	if !errors.As(err, &l) || len(l) != 4 {
+		t.Fatalf("got error %v, wanted ErrorList of length 4", err)
+	}
+	for i, err := range l {
+		if pos, _ := errorPos(err); pos.Line != 2*i+2 {
+			t.Errorf("%d: got error %v, wanted error on line %d", i, err, 2*i+2)
+		}
+	}
+}
+
+func TestReadWithOptionsPartialResultsSubsections(t *testing.T) {
+	cfg := &struct {
+		Host map[string]*struct{ Port, Weight int }
+		A    struct{ Int int }
+	}{}
+	src := "[host \"x\"]\nport=a\nweight=b\n[a]\nint=c\n" +
+		"[host \"y\"]\nport=1\n[host \"z\"]\nport=d\n[host \"x\"]\nfoo=e\n"
+	err := ReadWithOptions(cfg, strings.NewReader(src), PartialResults(),
+		UnknownData(UnknownStrict))
+	var l ErrorList
+	if !errors.As(err, &l) || len(l) != 3 {
+		t.Fatalf("got error %v, wanted ErrorList of length 3", err)
+	}
+	for i, exp := range []struct {
+		sub   string
+		lines []int
+	}{{"x", []int{2, 3, 11}}, {"", []int{5}}, {"z", []int{9}}} {
+		errs := ErrorList{l[i]}
+		var se *SubsectionError
+		if errors.As(l[i], &se) {
+			if se.Section != "host" || se.Subsection != exp.sub {
+				t.Errorf("%d: got subsection %q %q, wanted %q", i,
+					se.Section, se.Subsection, exp.sub)
+			}
+			errs = se.Errs
+		} else if exp.sub != "" {
+			t.Errorf("%d: got error %v, wanted SubsectionError", i, l[i])
+		}
+		var lines []int
+		for _, err := range errs {
+			pos, _ := errorPos(err)
+			lines = append(lines, pos.Line)
+		}
+		if !reflect.DeepEqual(lines, exp.lines) {
+			t.Errorf("%d: got errors on lines %v, wanted %v", i, lines,
+				exp.lines)
+		}
+	}
+	if cfg.Host["y"] == nil || cfg.Host["y"].Port != 1 {
+		t.Errorf("got %+v, wanted valid subsection set", cfg.Host["y"])
+	}
+	var b bytes.Buffer
+	if err := FormatErrors(&b, []byte(src), err); err != nil ||
+		strings.Count(b.String(), "^") != 5 {
+		t.Errorf("got formatted errors\n%s", b.String())
+	}
+}
+
+func TestReadWithOptionsIndentedContinuations(t *testing.T) {
+	src := "[section]\n\tname = first\n\t\tsecond\n\t\t\"third\" ; comment\n\tint = 1\n"
+	cfg := &cBasic{}
+	if err := ReadStringWithOptions(cfg, src, IndentedContinuations()); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "first\nsecond\nthird"; cfg.Section.Name != exp || cfg.Section.Int != 1 {
+		t.Errorf("got %+v, wanted name %q and int 1", cfg.Section, exp)
+	}
+	// without the option, the continuation line is a syntax error
+	if err := ReadStringInto(&cBasic{}, src); !errors.Is(err, ErrSyntax) {
+		t.Errorf("got error %v, wanted %v", err, ErrSyntax)
+	}
+}
+
+func TestReadWithOptionsLiteralValues(t *testing.T) {
+	for _, tt := range []struct {
+		src, exp string
+	}{
+		{"query=a=b", "a=b"},
+		{"query = a=b&c=\"d\" ", `a=b&c="d"`},
+		{`query = C:\dir\`, `C:\dir\`},
+		{`query = "a;b"`, `"a`},
+		{"query = a # b", "a"},
+		{"query =", ""},
+	} {
+		res := &struct{ Section struct{ Query string } }{}
+		err := ReadStringWithOptions(res, "[section]\n"+tt.src, LiteralValues())
+		if err != nil {
+			t.Errorf("%q: got error %v", tt.src, err)
+		} else if res.Section.Query != tt.exp {
+			t.Errorf("%q: got %q, wanted %q", tt.src, res.Section.Query, tt.exp)
+		}
+	}
+	src := "[section]\n\tname = a\n\t\t\"b\"\n"
+	cfg := &cBasic{}
+	err := ReadStringWithOptions(cfg, src, LiteralValues(), IndentedContinuations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "a\n\"b\""; cfg.Section.Name != exp {
+		t.Errorf("got %q, wanted %q", cfg.Section.Name, exp)
+	}
+}
+
+func TestReadWithOptionsNameFolding(t *testing.T) {
+	type config struct {
+		Section struct {
+			Kelvin string `gcfg:"k"`
+			Name   string
+		}
+		Sectİon struct{ Name string } // dotted capital I
+	}
+	for _, tt := range []struct {
+		fold func(string) string
+		gcfg string
+		ok   bool
+	}{
+		{nil, "[SECTION]\n\u212a=x", true},
+		{FoldASCII, "[SECTION]\n\u212a=x", false},
+		{FoldASCII, "[SECTION]\nK=x\nNAME=y", true},
+		{nil, "[section]\nname=x", true},
+		{FoldASCII, "[sect\u0130on]\nname=x", true},
+		{FoldASCII, "[secti\u0307on]\nname=x", false},
+	} {
+		err := ReadStringWithOptions(&config{}, tt.gcfg, NameFolding(tt.fold))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%q: got error %v, wanted ok %v", tt.gcfg, err, tt.ok)
+		}
+	}
+}
+
+func TestReadWithOptionsMaxErrors(t *testing.T) {
+	src := strings.Repeat("[section]\n= bad\n", 100) + "[section]\nname=value\n"
+	for _, tt := range []struct {
+		max, exp int
+		set      bool
+	}{
+		{0, 100, true},
+		{10, 10, false},
+		{100, 100, false},
+		{101, 100, true},
+	} {
+		cfg := &cBasic{}
+		err := ReadWithOptions(cfg, strings.NewReader(src), PartialResults(),
+			MaxErrors(tt.max))
+		var l ErrorList
+		if !errors.As(err, &l) || len(l) != tt.exp {
+			t.Errorf("MaxErrors(%d): got %d errors, wanted %d", tt.max,
+				len(l), tt.exp)
+		}
+		if set := cfg.Section.Name == "value"; set != tt.set {
+			t.Errorf("MaxErrors(%d): got value set %v, wanted %v", tt.max,
+				set, tt.set)
+		}
+	}
+}
+
+func TestReadWithOptionsMaxValueLength(t *testing.T) {
+	type config struct {
+		Section cSubsS1
+		Sub     map[string]*cSubsS1
+	}
+	src := "[section]\nname=\"0123456789\"\n[sub \"x\"]\nname=0123456789x\n"
+	cfg := &config{}
+	err := ReadWithOptions(cfg, strings.NewReader(src), MaxValueLength(10))
+	exp := `4:1: value too long (11 bytes; maximum is 10) at section "sub", ` +
+		`subsection "x", variable "name"`
+	if !errors.Is(err, ErrValueTooLong) || err.Error() != exp {
+		t.Errorf("got error %v, wanted %q", err, exp)
+	}
+	defer func(n int) { DefaultMaxValueLength = n }(DefaultMaxValueLength)
+	DefaultMaxValueLength = 5
+	cfg = &config{}
+	err = ReadWithOptions(cfg, strings.NewReader(src), PartialResults())
+	var l ErrorList
+	if !errors.As(err, &l) || len(l) != 2 || !errors.Is(l[1], ErrValueTooLong) {
+		t.Errorf("got error %v, wanted 2 errors wrapping %v", err,
+			ErrValueTooLong)
+	}
+	if cfg.Sub["x"] == nil || cfg.Sub["x"].Name != "" {
+		t.Errorf("got %+v, wanted value not set", cfg.Sub["x"])
+	}
+	err = ReadWithOptions(&config{}, strings.NewReader(src), MaxValueLength(0))
+	if err != nil {
+		t.Errorf("got error %v, wanted no limit", err)
+	}
+}
+
+func TestReadWithOptionsLenientUTF8(t *testing.T) {
+	src := "[section]\nname=a\xffb\xfe\n"
+	cfg := &cBasic{}
+	if err := ReadWithOptions(cfg, strings.NewReader(src)); err == nil {
+		t.Errorf("got no error without LenientUTF8")
+	}
+	cfg = &cBasic{}
+	err := ReadWithOptions(cfg, strings.NewReader(src), LenientUTF8())
+	if FatalOnly(err) != nil {
+		t.Fatalf("got fatal error %v", err)
+	}
+	if exp := "a\ufffdb\ufffd"; cfg.Section.Name != exp {
+		t.Errorf("got %q, wanted %q", cfg.Section.Name, exp)
+	}
+	w := warnings.WarningsOnly(err)
+	if len(w) != 2 {
+		t.Fatalf("got warnings %v, wanted 2", w)
+	}
+	for i, exp := range []string{"2:7: invalid UTF-8 byte 0xff at offset 16",
+		"2:11: invalid UTF-8 byte 0xfe at offset 18"} {
+		if !errors.Is(w[i], ErrInvalidUTF8) ||
+			!strings.HasPrefix(w[i].Error(), exp) {
+			t.Errorf("got warning %v, wanted %q", w[i], exp)
+		}
+	}
+}
+
+func TestReadWithOptionsProgressHandler(t *testing.T) {
+	src := strings.Repeat("[section]\nname=value\n", 100)
+	var got []Progress
+	err := ReadWithOptions(&cBasic{}, strings.NewReader(src),
+		ProgressHandler(1000, func(p Progress) { got = append(got, p) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Progress{
+		{1, 1001, 2100, 96}, {1, 2003, 2100, 191}, {1, 2100, 2100, 200},
+		{2, 1001, 2100, 96}, {2, 2003, 2100, 191}, {2, 2100, 2100, 200},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %v, wanted %v", got, exp)
+	}
+}
+
+func TestReadWithOptionsWarningHandler(t *testing.T) {
+	cfg := "[section]\nname=value\nname2=value2\n[nonexistent]"
+	for i, tt := range []struct {
+		sev     Severity
+		fatal   bool
+		nwarn   int
+		nhandle int
+	}{
+		{SeverityInfo, false, 0, 2},
+		{SeverityWarning, false, 2, 2},
+		{SeverityError, true, 0, 1},
+	} {
+		res, n := &cBasic{}, 0
+		err := ReadWithOptions(res, strings.NewReader(cfg),
+			WarningHandler(func(err error, sev Severity) Severity {
+				if sev != SeverityWarning {
+					t.Errorf("%d: got severity %v for %v, wanted %v",
+						i, sev, err, SeverityWarning)
+				}
+				n++
+				return tt.sev
+			}))
+		if n != tt.nhandle {
+			t.Errorf("%d: handler called %d times, wanted %d", i, n, tt.nhandle)
+		}
+		if fatal := FatalOnly(err) != nil; fatal != tt.fatal {
+			t.Errorf("%d: got fatal %v, wanted %v: %v", i, fatal, tt.fatal, err)
+		}
+		if nwarn := len(warnings.WarningsOnly(err)); nwarn != tt.nwarn {
+			t.Errorf("%d: got %d warnings, wanted %d: %v", i, nwarn, tt.nwarn, err)
+		}
+		if res.Section.Name != "value" {
+			t.Errorf("%d: got name %q, wanted %q", i, res.Section.Name, "value")
+		}
+	}
+}
+
+func TestReadWithOptionsStatsHandler(t *testing.T) {
+	cfg := "; comment\n[section]\nname=value\nname2=value2\n[sub \"a\"]\nname=value\n"
+	var st Stats
+	called := 0
+	err := ReadWithOptions(&struct {
+		Section cBasicS1
+		Sub     map[string]*cSubsS1
+	}{}, strings.NewReader(cfg), StatsHandler(func(s Stats) {
+		st = s
+		called++
+	}))
+	if FatalOnly(err) != nil {
+		t.Fatal(err)
+	}
+	if called != 1 {
+		t.Errorf("stats handler called %d times, wanted 1", called)
+	}
+	exp := Stats{Bytes: len(cfg), Lines: 6, Sections: 2, Variables: 3,
+		Skipped: 1, Warnings: 1, Elapsed: st.Elapsed}
+	if st != exp {
+		t.Errorf("got %+v, wanted %+v", st, exp)
+	}
+}
+
+func TestReadWithOptionsStatsInto(t *testing.T) {
+	cfg := "[section]\nname=value\nint=x\n[sub \"a\"]\nname=value\nother=1\n"
+	var st Stats
+	called := false
+	err := ReadWithOptions(&struct {
+		Section struct {
+			Name string
+			Int  int
+		}
+		Sub map[string]*cSubsS1
+	}{}, strings.NewReader(cfg), PartialResults(),
+		WarningHandler(func(err error, sev Severity) Severity {
+			return SeverityInfo
+		}), StatsHandler(func(Stats) { called = true }), StatsInto(&st))
+	if err == nil {
+		t.Fatal("got no error, wanted error for int")
+	}
+	if !called {
+		t.Errorf("previously set stats handler not called")
+	}
+	if st.Variables != 4 || st.Skipped != 2 || st.Warnings != 0 {
+		t.Errorf("got %+v, wanted 4 variables, 2 skipped, 0 warnings", st)
+	}
+}
+
+func TestReadWithOptionsLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelDebug}))
+	err := ReadWithOptions(&cBasic{}, strings.NewReader("[section]\nname=value"),
+		Logger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`msg="gcfg: scanned token" pos=2:1 tok=IDENT lit=name`,
+		`msg="gcfg: entering section" section=section`,
+		`msg="gcfg: set" section=section subsection="" variable=name blank=false value=value append=false err=<nil>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("log doesn't contain %q:\n%s", s, buf.String())
+		}
+	}
+}
+
+type cEnv struct {
+	Section struct {
+		Name  string   `gcfg:",env=GCFG_TEST_NAME"`
+		Int   int      `gcfg:",env=GCFG_TEST_INT"`
+		Multi []string `gcfg:",env=GCFG_TEST_MULTI"`
+		Unset string   `gcfg:",env=GCFG_TEST_UNSET"`
+	}
+	Sub map[string]*struct {
+		Name string `gcfg:",env=GCFG_TEST_NAME"`
+	}
+}
+
+func TestReadStringIntoEnv(t *testing.T) {
+	t.Setenv("GCFG_TEST_NAME", "env")
+	t.Setenv("GCFG_TEST_INT", "0x10")
+	t.Setenv("GCFG_TEST_MULTI", "a")
+	res := &cEnv{}
+	err := ReadStringInto(res, "[section]\nint=1\n[sub \"a\"]\n[sub \"b\"]\nname=file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.Section; s.Name != "env" || s.Int != 1 ||
+		!reflect.DeepEqual(s.Multi, []string{"a"}) || s.Unset != "" {
+		t.Errorf("got section %+v", s)
+	}
+	if n := res.Sub["a"].Name; n != "env" {
+		t.Errorf("got subsection a name %q, wanted %q", n, "env")
+	}
+	if n := res.Sub["b"].Name; n != "file" {
+		t.Errorf("got subsection b name %q, wanted %q", n, "file")
+	}
+	if len(res.Sub) != 2 {
+		t.Errorf("got %d subsections, wanted 2", len(res.Sub))
+	}
+
+	t.Setenv("GCFG_TEST_INT", "x")
+	if err := ReadStringInto(&cEnv{}, ""); err == nil {
+		t.Errorf("invalid environment value: no error")
+	}
+}
+
+type cFromFile struct {
+	Section struct {
+		Secret string `gcfg:",fromfile"`
+		Int    *int   `gcfg:",fromfile"`
+	}
+}
+
+func TestReadStringIntoFromFile(t *testing.T) {
+	dir := t.TempDir()
+	secret, num := dir+"/secret", dir+"/int"
+	if err := os.WriteFile(secret, []byte("s3cr3t\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(num, []byte("42\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	res := &cFromFile{}
+	cfg := fmt.Sprintf("[section]\nsecret=%q\nint=%q", secret, num)
+	if err := ReadStringInto(res, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if s := res.Section; s.Secret != "s3cr3t" || s.Int == nil || *s.Int != 42 {
+		t.Errorf("got section %+v", s)
+	}
+	err := ReadStringInto(&cFromFile{}, "[section]\nsecret="+dir+"/missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got error %v, wanted %v", err, os.ErrNotExist)
+	}
+}
+
+type cRelTo struct {
+	Section struct {
+		Path   string   `gcfg:",relto=config"`
+		Paths  []string `gcfg:",relto=config"`
+		Secret string   `gcfg:",fromfile,relto=config"`
+	}
+}
+
+func TestReadStringIntoDefault(t *testing.T) {
+	type sect struct {
+		Port    int           `gcfg:",default=8080"`
+		Host    string        `gcfg:",default=localhost"`
+		Timeout time.Duration `gcfg:",default=5s"`
+		Tags    []string      `gcfg:",default=a"`
+		Mode    string        `gcfg:",default=FAST,transform=lower"`
+		Other   int
+	}
+	type config struct {
+		Section sect
+		Sub     map[string]*sect
+	}
+	res := &config{}
+	res.Section.Host = "preset"
+	src := "[section]\nport=80\n[sub \"a\"]\ntags=b\n[sub \"b\"]\nhost="
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	exp := sect{Port: 80, Host: "preset", Timeout: 5 * time.Second,
+		Tags: []string{"a"}, Mode: "fast"}
+	if !reflect.DeepEqual(res.Section, exp) {
+		t.Errorf("got %+v, wanted %+v", res.Section, exp)
+	}
+	exp = sect{Port: 8080, Host: "localhost", Timeout: 5 * time.Second,
+		Tags: []string{"b"}, Mode: "fast"}
+	if !reflect.DeepEqual(*res.Sub["a"], exp) {
+		t.Errorf("got %+v, wanted %+v", *res.Sub["a"], exp)
+	}
+	// an empty value is not replaced by the default
+	if h := res.Sub["b"].Host; h != "" {
+		t.Errorf("got host %q, wanted empty", h)
+	}
+	// the environment takes precedence
+	type envSect struct {
+		Name string `gcfg:",env=GCFG_TEST_DEFAULT,default=dflt"`
+	}
+	t.Setenv("GCFG_TEST_DEFAULT", "env")
+	res2 := &struct{ Section envSect }{}
+	if err := ReadStringInto(res2, "[section]"); err != nil {
+		t.Fatal(err)
+	}
+	if res2.Section.Name != "env" {
+		t.Errorf("got %q, wanted %q", res2.Section.Name, "env")
+	}
+}
+
+func TestReadStringIntoRequired(t *testing.T) {
+	type sect struct {
+		Name string `gcfg:",required"`
+		Port int    `gcfg:",required,default=80"`
+		Host string `gcfg:",required,env=GCFG_TEST_REQUIRED"`
+	}
+	type config struct {
+		Section sect             `gcfg:",required"`
+		Sub     map[string]*sect `gcfg:",required"`
+	}
+	t.Setenv("GCFG_TEST_REQUIRED", "example.com")
+	res := &config{}
+	src := "[section]\nname=a\n[sub \"x\"]\nname=b\n[sub \"y\"]"
+	if err := ReadStringInto(res, src); err == nil {
+		t.Fatal("got no error, wanted error for name of subsection y")
+	} else if !errors.Is(err, ErrMissingRequired) {
+		t.Errorf("got error %v, wanted %v", err, ErrMissingRequired)
+	} else if got := err.Error(); !strings.Contains(got, `"y"`) ||
+		strings.Contains(got, `"x"`) {
+		t.Errorf("got error %q, wanted error for subsection y only", got)
+	}
+	if s := res.Sub["x"]; s.Port != 80 || s.Host != "example.com" {
+		t.Errorf("got %+v, wanted default port and host from environment", s)
+	}
+	// all missing sections and variables are reported
+	err := ReadStringInto(&config{}, "")
+	var l ErrorList
+	if !errors.As(err, &l) || len(l) != 2 {
+		t.Fatalf("got error %v, wanted 2 errors", err)
+	}
+	for _, err := range l {
+		if !errors.Is(err, ErrMissingRequired) {
+			t.Errorf("got error %v, wanted %v", err, ErrMissingRequired)
+		}
+	}
+	// with PartialResults, the errors are collected individually
+	err = ReadStringWithOptions(&config{}, "[section]\n[sub \"x\"]", PartialResults())
+	if !errors.As(err, &l) || len(l) != 2 {
+		t.Errorf("got error %v, wanted 2 errors", err)
+	}
+}
+
+type cValidate struct {
+	Range  cValidateS1
+	Ranges map[string]*cValidateS1
+}
+
+type cValidateS1 struct{ Min, Max int }
+
+var errValidate = errors.New("min is greater than max")
+
+func (s *cValidateS1) Validate() error {
+	if s.Min > s.Max {
+		return errValidate
+	}
+	return nil
+}
+
+func (c *cValidate) Validate() error {
+	if len(c.Ranges) > 2 {
+		return fmt.Errorf("too many ranges")
+	}
+	return nil
+}
+
+func TestReadStringIntoValidate(t *testing.T) {
+	for _, tt := range []struct {
+		src, exp string
+	}{
+		{"[range]\nmax=1\n[ranges \"a\"]\nmin=1\nmax=2", ""},
+		{"[range]\nmin=1", `min is greater than max at section "range"`},
+		{"[ranges \"a\"]\nmax=1\n[ranges \"b\"]\nmin=1",
+			`min is greater than max at section "ranges", subsection "b"`},
+		{"[ranges \"a\"]\n[ranges \"b\"]\n[ranges \"c\"]", "too many ranges"},
+	} {
+		err := ReadStringInto(&cValidate{}, tt.src)
+		switch {
+		case tt.exp == "" && err != nil:
+			t.Errorf("%q: got error %v", tt.src, err)
+		case tt.exp != "" && (err == nil || err.Error() != tt.exp):
+			t.Errorf("%q: got error %v, wanted %q", tt.src, err, tt.exp)
+		}
+	}
+	err := ReadStringWithOptions(&cValidate{},
+		"[range]\nmin=1\n[ranges \"a\"]\nmin=1", PartialResults())
+	var l ErrorList
+	if !errors.As(err, &l) || len(l) != 2 || !errors.Is(l[1], errValidate) {
+		t.Fatalf("got error %v, wanted 2 errors", err)
+	}
+	var ve *ValueError
+	if !errors.As(l[1], &ve) || *ve.Subsection != "a" {
+		t.Errorf("got error %v, wanted ValueError for subsection a", l[1])
+	}
+}
+
+func TestReadStringIntoMeta(t *testing.T) {
+	type sect struct {
+		Name   string
+		Int    int
+		Labels map[string]string `gcfg:",dotted"`
+	}
+	res := &struct {
+		Section sect
+		Sub     map[string]*sect
+	}{}
+	var m Meta
+	src := "[section]\nint=0\nlabels.team=x\n[sub \"A\"]\n[sub \"b\"]\nname=a\nname=b"
+	if err := ReadStringWithOptions(res, src, MetaInto(&m)); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		sect, sub, name string
+		exp             bool
+	}{
+		{"section", "", "", true},
+		{"Section", "", "Int", true},
+		{"section", "", "name", false},
+		{"section", "", "labels", true},
+		{"section", "", "labels.team", true},
+		{"sub", "A", "", true},
+		{"sub", "a", "", false},
+		{"sub", "A", "name", false},
+		{"sub", "", "", false},
+	} {
+		if got := m.Defined(tt.sect, tt.sub, tt.name); got != tt.exp {
+			t.Errorf("%q %q %q: got defined %v, wanted %v", tt.sect, tt.sub,
+				tt.name, got, tt.exp)
+		}
+	}
+	if ps := m.Positions("sub", "b", "name"); len(ps) != 2 || ps[0].Line != 6 ||
+		ps[1].Line != 7 {
+		t.Errorf("got positions %v, wanted lines 6 and 7", ps)
+	}
+	if subs := m.Subsections("SUB"); !reflect.DeepEqual(subs, []string{"A", "b"}) {
+		t.Errorf("got subsections %q, wanted %q", subs, []string{"A", "b"})
+	}
+}
+
+func TestReadStringIntoAny(t *testing.T) {
+	type sect struct {
+		Name  string
+		Extra map[string][]string `gcfg:",any"`
+	}
+	res := &struct {
+		Section sect
+		Sub     map[string]*sect
+	}{}
+	src := "[section]\nname=a\nNew=x\nnew=y\nextra=z\nlabels.team=t\n" +
+		"[sub \"s\"]\nother=o"
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	exp := sect{Name: "a", Extra: map[string][]string{"new": {"x", "y"},
+		"extra": {"z"}, "labels.team": {"t"}}}
+	if !reflect.DeepEqual(res.Section, exp) {
+		t.Errorf("got %+v, wanted %+v", res.Section, exp)
+	}
+	exp = sect{Extra: map[string][]string{"other": {"o"}}}
+	if !reflect.DeepEqual(*res.Sub["s"], exp) {
+		t.Errorf("got %+v, wanted %+v", *res.Sub["s"], exp)
+	}
+	if err := RoundTrip(res); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadStringIntoAnySections(t *testing.T) {
+	res := &struct {
+		Section struct{ Name string }
+		Plugins map[string]map[string]string `gcfg:",any"`
+	}{}
+	src := "[section]\nname=a\n[Cache]\nSize=1\n[plugin \"Auth\"]\nkey=k\n" +
+		"[empty]\n[cache]\nsize=2"
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]map[string]string{"cache": {"size": "2"},
+		"plugin.Auth": {"key": "k"}, "empty": {}}
+	if res.Section.Name != "a" || !reflect.DeepEqual(res.Plugins, exp) {
+		t.Errorf("got %+v, wanted plugins %v", res, exp)
+	}
+	b, err := Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expB := "[section]\n\tname = a\n\n[cache]\n\tsize = 2\n\n[empty]\n" +
+		"\n[plugin \"Auth\"]\n\tkey = k\n"
+	if string(b) != expB {
+		t.Errorf("got\n%s\nwanted\n%s", b, expB)
+	}
+	if err := RoundTrip(res); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadStringIntoEquals(t *testing.T) {
+	for _, tt := range []struct {
+		src, exp string
+	}{
+		{"query=a=b", "a=b"},
+		{"query = a = b ", "a = b"},
+		{"query==b", "=b"},
+		{"query=a=", "a="},
+		{"query=a=b ; c=d", "a=b"},
+		{`query="a=;"=b`, "a=;=b"},
+	} {
+		res := &struct{ Section struct{ Query string } }{}
+		if err := ReadStringInto(res, "[section]\n"+tt.src); err != nil {
+			t.Errorf("%q: got error %v", tt.src, err)
+		} else if res.Section.Query != tt.exp {
+			t.Errorf("%q: got %q, wanted %q", tt.src, res.Section.Query, tt.exp)
+		}
+	}
+}
+
+func TestReadStringIntoNumericMaps(t *testing.T) {
+	type sect struct {
+		Quota  map[string]*big.Int   `gcfg:",dotted,min=0"`
+		Weight map[string]*big.Float `gcfg:",dotted,min=0,max=1"`
+		Ratio  map[string]float64    `gcfg:",dotted,max=1"`
+	}
+	res := &struct{ Section sect }{}
+	src := "[section]\nquota.a=0x10\nquota.b=12345678901234567890123\n" +
+		"weight.a=0.25\nweight.b=0x1p-1\nratio.a=0.5"
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	s := res.Section
+	q, _ := new(big.Int).SetString("12345678901234567890123", 10)
+	if s.Quota["a"].Int64() != 16 || s.Quota["b"].Cmp(q) != 0 ||
+		s.Weight["a"].String() != "0.25" || s.Weight["b"].String() != "0.5" ||
+		s.Ratio["a"] != 0.5 {
+		t.Errorf("got %+v", s)
+	}
+	if err := RoundTrip(res); err != nil {
+		t.Error(err)
+	}
+	for _, tt := range []struct {
+		src, exp string
+	}{
+		{"quota.a=-1", "value -1 out of range: must be at least 0"},
+		{"weight.a=1.5", "value 1.5 out of range: must be between 0 and 1"},
+		{"ratio.a=2", "value 2 out of range: must be at most 1"},
+		{"weight.a=x", "weight.a"},
+	} {
+		err := ReadStringInto(res, "[section]\n"+tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.exp) {
+			t.Errorf("%q: got error %v, wanted %q", tt.src, err, tt.exp)
+		}
+	}
+}
+
+func TestReadFSInto(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/app.gcfg": {Data: []byte("\ufeff[section]\npath=data\n" +
+			"paths=a/b\nsecret=secret")},
+		"conf/secret": {Data: []byte("s3cr3t\n")},
+	}
+	res := &cRelTo{}
+	if err := ReadFSInto(res, fsys, "conf/app.gcfg"); err != nil {
+		t.Fatal(err)
+	}
+	s := res.Section
+	if s.Path != "conf/data" || !reflect.DeepEqual(s.Paths, []string{"conf/a/b"}) ||
+		s.Secret != "s3cr3t" {
+		t.Errorf("got %+v, wanted values relative to conf in fsys", s)
+	}
+	err := ReadFSWithOptions(&cBasic{}, fsys, "conf/app.gcfg",
+		WarningHandler(func(err error, sev Severity) Severity {
+			return SeverityError
+		}))
+	if err == nil || !strings.HasPrefix(err.Error(), "conf/app.gcfg:2:1: ") {
+		t.Errorf("got error %v, wanted error at conf/app.gcfg:2:1", err)
+	}
+	if err := ReadFSInto(res, fsys, "nonexistent"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, wanted %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestReadFilesInto(t *testing.T) {
+	type config struct {
+		Section struct {
+			Name  string
+			Int   int
+			Multi []string
+		}
+	}
+	dir := t.TempDir()
+	files := []string{"[section]\nname=system\nint=1\nmulti=a\nmulti=b",
+		"[section]\nname=global\nmulti=c\nunknown=x",
+		"[section]\nmulti=d\nmulti=e"}
+	var filenames []string
+	for i, f := range files {
+		filename := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.WriteFile(filename, []byte(f), 0600); err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, filename)
+	}
+	for _, tt := range []struct {
+		opts  []Option
+		multi []string
+	}{
+		{nil, []string{"a", "b", "c", "d", "e"}},
+		{[]Option{ReplaceMultiValues()}, []string{"d", "e"}},
+	} {
+		res := &config{}
+		err := ReadFilesWithOptions(res, filenames, tt.opts...)
+		// the unknown variable, and multi defined once in the second file
+		if FatalOnly(err) != nil || len(warnings.WarningsOnly(err)) != 3 {
+			t.Errorf("got error %v, wanted 3 warnings", err)
+		}
+		s := res.Section
+		if s.Name != "global" || s.Int != 1 || !reflect.DeepEqual(s.Multi, tt.multi) {
+			t.Errorf("got %+v, wanted name global, int 1, multi %q", s, tt.multi)
+		}
+	}
+	// reading stops at the first fatal error
+	res := &config{}
+	err := ReadFilesInto(res, filenames[0], filepath.Join(dir, "nonexistent"),
+		filenames[1])
+	if !errors.Is(FatalOnly(err), os.ErrNotExist) || res.Section.Name != "system" {
+		t.Errorf("got error %v, name %q; wanted %v after the first file", err,
+			res.Section.Name, os.ErrNotExist)
+	}
+}
+
+func TestReadFilesIntoInconsistentUse(t *testing.T) {
+	dir := t.TempDir()
+	var filenames []string
+	for i, f := range []string{"[section]\nname=a\nint=1",
+		"[section]\nname=b\nname=c\nint=2", "[section]\nname=d"} {
+		filename := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.WriteFile(filename, []byte(f), 0600); err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, filename)
+	}
+	err := ReadFilesInto(&cBasic{}, filenames...)
+	w := warnings.WarningsOnly(err)
+	if FatalOnly(err) != nil || len(w) != 2 {
+		t.Fatalf("got error %v, wanted 2 warnings", err)
+	}
+	for i, exp := range []InconsistentUseError{
+		{Pos: token.Position{Filename: filenames[1], Line: 2, Column: 1},
+			PrevPos: token.Position{Filename: filenames[0], Line: 2, Column: 1},
+			Section: "section", Variable: "name", Repeated: true},
+		{Pos: token.Position{Filename: filenames[2], Line: 2, Column: 1},
+			PrevPos: token.Position{Filename: filenames[1], Line: 2, Column: 1},
+			Section: "section", Variable: "name"},
+	} {
+		var e *InconsistentUseError
+		if !errors.As(w[i], &e) || !errors.Is(e, ErrInconsistentUse) {
+			t.Errorf("%d: got %v, wanted InconsistentUseError", i, w[i])
+			continue
+		}
+		e.Pos.Offset, e.PrevPos.Offset = 0, 0
+		if !reflect.DeepEqual(*e, exp) {
+			t.Errorf("%d: got %+v, wanted %+v", i, *e, exp)
+		}
+	}
+	// the warnings can be made fatal
+	err = ReadFilesWithOptions(&cBasic{}, filenames,
+		WarningHandler(func(err error, sev Severity) Severity {
+			return SeverityError
+		}))
+	if !errors.Is(FatalOnly(err), ErrInconsistentUse) {
+		t.Errorf("got error %v, wanted %v", err, ErrInconsistentUse)
+	}
+}
+
+func TestReadFileIntoRelTo(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/secret", []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	filename := dir + "/config.gcfg"
+	cfg := "[section]\npath=data\npaths=a/b\npaths=/abs\nsecret=secret"
+	if err := os.WriteFile(filename, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	res := &cRelTo{}
+	if err := ReadFileInto(res, filename); err != nil {
+		t.Fatal(err)
+	}
+	s := res.Section
+	if exp := filepath.Join(dir, "data"); s.Path != exp {
+		t.Errorf("got path %q, wanted %q", s.Path, exp)
+	}
+	if exp := []string{filepath.Join(dir, "a/b"), "/abs"}; !reflect.DeepEqual(s.Paths, exp) {
+		t.Errorf("got paths %q, wanted %q", s.Paths, exp)
+	}
+	if s.Secret != "s3cr3t" {
+		t.Errorf("got secret %q, wanted %q", s.Secret, "s3cr3t")
+	}
+	// without a file name, paths are left as is
+	res = &cRelTo{}
+	if err := ReadStringInto(res, "[section]\npath=data"); err != nil {
+		t.Fatal(err)
+	}
+	if res.Section.Path != "data" {
+		t.Errorf("got path %q, wanted %q", res.Section.Path, "data")
+	}
+}
+
+type cDotted struct {
+	Section struct {
+		Labels map[string]string `gcfg:",dotted"`
+		Ports  map[string][]int  `gcfg:",dotted"`
+		Name   string
+	}
+}
+
+var dottedtests = []struct {
+	cfg string
+	exp map[string]string
+	ok  bool
+}{
+	{"labels.team = infra\nlabels.Tier-1 = x", map[string]string{"team": "infra", "Tier-1": "x"}, true},
+	{"LABELS.team = a\nlabels.team = b", map[string]string{"team": "b"}, true},
+	{"labels.0 = zero", map[string]string{"0": "zero"}, true},
+	{"labels.a = a\nlabels\nlabels.b = b", map[string]string{"b": "b"}, true},
+	{"labels = x", nil, false},
+	{"labels. = x", nil, false},
+	{"name.x = x", nil, false},
+	{"unknown.x = x", nil, false},
+}
+
+func TestReadStringIntoDotted(t *testing.T) {
+	for i, tt := range dottedtests {
+		res := &cDotted{}
+		err := ReadStringInto(res, "[section]\n"+tt.cfg)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%d: got error %v, wanted ok %v", i, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(res.Section.Labels, tt.exp) {
+			t.Errorf("%d: got %v, wanted %v", i, res.Section.Labels, tt.exp)
+		}
+	}
+	res := &cDotted{}
+	err := ReadStringInto(res, "[section]\nports.http=80\nports.http=8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := map[string][]int{"http": {80, 8080}}; !reflect.DeepEqual(res.Section.Ports, exp) {
+		t.Errorf("got %v, wanted %v", res.Section.Ports, exp)
+	}
+}
+
+var duplicatemapkeystests = []struct {
+	policy MapKeyPolicy
+	cfg    string
+	exp    map[string]string
+	ok     bool
+}{
+	{MapKeysLastWins, "labels.a = x\nlabels.a = y", map[string]string{"a": "y"}, true},
+	{MapKeysFirstWins, "labels.a = x\nlabels.a = y", map[string]string{"a": "x"}, true},
+	{MapKeysFirstWins, "labels.a = x\nlabels\nlabels.a = y", map[string]string{"a": "y"}, true},
+	{MapKeysError, "labels.a = x\nlabels.b = y", map[string]string{"a": "x", "b": "y"}, true},
+	{MapKeysError, "labels.a = x\nlabels.a = y", nil, false},
+	{MapKeysError, "labels.a = x\nlabels.A = y", map[string]string{"a": "x", "A": "y"}, true},
+	{MapKeysConcat, "labels.a = x\nlabels.a = y\nlabels.a = z", map[string]string{"a": "x,y,z"}, true},
+	{MapKeysError, "ports.http = 80\nports.http = 8080", map[string]string(nil), true},
+}
+
+func TestReadWithOptionsDuplicateMapKeys(t *testing.T) {
+	for i, tt := range duplicatemapkeystests {
+		res := &cDotted{}
+		err := ReadStringWithOptions(res, "[section]\n"+tt.cfg,
+			DuplicateMapKeys(tt.policy, ","))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%d: got error %v, wanted ok %v", i, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(res.Section.Labels, tt.exp) {
+			t.Errorf("%d: got %v, wanted %v", i, res.Section.Labels, tt.exp)
+		}
+	}
+	// keys set before reading don't count
+	res := &cDotted{}
+	res.Section.Labels = map[string]string{"a": "default"}
+	err := ReadStringWithOptions(res, "[section]\nlabels.a = x",
+		DuplicateMapKeys(MapKeysError, ""))
+	if err != nil || res.Section.Labels["a"] != "x" {
+		t.Errorf("got %v, error %v; wanted key set to %q", res.Section.Labels,
+			err, "x")
+	}
+}
+
+type cIndexed struct {
+	Section struct {
+		Server []string   `gcfg:",indexed"`
+		Port   []*int     `gcfg:",indexed"`
+		Multi  [][]string `gcfg:",indexed"`
+	}
+}
+
+var indexedtests = []struct {
+	cfg string
+	exp []string
+	ok  bool
+}{
+	{"server.0 = a\nserver.1 = b", []string{"a", "b"}, true},
+	{"server[1] = b\nserver[0] = a", []string{"a", "b"}, true},
+	{"server.0 = a\nserver\nserver.0 = b", []string{"b"}, true},
+	{"server.0 = a\nserver[0] = b", nil, false},
+	{"server.0 = a\nserver.2 = c", nil, false},
+	{"server.1 = b", nil, false},
+	{"server.01 = a", nil, false},
+	{"server.x = a", nil, false},
+	{"server[0 = a", nil, false},
+	{"server.100000 = a", nil, false},
+	{"server = a", nil, false},
+}
+
+func TestReadStringIntoIndexed(t *testing.T) {
+	for i, tt := range indexedtests {
+		res := &cIndexed{}
+		err := ReadStringInto(res, "[section]\n"+tt.cfg)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%d: got error %v, wanted ok %v", i, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(res.Section.Server, tt.exp) {
+			t.Errorf("%d: got %q, wanted %q", i, res.Section.Server, tt.exp)
+		}
+	}
+	res := &cIndexed{}
+	err := ReadStringInto(res, "[section]\nport.1=2\nport.0=1\n"+
+		"multi.1=c\nmulti.0=a\nmulti.0=b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Section.Port; len(p) != 2 || *p[0] != 1 || *p[1] != 2 {
+		t.Errorf("got port %v", p)
+	}
+	if exp := [][]string{{"a", "b"}, {"c"}}; !reflect.DeepEqual(res.Section.Multi, exp) {
+		t.Errorf("got multi %q, wanted %q", res.Section.Multi, exp)
+	}
+}
+
+type cAppend struct {
+	Section struct {
+		Name  string
+		PName *string
+		Multi []string
+	}
+}
+
+var appendtests = []struct {
+	cfg   string
+	name  string
+	pname *string
+	multi []string
+	ok    bool
+}{
+	{"name=a\nname+=b", "a b", nil, nil, true},
+	{"name+=a\nname += b\nname+=", "a b ", nil, nil, true},
+	{"pname+=a\npname+=b", "", newString("a b"), nil, true},
+	{"multi=a\nmulti+=b", "", nil, []string{"a", "b"}, true},
+	{"multi\nmulti+=a", "", nil, []string{"a"}, true},
+	{"name+", "", nil, nil, false},
+}
+
+func TestReadWithOptionsAppendOperator(t *testing.T) {
+	for i, tt := range appendtests {
+		res := &cAppend{}
+		err := ReadWithOptions(res, strings.NewReader("[section]\n"+tt.cfg),
+			AppendOperator(" "))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%d: got error %v, wanted ok %v", i, err, tt.ok)
+			continue
+		}
+		if s := res.Section; tt.ok && (s.Name != tt.name ||
+			!reflect.DeepEqual(s.PName, tt.pname) ||
+			!reflect.DeepEqual(s.Multi, tt.multi)) {
+			t.Errorf("%d: got %+v", i, s)
+		}
+	}
+	err := ReadWithOptions(&cBasic{}, strings.NewReader("[section]\nint=1\nint+=2"),
+		AppendOperator(","))
+	if !errors.Is(err, errAppendUnsupported) {
+		t.Errorf("append to int: got error %v, wanted %v", err, errAppendUnsupported)
+	}
+	err = ReadStringInto(&cBasic{}, "[section]\nname+=a")
+	if !errors.Is(err, ErrSyntax) {
+		t.Errorf("append not enabled: got error %v, wanted %v", err, ErrSyntax)
+	}
+}
+
+var platformtests = []struct {
+	goos, goarch string
+	exp          string
+}{
+	{"linux", "amd64", "linux-amd64"},
+	{"linux", "arm64", "arm64"},
+	{"linux", "386", "linux"},
+	{"windows", "arm64", "windows-arm64"},
+	{"darwin", "386", "base"},
+}
+
+func TestReadWithOptionsPlatform(t *testing.T) {
+	cfg := `[section]
+name = base
+[section "linux"]
+name = linux
+[section "arm64"]
+name = arm64
+[section "linux/amd64"]
+name = linux-amd64
+[section "windows/arm64"]
+name = windows-arm64
+`
+	for _, tt := range platformtests {
+		res := &cBasic{}
+		err := ReadWithOptions(res, strings.NewReader(cfg),
+			Platform(tt.goos, tt.goarch))
+		if err != nil {
+			t.Errorf("%s/%s: got error %v", tt.goos, tt.goarch, err)
+			continue
+		}
+		if res.Section.Name != tt.exp {
+			t.Errorf("%s/%s: got %q, wanted %q", tt.goos, tt.goarch,
+				res.Section.Name, tt.exp)
+		}
+	}
+	err := ReadWithOptions(&cBasic{}, strings.NewReader("[section \"unknown\"]"),
+		Platform("linux", "amd64"))
+	if err == nil || FatalOnly(err) != nil {
+		t.Errorf("unknown subsection: got error %v, wanted warning", err)
+	}
+	if err := ReadStringInto(&cBasic{}, cfg); err == nil {
+		t.Errorf("without option: no error")
+	}
+}
+
+type cMail struct {
+	Section struct {
+		From *mail.Address
+		To   []*mail.Address
+	}
+}
+
+func TestReadStringIntoMailAddress(t *testing.T) {
+	res := &cMail{}
+	cfg := "[section]\nfrom = Alerts <alerts@example.com>\n" +
+		"to = a@example.com, \"B. User\" <b@example.com>\nto = c@example.com"
+	if err := ReadStringInto(res, cfg); err != nil {
+		t.Fatal(err)
+	}
+	from := mail.Address{Name: "Alerts", Address: "alerts@example.com"}
+	if res.Section.From == nil || *res.Section.From != from {
+		t.Errorf("got from %v, wanted %v", res.Section.From, from)
+	}
+	exp := []*mail.Address{
+		{Address: "a@example.com"},
+		{Name: "B. User", Address: "b@example.com"},
+		{Address: "c@example.com"},
+	}
+	if !reflect.DeepEqual(res.Section.To, exp) {
+		t.Errorf("got to %v, wanted %v", res.Section.To, exp)
+	}
+	if err := ReadStringInto(&cMail{}, "[section]\nto = a@example.com, invalid"); err == nil {
+		t.Errorf("invalid address list: no error")
+	}
+}
+
+type cVersion struct {
+	Section struct {
+		Version  types.Version
+		Versions []*types.Version `gcfg:",minver=1.2.0"`
+		Min      *types.Version   `gcfg:",minver=1.2.0"`
+	}
+}
+
+var versiontests = []struct {
+	cfg string
+	ok  bool
+}{
+	{"version = 0.1.0-rc.1", true},
+	{"version = 1.x", false},
+	{"min = 1.2.0", true},
+	{"min = v2.0.0", true},
+	{"min = 1.2.0-rc.1", false},
+	{"min = 1.1.9", false},
+	{"versions = 1.2.0\nversions = 1.0.0", false},
+}
+
+func TestReadStringIntoVersion(t *testing.T) {
+	for _, tt := range versiontests {
+		err := ReadStringInto(&cVersion{}, "[section]\n"+tt.cfg)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%q: got error %v, wanted ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
+
+type cHostPort struct {
+	Section struct {
+		Addr    types.HostPort
+		Members []types.HostPort `gcfg:",port=7946"`
+	}
+}
+
+func TestReadStringIntoHostPort(t *testing.T) {
+	res := &cHostPort{}
+	cfg := "[section]\naddr = :8080\nmembers = a\nmembers = b:1\nmembers = ::1"
+	if err := ReadStringInto(res, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (types.HostPort{Port: 8080}); res.Section.Addr != exp {
+		t.Errorf("got addr %+v, wanted %+v", res.Section.Addr, exp)
+	}
+	exp := []types.HostPort{{Host: "a", Port: 7946}, {Host: "b", Port: 1},
+		{Host: "::1", Port: 7946}}
+	if !reflect.DeepEqual(res.Section.Members, exp) {
+		t.Errorf("got members %+v, wanted %+v", res.Section.Members, exp)
+	}
+	if err := ReadStringInto(&cHostPort{}, "[section]\naddr = a"); err == nil {
+		t.Errorf("missing port: no error")
+	}
+}
+
+type cCIDR struct {
+	Section struct {
+		Allow types.CIDRList
+		Deny  types.CIDRList `gcfg:",nooverlap"`
+	}
+}
+
+func TestReadStringIntoCIDRList(t *testing.T) {
+	res := &cCIDR{}
+	cfg := "[section]\nallow = 10.0.0.0/8, 10.1.0.0/16\nallow = ::1\n" +
+		"deny = 192.168.0.0/16\ndeny = 172.16.0.0/12"
+	if err := ReadStringInto(res, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "10.0.0.0/8, 10.1.0.0/16, ::1/128"; res.Section.Allow.String() != exp {
+		t.Errorf("got allow %q, wanted %q", res.Section.Allow, exp)
+	}
+	if exp := "192.168.0.0/16, 172.16.0.0/12"; res.Section.Deny.String() != exp {
+		t.Errorf("got deny %q, wanted %q", res.Section.Deny, exp)
+	}
+	cfg = "[section]\ndeny = 192.168.0.0/16\ndeny = 192.168.1.0/24"
+	if err := ReadStringInto(&cCIDR{}, cfg); err == nil {
+		t.Errorf("overlapping prefixes: no error")
+	}
+}
+
+type cDuration struct {
+	Section struct {
+		Timeout  time.Duration  `gcfg:",min=1s,max=10m"`
+		PTimeout *time.Duration `gcfg:",max=1h"`
+		Retries  int            `gcfg:",min=1"`
+		Nanos    time.Duration
+	}
+}
+
+var durationtests = []struct {
+	cfg string
+	err string
+}{
+	{"timeout = 1m30s", ""},
+	{"timeout = 1s", ""},
+	{"timeout = 10m", ""},
+	{"timeout = 500ms", "value 500ms out of range: must be between 1s and 10m0s"},
+	{"timeout = 11m", "value 11m0s out of range: must be between 1s and 10m0s"},
+	{"ptimeout = 2h", "value 2h0m0s out of range: must be at most 1h0m0s"},
+	{"retries = 0", "value 0 out of range: must be at least 1"},
+	{"nanos = 1000", ""},
+	{"nanos = 1x", "time: unknown unit"},
+}
+
+func TestReadStringIntoDurationRange(t *testing.T) {
+	for _, tt := range durationtests {
+		err := ReadStringInto(&cDuration{}, "[section]\n"+tt.cfg)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%q: got error %v", tt.cfg, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%q: got error %v, wanted %q", tt.cfg, err, tt.err)
+		}
+	}
+	res := &cDuration{}
+	if err := ReadStringInto(res, "[section]\ntimeout=1m30s\nnanos=1000"); err != nil {
+		t.Fatal(err)
+	}
+	if s := res.Section; s.Timeout != 90*time.Second || s.Nanos != 1000 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+type cURL struct {
+	Section struct {
+		URL     url.URL
+		Webhook *url.URL   `gcfg:",schemes=https,requirehost"`
+		Mirrors []*url.URL `gcfg:",schemes=http|https"`
+	}
+}
+
+var urltests = []struct {
+	cfg string
+	ok  bool
+}{
+	{"url = file:///etc/passwd", true},
+	{"url = %zz", false},
+	{"webhook = https://example.com/hook", true},
+	{"webhook = HTTPS://example.com/hook", true},
+	{"webhook = http://example.com/hook", false},
+	{"webhook = file:///tmp/hook", false},
+	{"webhook = https:///hook", false},
+	{"webhook = https:hook", false},
+	{"mirrors = http://a\nmirrors = https://b", true},
+	{"mirrors = http://a\nmirrors = ftp://b", false},
+}
+
+func TestReadStringIntoURL(t *testing.T) {
+	for _, tt := range urltests {
+		res := &cURL{}
+		err := ReadStringInto(res, "[section]\n"+tt.cfg)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%q: got error %v, wanted ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+	res := &cURL{}
+	if err := ReadStringInto(res, "[section]\nwebhook = https://example.com/hook?a=1"); err != nil {
+		t.Fatal(err)
+	}
+	if u := res.Section.Webhook; u == nil || u.Host != "example.com" || u.RawQuery != "a=1" {
+		t.Errorf("got webhook %v", u)
+	}
+}
+
+func BenchmarkReadStringInto(b *testing.B) {
+	var src strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&src, "[sub \"s%d\"]\nname = value %d ; comment\n", i, i)
+	}
+	s := src.String()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		cfg := &cSubs{}
+		if err := ReadStringInto(cfg, s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadStringIntoStrings(b *testing.B) {
+	type config struct {
+		Section struct {
+			Multi []string
+			Name  string
+		}
+	}
+	var src strings.Builder
+	src.WriteString("[section]\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&src, "multi = \"value %d with \\\"quotes\\\"\" and text\n", i)
+		fmt.Fprintf(&src, "name = plain value %d ; comment\n", i)
+	}
+	s := src.String()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		cfg := &config{}
+		if err := ReadStringInto(cfg, s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMustReadStringInto(t *testing.T) {
+	res := &cBasic{}
+	MustReadStringInto(res, "[section]\nname=value")
+	if res.Section.Name != "value" {
+		t.Errorf("got %q, wanted %q", res.Section.Name, "value")
+	}
+	for _, tt := range []struct {
+		gcfg string
+		exp  string
+	}{
+		{"[section]\nname", "gcfg: failed to read config: 2:1: blank value not supported for type string (only bool variables can be specified without '= value') at section \"section\", variable \"name\""},
+		{"[section]\nname=value\n[nonexistent]\n[section]\nnonexistent=1\n",
+			"gcfg: failed to read config:\n\t3:2: can't store data at section \"nonexistent\"\n\t5:1: can't store data at section \"section\", variable \"nonexistent\""},
+	} {
+		func() {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok || err.Error() != tt.exp {
+					t.Errorf("%q: got panic %v, wanted %q", tt.gcfg, err, tt.exp)
+				}
+			}()
+			MustReadStringInto(&cBasic{}, tt.gcfg)
+		}()
+	}
+}
+
+func TestMustReadFileInto(t *testing.T) {
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !errors.Is(err, os.ErrNotExist) ||
+			!strings.HasPrefix(err.Error(),
+				"gcfg: failed to read config from testdata/nonexistent: ") {
+			t.Errorf("got panic %v", err)
+		}
+	}()
+	MustReadFileInto(&cBasic{}, "testdata/nonexistent")
+}
+
+func TestReadIntoAll(t *testing.T) {
+	type db struct {
+		Database struct{ Host string }
+	}
+	type web struct {
+		Server struct{ Port int }
+		Sites  map[string]*struct{ Root string }
+		Common struct{ Name string }
+	}
+	src := "[database]\nhost=db\n[server]\nport=80\n[sites \"a\"]\nroot=/a\n" +
+		"[common]\nname=x\n"
+	d, w := &db{}, &web{}
+	if err := ReadIntoAll(strings.NewReader(src), d, w); err != nil {
+		t.Fatal(err)
+	}
+	if d.Database.Host != "db" || w.Server.Port != 80 ||
+		w.Sites["a"] == nil || w.Sites["a"].Root != "/a" ||
+		w.Common.Name != "x" {
+		t.Errorf("got %+v, %+v", d, w)
+	}
+	for _, tt := range []struct {
+		gcfg string
+		err  error
+	}{
+		{"[unknown]\n", ErrUnknownSection},
+		{"[server]\nunknown=1\n", ErrUnknownVariable},
+		{"[sites \"b\"]\nunknown=1\n", ErrUnknownVariable},
+	} {
+		err := ReadIntoAll(strings.NewReader(tt.gcfg), &db{}, &web{})
+		w := warnings.WarningsOnly(err)
+		if FatalOnly(err) != nil || len(w) != 1 || !errors.Is(w[0], tt.err) {
+			t.Errorf("%q: got error %v, wanted %v", tt.gcfg, err, tt.err)
+		}
+	}
+}
+
+func TestReadStringIntoErrorValue(t *testing.T) {
+	res := &struct {
+		Section struct {
+			Int    int
+			Bool   bool
+			Secret int  `gcfg:",secret"`
+			Pass   bool `gcfg:",secret"`
+		}
+	}{}
+	for _, tt := range []struct {
+		gcfg, exp string
+	}{
+		{"int=x", `2:1: failed to parse "x" as int: expected integer at section "section", variable "int"`},
+		{"bool=x", "2:1: failed to parse bool `x` at section \"section\", variable \"bool\", value \"x\""},
+		{"int=1 2", `2:1: failed to parse "1 2" as int: extra characters "2" at section "section", variable "int"`},
+		{"secret=x1", `2:1: invalid value at section "section", variable "secret", value <redacted>`},
+		{"pass=\"a\\tb\"", `2:1: invalid value at section "section", variable "pass", value <redacted>`},
+	} {
+		err := ReadStringInto(res, "[section]\n"+tt.gcfg)
+		if err == nil || err.Error() != tt.exp {
+			t.Errorf("%q: got error %v, wanted %q", tt.gcfg, err, tt.exp)
+		}
+	}
+}
+
+func TestReadStringIntoImplicit(t *testing.T) {
+	type sect struct {
+		Level   string   `gcfg:",implicit=debug"`
+		Retries int      `gcfg:",implicit=3"`
+		Quiet   bool     `gcfg:",implicit=false"`
+		Tags    []string `gcfg:",implicit=all"`
+		Name    string
+	}
+	res := &struct{ Section sect }{Section: sect{Quiet: true}}
+	err := ReadStringInto(res, "[section]\nlevel\nretries\nquiet\ntags=a\ntags\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := sect{Level: "debug", Retries: 3, Tags: []string{"a", "all"}}
+	if !reflect.DeepEqual(res.Section, exp) {
+		t.Errorf("got %+v, wanted %+v", res.Section, exp)
+	}
+	err = ReadStringInto(res, "[section]\nname")
+	if !errors.Is(err, errBlankUnsupported) {
+		t.Errorf("got error %v, wanted %v", err, errBlankUnsupported)
+	}
+}
+
+func TestReadStringIntoIntOverflow(t *testing.T) {
+	res := &struct{ Section struct{ Small int8 } }{}
+	err := ReadStringInto(res, "[section]\nsmall=300")
+	exp := `2:1: value "300" out of range for int8: must be between -128 and 127` +
+		` at section "section", variable "small"`
+	if err == nil || err.Error() != exp {
+		t.Errorf("got error %v, wanted %q", err, exp)
+	}
+}
+
+func init() {
+	RegisterTransform("test-nonempty", func(s string) (string, error) {
+		if s == "" {
+			return "", fmt.Errorf("empty value")
+		}
+		return s, nil
+	})
+	// registers a transform named by the value while being applied
+	RegisterTransform("test-register", func(s string) (string, error) {
+		RegisterTransform("test-registered-"+s, func(s string) (string, error) {
+			return strings.ToUpper(s), nil
+		})
+		return s, nil
+	})
+}
+
+func TestReadStringIntoTransform(t *testing.T) {
+	type sect struct {
+		Mode  string   `gcfg:",transform=trim|lower"`
+		Code  string   `gcfg:",transform=upper"`
+		Level int      `gcfg:",transform=trim"`
+		Tags  []string `gcfg:",transform=lower"`
+		Name  string   `gcfg:",transform=test-nonempty"`
+	}
+	res := &struct{ Section sect }{}
+	src := "[section]\nmode=\" Fast \"\ncode=ab\nlevel=\" 3 \"\ntags=A\ntags=b\nname=x"
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	exp := sect{Mode: "fast", Code: "AB", Level: 3, Tags: []string{"a", "b"},
+		Name: "x"}
+	if !reflect.DeepEqual(res.Section, exp) {
+		t.Errorf("got %+v, wanted %+v", res.Section, exp)
+	}
+	err := ReadStringInto(res, "[section]\nname=\"\"")
+	exp2 := `2:1: empty value at section "section", variable "name", value ""`
+	if err == nil || err.Error() != exp2 {
+		t.Errorf("got error %v, wanted %q", err, exp2)
+	}
+	res2 := &struct {
+		Section struct {
+			Name string `gcfg:",transform=test-register|test-registered-x"`
+		}
+	}{}
+	if err := ReadStringInto(res2, "[section]\nname=x"); err != nil ||
+		res2.Section.Name != "X" {
+		t.Errorf("got %q, %v; wanted %q", res2.Section.Name, err, "X")
+	}
+}
+
+func init() {
+	RegisterSetter("test-millis", func(d interface{}, value string) error {
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d.(*int64) = dur.Milliseconds()
+		return nil
+	})
+}
+
+func TestReadStringIntoSetter(t *testing.T) {
+	type sect struct {
+		Timeout  int64   `gcfg:",setter=test-millis"`
+		Count    int64   // parsed as usual
+		Delays   []int64 `gcfg:",setter=test-millis"`
+		Disabled int64   `gcfg:",setter=test-millis,implicit=0s"`
+	}
+	res := &struct{ Section sect }{}
+	src := "[section]\ntimeout=1.5s\ncount=15\ndelays=1s\ndelays=2ms\ndisabled"
+	if err := ReadStringInto(res, src); err != nil {
+		t.Fatal(err)
+	}
+	exp := sect{Timeout: 1500, Count: 15, Delays: []int64{1000, 2}}
+	if !reflect.DeepEqual(res.Section, exp) {
+		t.Errorf("got %+v, wanted %+v", res.Section, exp)
+	}
+	for _, src := range []string{"timeout=1500", "timeout"} {
+		if err := ReadStringInto(res, "[section]\n"+src); err == nil {
+			t.Errorf("%q: got ok, wanted error", src)
+		}
+	}
+}
+
+func TestReadWithOptionsINIDialect(t *testing.T) {
+	type config struct {
+		Log_Files struct {
+			Path    string
+			Format  string
+			Message string
+			Verbose bool
+		}
+		Server struct {
+			Host_Name string
+			Banner    string
+		}
+	}
+	src := "; comment\r\n[log files]\r\npath: C:\\logs\\\"x\"\r\n" +
+		"format = %(asctime)s ; not a comment\r\nmessage =\r\n" +
+		"  first\r\n\r\n  second\r\nverbose\r\n" +
+		"[server]\nhost_name:example.com\nbanner = a\n\tb\n"
+	cfg := &config{}
+	if err := ReadWithOptions(cfg, strings.NewReader(src),
+		INIDialect()); err != nil {
+		t.Fatal(err)
+	}
+	exp := config{}
+	exp.Log_Files.Path = `C:\logs\"x"`
+	exp.Log_Files.Format = "%(asctime)s ; not a comment"
+	exp.Log_Files.Message = "\nfirst\nsecond"
+	exp.Log_Files.Verbose = true
+	exp.Server.Host_Name = "example.com"
+	exp.Server.Banner = "a\nb"
+	if !reflect.DeepEqual(*cfg, exp) {
+		t.Errorf("got %+v, wanted %+v", *cfg, exp)
+	}
+	err := ReadWithOptions(&config{}, strings.NewReader("[server]\n\nbad: 1\n"),
+		INIDialect())
+	if err == nil || !strings.Contains(err.Error(), "3:1: can't store data") {
+		t.Errorf("got error %v, wanted warning on line 3", err)
+	}
+}
+
+func TestReadStringIntoStrictLenient(t *testing.T) {
+	type config struct {
+		App   cBasicS1 `gcfg:",strict"`
+		Extra cBasicS1 `gcfg:",lenient"`
+		Other cBasicS1
+	}
+	for _, tt := range []struct {
+		gcfg  string
+		fatal bool
+		warns int
+	}{
+		{"[app]\nname=x", false, 0},
+		{"[app]\nnmae=x", true, 0},
+		{"[extra]\nfoo=x\nbar=y", false, 0},
+		{"[other]\nfoo=x", false, 1},
+		{"[extra]\nfoo=x\n[other]\nfoo=x\n[app]\nfoo=x", true, 1},
+	} {
+		handled := 0
+		err := ReadWithOptions(&config{}, strings.NewReader(tt.gcfg),
+			WarningHandler(func(err error, sev Severity) Severity {
+				handled++
+				return sev
+			}))
+		if fatal := FatalOnly(err) != nil; fatal != tt.fatal {
+			t.Errorf("%q: got error %v, wanted fatal %v", tt.gcfg, err, tt.fatal)
+		}
+		if fatal := FatalOnly(err); tt.fatal && !errors.Is(fatal, ErrUnknownVariable) {
+			t.Errorf("%q: got error %v, wanted %v", tt.gcfg, fatal, ErrUnknownVariable)
+		}
+		if handled != tt.warns {
+			t.Errorf("%q: got %d warnings handled, wanted %d", tt.gcfg,
+				handled, tt.warns)
+		}
+	}
+}
+
+func TestReadWithOptionsUnknownData(t *testing.T) {
+	type config struct {
+		App   cBasicS1 `gcfg:",strict"`
+		Extra cBasicS1 `gcfg:",lenient"`
+		Other cBasicS1
+	}
+	for _, tt := range []struct {
+		mode  UnknownDataMode
+		gcfg  string
+		err   bool
+		fatal bool
+	}{
+		{UnknownWarning, "[other]\nfoo=x", true, false},
+		{UnknownLenient, "[other]\nfoo=x", false, false},
+		{UnknownLenient, "[unknown]\nfoo=x\n[other \"sub\"]", false, false},
+		{UnknownLenient, "[app]\nfoo=x", true, true},
+		{UnknownStrict, "[other]\nfoo=x", true, true},
+		{UnknownStrict, "[unknown]", true, true},
+		{UnknownStrict, "[extra]\nfoo=x", false, false},
+	} {
+		err := ReadStringWithOptions(&config{}, tt.gcfg, UnknownData(tt.mode))
+		if (err != nil) != tt.err || (FatalOnly(err) != nil) != tt.fatal {
+			t.Errorf("%d %q: got error %v, wanted error %v, fatal %v",
+				tt.mode, tt.gcfg, err, tt.err, tt.fatal)
+		}
+	}
+}
+
+type storage interface{ location() string }
+
+type s3Storage struct{ bucket string }
+
+func (s *s3Storage) location() string { return "s3:" + s.bucket }
+func (s *s3Storage) UnmarshalText(b []byte) error {
+	s.bucket = string(b)
+	return nil
+}
+
+type localStorage string
+
+func (s localStorage) location() string { return "local:" + string(s) }
+
+func init() {
+	RegisterKind((*storage)(nil), "s3", func() interface{} { return &s3Storage{} })
+	RegisterKind((*storage)(nil), "local", func() interface{} {
+		return new(localStorage)
+	})
+}
+
+func TestReadStringIntoKind(t *testing.T) {
+	type config struct {
+		Section struct {
+			Type    string
+			Backend storage   `gcfg:",kind=type"`
+			Mirrors []storage `gcfg:",kind=type"`
+		}
+	}
+	for _, tt := range []struct {
+		gcfg    string
+		backend string
+		mirrors []string
+		err     string
+	}{
+		{"[section]\nbackend=/data", "local:/data", nil, ""},
+		{"[section]\ntype=s3\nbackend=bucket\nmirrors=a\ntype=local\nmirrors=b",
+			"s3:bucket", []string{"s3:a", "local:b"}, ""},
+		{"[section]\ntype=ftp\nbackend=x", "", nil, `3:1: unknown kind "ftp" ` +
+			`of gcfg.storage (registered: "local", "s3") at section "section", ` +
+			`variable "backend", value "x"`},
+	} {
+		cfg := &config{}
+		cfg.Section.Type = "local"
+		err := ReadStringInto(cfg, tt.gcfg)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: got error %v, wanted %q", tt.gcfg, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: got error %v", tt.gcfg, err)
+			continue
+		}
+		var mirrors []string
+		for _, m := range cfg.Section.Mirrors {
+			mirrors = append(mirrors, m.location())
+		}
+		if b, err := Marshal(cfg); tt.mirrors == nil && (err != nil ||
+			!strings.Contains(string(b), "backend = /data")) {
+			t.Errorf("%q: got marshaled\n%s, error %v", tt.gcfg, b, err)
+		}
+		if cfg.Section.Backend.location() != tt.backend ||
+			!reflect.DeepEqual(mirrors, tt.mirrors) {
+			t.Errorf("%q: got %v %v, wanted %v %v", tt.gcfg,
+				cfg.Section.Backend.location(), mirrors, tt.backend,
+				tt.mirrors)
+		}
+	}
+}
+
+func TestReadStringIntoStrictTypes(t *testing.T) {
+	type config struct {
+		Section struct {
+			Name    string
+			Port    int
+			Timeout time.Duration
+			Ratio   float64
+			Labels  map[string]float64 `gcfg:",dotted"`
+		}
+	}
+	for _, tt := range []struct {
+		src string
+		ok  bool
+	}{
+		{"name=x\nport=1\ntimeout=1s", true},
+		{"ratio=0.5", false},
+		{"labels.a=0.5", false},
+	} {
+		if err := ReadStringInto(&config{}, "[section]\n"+tt.src); err != nil {
+			t.Errorf("%q: got error %v without StrictTypes", tt.src, err)
+		}
+		err := ReadStringWithOptions(&config{}, "[section]\n"+tt.src,
+			StrictTypes())
+		if tt.ok && err != nil {
+			t.Errorf("%q: got error %v", tt.src, err)
+		} else if !tt.ok && (!errors.Is(err, ErrUnsupportedType) ||
+			!strings.Contains(err.Error(), "StrictTypes")) {
+			t.Errorf("%q: got error %v, wanted ErrUnsupportedType", tt.src, err)
+		}
+	}
+}
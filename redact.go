@@ -0,0 +1,86 @@
+package gcfg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A RedactFunc reports whether the values of the variable name in section are
+// to be redacted; see Redact. The names are in lower case, and name includes
+// the key for dotted and indexed variables (see the package documentation);
+// e.g. "token.github".
+type RedactFunc func(section, name string) bool
+
+// redactFuncs holds the RedactFuncs registered using RegisterRedactFunc.
+var redactFuncs = struct {
+	sync.RWMutex
+	fs []RedactFunc
+}{}
+
+// redacted replaces the values redacted by an Encoder.
+const redacted = "<redacted>"
+
+// RegisterRedactFunc adds f to the redaction policy of the program, which
+// applies to all configs encoded using the Redact option (or Dump), whether
+// or not their types are defined by the caller; this makes it possible to
+// enforce centrally that some variables (e.g. those named "password" or
+// "token") never appear in logs. A variable is redacted if any of the
+// registered RedactFuncs returns true for it.
+//
+// RegisterRedactFunc is typically called from an init function; it panics if
+// f is nil.
+func RegisterRedactFunc(f RedactFunc) {
+	if f == nil {
+		panic(fmt.Errorf("nil RedactFunc"))
+	}
+	redactFuncs.Lock()
+	defer redactFuncs.Unlock()
+	redactFuncs.fs = append(redactFuncs.fs, f)
+}
+
+// Redact returns an EncoderOption that makes the Encoder write "<redacted>"
+// instead of the values of the variables with the ",secret" struct tag
+// option, of those for which a RedactFunc registered using
+// RegisterRedactFunc returns true, and, if f is not nil, of those for which f
+// returns true. The output is meant for display or logging; it isn't read
+// back to the same config.
+func Redact(f RedactFunc) EncoderOption {
+	return func(e *Encoder) {
+		e.redact, e.redactFunc = true, f
+	}
+}
+
+// Dump returns the encoding of config for display or logging, as returned by
+// Marshal with the Redact(nil) option. If config can't be encoded, the error
+// message is returned instead.
+func Dump(config interface{}) string {
+	b, err := Marshal(config, Redact(nil))
+	if err != nil {
+		return "gcfg: dump: " + err.Error()
+	}
+	return string(b)
+}
+
+// redacts reports whether the Encoder redacts the value of the variable at l,
+// with the tag t.
+func (e *Encoder) redacts(l loc, t tag) bool {
+	if !e.redact {
+		return false
+	}
+	if t.secret {
+		return true
+	}
+	sect, name := strings.ToLower(l.section), strings.ToLower(*l.variable)
+	if e.redactFunc != nil && e.redactFunc(sect, name) {
+		return true
+	}
+	redactFuncs.RLock()
+	defer redactFuncs.RUnlock()
+	for _, f := range redactFuncs.fs {
+		if f(sect, name) {
+			return true
+		}
+	}
+	return false
+}
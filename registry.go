@@ -0,0 +1,120 @@
+package gcfg
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// A Registry dispatches the sections read from configuration data to the
+// components of a program that registered them, so that e.g. adding a plugin
+// doesn't require editing a central config struct. The zero value is an empty
+// registry ready to use.
+//
+// Each component registers the name of its section together with a prototype
+// of the struct holding the section variables and a callback; the prototype
+// also provides the default values. After a successful read, the callback is
+// called with a pointer to a copy of the prototype with the values set, once
+// for the section (with sub == "") and once for each of its subsections
+// present in the data. Sections that are not registered are reported as
+// extra data (see FatalOnly).
+type Registry struct {
+	sections []*registration
+}
+
+type registration struct {
+	name  string
+	proto reflect.Value // struct value
+	fn    func(sub string, v interface{}) error
+}
+
+// Register registers section with prototype proto, which must be a pointer to
+// a struct, and callback fn. It panics if proto is not a pointer to a struct
+// or if section is already registered.
+func (r *Registry) Register(section string, proto interface{},
+	fn func(sub string, v interface{}) error) {
+	//
+	v := reflect.ValueOf(proto)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("prototype must be a pointer to a struct: "+
+			"section %q", section))
+	}
+	for _, s := range r.sections {
+		if s.name == section {
+			panic(fmt.Errorf("section already registered: section %q",
+				section))
+		}
+	}
+	r.sections = append(r.sections, &registration{section, v.Elem(), fn})
+}
+
+// Read reads gcfg formatted data from reader, with the behavior modified by
+// opts as for ReadWithOptions, and calls the callbacks of the registered
+// sections read. Callbacks are called in the order the sections were
+// registered, and for subsections in the order of their names.
+//
+// If reading fails, no callbacks are called. If a callback returns an error,
+// the remaining callbacks are not called, and the error is returned.
+// Otherwise, the (non-fatal) result of reading is returned.
+func (r *Registry) Read(reader io.Reader, opts ...Option) error {
+	src, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return r.read("", src, opts)
+}
+
+// ReadFile is like Read, but reads from the file filename; like ReadFileInto,
+// it skips a single leading UTF8 BOM sequence if it exists.
+func (r *Registry) ReadFile(filename string, opts ...Option) error {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return r.read(filename, skipLeadingUtf8Bom(src), opts)
+}
+
+func (r *Registry) read(filename string, src []byte, opts []Option) error {
+	// The config struct is built with a map field for each section (so
+	// that it can be read both with and without subsections), and a
+	// default field holding the prototype.
+	fields := make([]reflect.StructField, 0, 2*len(r.sections))
+	for i, s := range r.sections {
+		fields = append(fields, reflect.StructField{
+			Name: "S" + strconv.Itoa(i),
+			Type: reflect.MapOf(reflect.TypeOf(""),
+				reflect.PtrTo(s.proto.Type())),
+			Tag: reflect.StructTag("gcfg:" + strconv.Quote(s.name)),
+		}, reflect.StructField{
+			Name: "D" + strconv.Itoa(i),
+			Type: s.proto.Type(),
+			Tag:  reflect.StructTag("gcfg:" + strconv.Quote("default-"+s.name)),
+		})
+	}
+	vCfg := reflect.New(reflect.StructOf(fields))
+	for i, s := range r.sections {
+		vCfg.Elem().Field(2*i + 1).Set(s.proto)
+	}
+	err := readInto(vCfg.Interface(), filename, src, newOptions(opts))
+	if FatalOnly(err) != nil {
+		return err
+	}
+	for i, s := range r.sections {
+		vSect := vCfg.Elem().Field(2 * i)
+		subs := make([]string, 0, vSect.Len())
+		for _, k := range vSect.MapKeys() {
+			subs = append(subs, k.String())
+		}
+		sort.Strings(subs)
+		for _, sub := range subs {
+			v := vSect.MapIndex(reflect.ValueOf(sub)).Interface()
+			if err := s.fn(sub, v); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package gcfg
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/warnings.v0"
+)
+
+type regPlugin struct {
+	Name  string
+	Level int
+}
+
+func TestRegistryRead(t *testing.T) {
+	var r Registry
+	var got []string
+	r.Register("plugin", &regPlugin{Level: 1}, func(sub string, v interface{}) error {
+		p := v.(*regPlugin)
+		got = append(got, sub+":"+p.Name+":"+strconv.Itoa(p.Level))
+		return nil
+	})
+	r.Register("other", &struct{ Value string }{}, func(sub string, v interface{}) error {
+		got = append(got, "other:"+v.(*struct{ Value string }).Value)
+		return nil
+	})
+	src := "[plugin \"b\"]\nname=B\nlevel=2\n[plugin \"a\"]\nname=A\n" +
+		"[plugin]\nname=base\n[other]\nvalue=x\n"
+	if err := r.Read(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{":base:1", "a:A:1", "b:B:2", "other:x"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %q, wanted %q", got, exp)
+	}
+
+	got = nil
+	err := r.Read(strings.NewReader("[plugin]\nname=x\n[unknown]\n"))
+	if FatalOnly(err) != nil || !errors.Is(warnings.WarningsOnly(err)[0], ErrUnknownSection) {
+		t.Errorf("got error %v, wanted %v", err, ErrUnknownSection)
+	}
+	if len(got) != 1 {
+		t.Errorf("got %q, wanted callback called for known section", got)
+	}
+
+	got = nil
+	if err := r.Read(strings.NewReader("[plugin]\nlevel=x\n")); err == nil || got != nil {
+		t.Errorf("got error %v, calls %q; wanted error and no calls", err, got)
+	}
+
+	errCallback := errors.New("callback error")
+	r.Register("failing", &regPlugin{}, func(string, interface{}) error {
+		return errCallback
+	})
+	if err := r.Read(strings.NewReader("[failing]\n")); err != errCallback {
+		t.Errorf("got error %v, wanted %v", err, errCallback)
+	}
+}
+
+func TestRegistryRegisterPanics(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		proto interface{}
+	}{
+		{"plugin", regPlugin{}},
+		{"dup", &regPlugin{}},
+	} {
+		var r Registry
+		r.Register("dup", &regPlugin{}, nil)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: got no panic", tt.name)
+				}
+			}()
+			r.Register(tt.name, tt.proto, nil)
+		}()
+	}
+}
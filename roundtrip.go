@@ -0,0 +1,125 @@
+package gcfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// RoundTrip marshals config (a struct or a pointer to a struct) using opts,
+// reads the result back into a new value of the same type, and compares it
+// with config. It returns an error describing the first difference found, or
+// the error from marshaling or reading back, if any.
+//
+// RoundTrip is meant to be used in tests, to assert that the custom types and
+// struct tag options used in a config are fully supported by both Marshal and
+// the Read*Into functions. Note that values that are written but read back
+// differently are reported; e.g. omitted (see the ",omitempty" struct tag
+// option) subsection variables that take a non-zero default value when read.
+// Nil and empty slices and maps are considered equal.
+func RoundTrip(config interface{}, opts ...EncoderOption) error {
+	b, err := Marshal(config, opts...)
+	if err != nil {
+		return fmt.Errorf("gcfg: round trip: failed to marshal: %w", err)
+	}
+	vCfg := reflect.Indirect(reflect.ValueOf(config))
+	res := reflect.New(vCfg.Type())
+	if err := ReadBytesInto(res.Interface(), b); err != nil {
+		return fmt.Errorf("gcfg: round trip: failed to read back: %w\n%s",
+			err, b)
+	}
+	if l, got, exp, ok := configDiff(vCfg, res.Elem()); !ok {
+		return fmt.Errorf("gcfg: round trip: got %s, wanted %s at %s\n%s",
+			got, exp, l, b)
+	}
+	return nil
+}
+
+// configDiff compares the configs exp and got, and returns the location of
+// the first difference with the differing values formatted, or ok if equal.
+func configDiff(exp, got reflect.Value) (l loc, gotS, expS string, ok bool) {
+	for i := 0; i < exp.NumField(); i++ {
+		f := exp.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		sect, st := fieldName(f)
+		vExp, vGot := exp.Field(i), got.Field(i)
+		if st.any {
+			if (vExp.Len() > 0 || vGot.Len() > 0) &&
+				!reflect.DeepEqual(vExp.Interface(), vGot.Interface()) {
+				return loc{section: sect}, formatDiffValue(vGot),
+					formatDiffValue(vExp), false
+			}
+			continue
+		}
+		if vExp.Kind() != reflect.Map {
+			l := loc{section: sect}
+			if l, gotS, expS, ok = sectionDiff(l, vExp, vGot); !ok {
+				return l, gotS, expS, false
+			}
+			continue
+		}
+		subs := map[string]bool{}
+		for _, vs := range []reflect.Value{vExp, vGot} {
+			for _, k := range vs.MapKeys() {
+				if !vs.MapIndex(k).IsNil() {
+					subs[k.String()] = true
+				}
+			}
+		}
+		keys := make([]string, 0, len(subs))
+		for k := range subs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			k := k
+			l := loc{section: sect, subsection: &k}
+			pe := vExp.MapIndex(reflect.ValueOf(k))
+			pg := vGot.MapIndex(reflect.ValueOf(k))
+			switch {
+			case !pe.IsValid() || pe.IsNil():
+				return l, "subsection", "no subsection", false
+			case !pg.IsValid() || pg.IsNil():
+				return l, "no subsection", "subsection", false
+			}
+			if l, gotS, expS, ok = sectionDiff(l, pe.Elem(), pg.Elem()); !ok {
+				return l, gotS, expS, false
+			}
+		}
+	}
+	return loc{}, "", "", true
+}
+
+// sectionDiff is like configDiff, for the sections exp and got at l.
+func sectionDiff(l loc, exp, got reflect.Value) (loc, string, string, bool) {
+	for i := 0; i < exp.NumField(); i++ {
+		f := exp.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		name, _ := fieldName(f)
+		vExp, vGot := exp.Field(i), got.Field(i)
+		switch vExp.Kind() {
+		case reflect.Slice, reflect.Map:
+			if vExp.Len() == 0 && vGot.Len() == 0 {
+				continue
+			}
+		}
+		if !reflect.DeepEqual(vExp.Interface(), vGot.Interface()) {
+			l.variable = &name
+			return l, formatDiffValue(vGot), formatDiffValue(vExp), false
+		}
+	}
+	return l, "", "", true
+}
+
+// formatDiffValue formats v for reporting a difference, dereferencing
+// pointers.
+func formatDiffValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		return "&" + formatDiffValue(v.Elem())
+	}
+	return fmt.Sprintf("%#v", v.Interface())
+}
@@ -0,0 +1,47 @@
+package gcfg
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, cfg := range []interface{}{
+		&cBasic{Section: cBasicS1{Name: "value", Int: 1}},
+		cBasic{},
+		&cSubs{map[string]*cSubsS1{"": {"z"}, "a": {`"x"`}}},
+		&struct{ M1 cMultiS1 }{cMultiS1{[]string{}}},
+		&cOmit{cOmitS1{"n", 1, []string{"a"}, newString(""), big.NewInt(0),
+			new(int), true, 0}},
+	} {
+		if err := RoundTrip(cfg); err != nil {
+			t.Errorf("%+v: got error %v", cfg, err)
+		}
+	}
+}
+
+type cRoundTripDefault struct {
+	Default_Sub cSubsS1
+	Sub         map[string]*cRoundTripS1
+}
+
+type cRoundTripS1 struct {
+	Name string `gcfg:",omitempty"`
+}
+
+func TestRoundTripErrors(t *testing.T) {
+	for _, tt := range []struct {
+		cfg interface{}
+		exp string
+	}{
+		{&cSubs{map[string]*cSubsS1{"a\nb": {"x"}}}, "failed to marshal"},
+		{&cRoundTripDefault{cSubsS1{"dflt"}, map[string]*cRoundTripS1{"a": {}}},
+			`got "dflt", wanted "" at section "sub", subsection "a", variable "name"`},
+	} {
+		err := RoundTrip(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.exp) {
+			t.Errorf("%+v: got error %v, wanted %q", tt.cfg, err, tt.exp)
+		}
+	}
+}
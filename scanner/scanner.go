@@ -14,6 +14,7 @@ package scanner
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -47,6 +48,9 @@ type Scanner struct {
 	rdOffset   int  // reading offset (position after current character)
 	lineOffset int  // current line offset
 	nextVal    bool // next token is expected to be a value
+	nextKey    bool // next token may be a key (after a period or an index bracket)
+	identEnd   int  // offset after the last identifier, or -1
+	inVal      bool // within a value after its first segment (ScanSegments)
 
 	// public state - ok to modify
 	ErrorCount int // number of errors encountered
@@ -92,6 +96,9 @@ type Mode uint
 
 const (
 	ScanComments Mode = 1 << iota // return comments as COMMENT tokens
+	ScanSegments                  // return value segments as separate STRING tokens
+	ScanContinuations             // continue values on more indented lines
+	ScanLiteralValues             // return values verbatim, without quoting
 )
 
 // Init prepares the scanner s to tokenize the text src by setting the
@@ -104,7 +111,19 @@ const (
 // Calls to Scan will invoke the error handler err if they encounter a
 // syntax error and err is not nil. Also, for each error encountered,
 // the Scanner field ErrorCount is incremented by one. The mode parameter
-// determines how comments are handled.
+// determines how comments and values are handled.
+//
+// In ScanContinuations mode, a value continues on each following line that
+// is indented more than the line of the variable (as in Python's configparser),
+// unless the line is blank or a comment; the value literal joins the lines
+// with a quoted new line ("\n"), with the indentation removed. Continuation
+// lines are not recognized in ScanSegments mode.
+//
+// In ScanLiteralValues mode, a value literal is the text up to a comment or
+// the end of the line, without trailing whitespace, and double quotes and
+// backslashes have no special meaning in it; continuation lines are joined
+// with a new line. Values are scanned in segments in ScanSegments mode
+// regardless.
 //
 // Note that Init may call err if there is an error in the first character
 // of the file.
@@ -126,6 +145,9 @@ func (s *Scanner) Init(file *token.File, src []byte, err ErrorHandler, mode Mode
 	s.lineOffset = 0
 	s.ErrorCount = 0
 	s.nextVal = false
+	s.nextKey = false
+	s.identEnd = -1
+	s.inVal = false
 
 	s.next()
 }
@@ -257,6 +279,129 @@ loop:
 	return string(lit)
 }
 
+// scanValLiteral scans a value in ScanLiteralValues mode; that is, the text up
+// to a comment or the end of the line, excluding trailing whitespace.
+//
+func (s *Scanner) scanValLiteral() string {
+	offs := s.offset
+
+	end := offs
+	for s.ch >= 0 && s.ch != '\n' && s.ch != ';' && s.ch != '#' {
+		ch := s.ch
+		s.next()
+		if !isWhiteSpace(ch) {
+			end = s.offset
+		}
+	}
+
+	return string(s.src[offs:end])
+}
+
+// scanValSegment scans a single segment of a value in ScanSegments mode;
+// that is a quoted string (including the quotes), or unquoted text up to the
+// next quoted string (including any whitespace before it) or the end of the
+// value (excluding trailing whitespace). It also reports whether the value
+// continues with another segment.
+//
+func (s *Scanner) scanValSegment() (string, bool) {
+	offs := s.offset
+
+	hasCR := false
+	end := offs
+	if s.ch == '"' {
+		s.next()
+		for s.ch != '"' {
+			if s.ch < 0 || s.ch == '\n' {
+				s.error(offs, "string not terminated")
+				return string(s.src[offs:s.offset]), false
+			}
+			ch := s.ch
+			s.next()
+			switch ch {
+			case '\\':
+				s.scanEscape(true)
+			case '\r':
+				hasCR = true
+			}
+		}
+		s.next()
+		end = s.offset
+	} else {
+		for s.ch >= 0 && s.ch != '\n' && s.ch != ';' && s.ch != '#' && s.ch != '"' {
+			ch := s.ch
+			s.next()
+			switch ch {
+			case '\\':
+				if s.ch == '\r' {
+					hasCR = true
+					s.next()
+				}
+				if s.ch != '\n' && s.ch != '"' {
+					s.error(offs, "unquoted '\\' must be followed by new line or double quote")
+					return string(s.src[offs:s.offset]), false
+				}
+				s.next()
+			case '\r':
+				hasCR = true
+			}
+			if !isWhiteSpace(ch) {
+				end = s.offset
+			}
+		}
+		if s.ch == '"' {
+			end = s.offset
+		}
+	}
+
+	lit := s.src[offs:end]
+	if hasCR {
+		lit = stripCR(lit)
+	}
+
+	// the value continues unless only whitespace remains before its end
+	i := s.offset
+	for i < len(s.src) && isWhiteSpace(rune(s.src[i])) {
+		i++
+	}
+	cont := i < len(s.src) && s.src[i] != '\n' && s.src[i] != ';' && s.src[i] != '#'
+
+	return string(lit), cont
+}
+
+// indent returns the number of whitespace characters at the start of the line
+// starting at offs.
+//
+func (s *Scanner) indent(offs int) int {
+	i := offs
+	for i < len(s.src) && isWhiteSpace(rune(s.src[i])) {
+		i++
+	}
+	return i - offs
+}
+
+// continues reports whether the value just scanned continues on the next
+// line in ScanContinuations mode; that is, if the next line is indented by
+// more than indent characters and is neither blank nor a comment. If so, it
+// advances to the first non-whitespace character of the next line.
+//
+func (s *Scanner) continues(indent int) bool {
+	if s.ch != '\n' {
+		return false
+	}
+	n := s.indent(s.rdOffset)
+	i := s.rdOffset + n
+	if n <= indent || i >= len(s.src) {
+		return false
+	}
+	switch s.src[i] {
+	case '\n', ';', '#':
+		return false
+	}
+	s.next()
+	s.skipWhitespace()
+	return true
+}
+
 func isWhiteSpace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\r'
 }
@@ -290,22 +435,56 @@ func (s *Scanner) skipWhitespace() {
 // set with Init. Token positions are relative to that file
 // and thus relative to the file set.
 //
+// Scanning takes time linear in the length of the source, whatever its
+// contents: each character is examined at most twice, as lookahead is
+// limited to the whitespace following a value segment (ScanSegments), and to
+// the indentation of the lines in a value (ScanContinuations). Escape
+// sequences are a single character after a backslash, and literals are
+// never rescanned, so no input makes the scanner backtrack.
+//
 func (s *Scanner) Scan() (pos token.Pos, tok token.Token, lit string) {
 scanAgain:
-	s.skipWhitespace()
+	if !s.inVal {
+		s.skipWhitespace()
+	}
 
 	// current token start
 	pos = s.file.Pos(s.offset)
 
 	// determine token value
+	nextKey := s.nextKey
+	s.nextKey = false
 	switch ch := s.ch; {
+	case s.nextVal && s.mode&ScanSegments != 0:
+		lit, s.nextVal = s.scanValSegment()
+		s.inVal = s.nextVal
+		tok = token.STRING
 	case s.nextVal:
-		lit = s.scanValString()
+		indent := s.indent(s.lineOffset)
+		scanVal, sep := s.scanValString, `"\n"`
+		if s.mode&ScanLiteralValues != 0 {
+			scanVal, sep = s.scanValLiteral, "\n"
+		}
+		lit = scanVal()
+		// concatenate in a builder to keep many continuation lines linear
+		var b strings.Builder
+		for s.mode&ScanContinuations != 0 && s.continues(indent) {
+			if b.Len() == 0 {
+				b.WriteString(lit)
+			}
+			b.WriteString(sep)
+			b.WriteString(scanVal())
+		}
+		if b.Len() > 0 {
+			lit = b.String()
+		}
 		tok = token.STRING
 		s.nextVal = false
-	case isLetter(ch):
+	case isLetter(ch), nextKey && isDigit(ch):
+		// keys following a period or bracket may start with a digit
 		lit = s.scanIdentifier()
 		tok = token.IDENT
+		s.identEnd = s.offset
 	default:
 		s.next() // always make progress
 		switch ch {
@@ -318,6 +497,8 @@ scanAgain:
 			lit = s.scanString()
 		case '[':
 			tok = token.LBRACK
+			// a bracket directly following an identifier starts an index
+			s.nextKey = s.file.Offset(pos) == s.identEnd
 		case ']':
 			tok = token.RBRACK
 		case ';', '#':
@@ -328,9 +509,22 @@ scanAgain:
 				goto scanAgain
 			}
 			tok = token.COMMENT
+		case '.':
+			tok = token.PERIOD
+			s.nextKey = true
 		case '=':
 			tok = token.ASSIGN
 			s.nextVal = true
+		case '+':
+			if s.ch != '=' {
+				s.error(s.file.Offset(pos), fmt.Sprintf("illegal character %#U", ch))
+				tok = token.ILLEGAL
+				lit = string(ch)
+				break
+			}
+			s.next()
+			tok = token.ADD
+			s.nextVal = true
 		default:
 			s.error(s.file.Offset(pos), fmt.Sprintf("illegal character %#U", ch))
 			tok = token.ILLEGAL
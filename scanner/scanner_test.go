@@ -5,6 +5,7 @@
 package scanner
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -47,8 +48,10 @@ var tokens = [...]elt{
 
 	// Operators and delimiters
 	{token.ASSIGN, "=", operator, "", "value"},
+	{token.ADD, "+=", operator, "", "value"},
 	{token.LBRACK, "[", operator, "", ""},
 	{token.RBRACK, "]", operator, "", ""},
+	{token.PERIOD, ".", operator, "", ""},
 	{token.EOL, "\n", operator, "", ""},
 
 	// Identifiers
@@ -58,6 +61,8 @@ var tokens = [...]elt{
 	{token.IDENT, "bar９８７６", literal, "", ""},
 	{token.IDENT, "foo-bar", literal, "", ""},
 	{token.IDENT, "foo", literal, ";\n", ""},
+	{token.IDENT, "0foo", literal, ".", ""},
+	{token.IDENT, "123", literal, ".", ""},
 	// String literals (subsection names)
 	{token.STRING, `"foobar"`, literal, "", ""},
 	{token.STRING, `"\""`, literal, "", ""},
@@ -160,13 +165,16 @@ func TestScan(t *testing.T) {
 			epos.Line = src_linecount
 			epos.Column = 2
 		}
-		if e.pre != "" && strings.ContainsRune("=;#", rune(e.pre[0])) {
+		if e.pre != "" && strings.ContainsRune("=.;#", rune(e.pre[0])) {
 			epos.Column = 1
 			checkPos(t, lit, pos, epos)
 			var etok token.Token
-			if e.pre[0] == '=' {
+			switch e.pre[0] {
+			case '=':
 				etok = token.ASSIGN
-			} else {
+			case '.':
+				etok = token.PERIOD
+			default:
 				etok = token.COMMENT
 			}
 			if tok != etok {
@@ -376,6 +384,7 @@ var errors = []struct {
 	{"\a", token.ILLEGAL, 0, "illegal character U+0007"},
 	{"/", token.ILLEGAL, 0, "illegal character U+002F '/'"},
 	{"_", token.ILLEGAL, 0, "illegal character U+005F '_'"},
+	{"+", token.ILLEGAL, 0, "illegal character U+002B '+'"},
 	{`…`, token.ILLEGAL, 0, "illegal character U+2026 '…'"},
 	{`""`, token.STRING, 0, ""},
 	{`"`, token.STRING, 0, "string not terminated"},
@@ -400,6 +409,121 @@ func TestScanErrors(t *testing.T) {
 	}
 }
 
+func TestScanKeys(t *testing.T) {
+	src := "foo.0 = a\nfoo[1]\nfoo [2]\n[0]"
+	exp := []struct {
+		tok token.Token
+		lit string
+	}{
+		{token.IDENT, "foo"}, {token.PERIOD, ""}, {token.IDENT, "0"},
+		{token.ASSIGN, ""}, {token.STRING, "a"}, {token.EOL, ""},
+		{token.IDENT, "foo"}, {token.LBRACK, ""}, {token.IDENT, "1"},
+		{token.RBRACK, ""}, {token.EOL, ""},
+		{token.IDENT, "foo"}, {token.LBRACK, ""}, {token.ILLEGAL, "2"},
+		{token.RBRACK, ""}, {token.EOL, ""},
+		{token.LBRACK, ""}, {token.ILLEGAL, "0"}, {token.RBRACK, ""},
+		{token.EOF, ""},
+	}
+	var s Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, 0)
+	for i, e := range exp {
+		_, tok, lit := s.Scan()
+		if tok != e.tok || lit != e.lit {
+			t.Errorf("%d: got %s %q, expected %s %q", i, tok, lit, e.tok, e.lit)
+		}
+	}
+}
+
+func TestScanSegments(t *testing.T) {
+	src := "a = x \"y z\"  w \nb=\"p\"\"q\" ; c\nc = \"\\\"\" u\\\n v\r\nd=\ne = \"a\" \n"
+	exp := []struct {
+		tok token.Token
+		lit string
+		off int
+	}{
+		{token.IDENT, "a", 0}, {token.ASSIGN, "", 2},
+		{token.STRING, `x `, 4}, {token.STRING, `"y z"`, 6},
+		{token.STRING, `  w`, 11}, {token.EOL, "", 15},
+		{token.IDENT, "b", 16}, {token.ASSIGN, "", 17},
+		{token.STRING, `"p"`, 18}, {token.STRING, `"q"`, 21},
+		{token.COMMENT, "; c", 25}, {token.EOL, "", 28},
+		{token.IDENT, "c", 29}, {token.ASSIGN, "", 31},
+		{token.STRING, `"\""`, 33}, {token.STRING, " u\\\n v", 37},
+		{token.EOL, "", 44},
+		{token.IDENT, "d", 45}, {token.ASSIGN, "", 46}, {token.STRING, "", 47},
+		{token.EOL, "", 47},
+		{token.IDENT, "e", 48}, {token.ASSIGN, "", 50},
+		{token.STRING, `"a"`, 52}, {token.EOL, "", 56},
+		{token.EOF, "", 57},
+	}
+	var s Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, ScanComments|ScanSegments)
+	for i, e := range exp {
+		pos, tok, lit := s.Scan()
+		off := fset.Position(pos).Offset
+		if tok != e.tok || lit != e.lit || off != e.off {
+			t.Errorf("%d: got %s %q at %d, expected %s %q at %d", i, tok, lit, off, e.tok, e.lit, e.off)
+		}
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("got %d errors, expected none", s.ErrorCount)
+	}
+}
+
+func TestScanContinuations(t *testing.T) {
+	src := "[s]\n\ta = x\n\t  y \"z\"\n\t\tw\n\tb = c\n\t; comment\n\t\tnot\n\td =\n\t\te\n"
+	exp := []struct {
+		tok token.Token
+		lit string
+	}{
+		{token.LBRACK, ""}, {token.IDENT, "s"}, {token.RBRACK, ""}, {token.EOL, ""},
+		{token.IDENT, "a"}, {token.ASSIGN, ""}, {token.STRING, `x"\n"y "z""\n"w`},
+		{token.EOL, ""},
+		{token.IDENT, "b"}, {token.ASSIGN, ""}, {token.STRING, "c"}, {token.EOL, ""},
+		{token.COMMENT, "; comment"}, {token.EOL, ""},
+		{token.IDENT, "not"}, {token.EOL, ""},
+		{token.IDENT, "d"}, {token.ASSIGN, ""}, {token.STRING, `"\n"e`}, {token.EOL, ""},
+		{token.EOF, ""},
+	}
+	var s Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, ScanComments|ScanContinuations)
+	for i, e := range exp {
+		_, tok, lit := s.Scan()
+		if tok != e.tok || lit != e.lit {
+			t.Errorf("%d: got %s %q, expected %s %q", i, tok, lit, e.tok, e.lit)
+		}
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("got %d errors, expected none", s.ErrorCount)
+	}
+}
+
+func TestScanLiteralValues(t *testing.T) {
+	src := "[s]\n\ta = x=\"y\\\" \\z ; c\n\t  w\n\tb =\t\r\n"
+	exp := []struct {
+		tok token.Token
+		lit string
+	}{
+		{token.LBRACK, ""}, {token.IDENT, "s"}, {token.RBRACK, ""}, {token.EOL, ""},
+		{token.IDENT, "a"}, {token.ASSIGN, ""}, {token.STRING, `x="y\" \z`},
+		{token.COMMENT, "; c"}, {token.EOL, ""},
+		{token.IDENT, "w"}, {token.EOL, ""},
+		{token.IDENT, "b"}, {token.ASSIGN, ""}, {token.STRING, ""}, {token.EOL, ""},
+		{token.EOF, ""},
+	}
+	var s Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, ScanComments|ScanLiteralValues)
+	for i, e := range exp {
+		_, tok, lit := s.Scan()
+		if tok != e.tok || lit != e.lit {
+			t.Errorf("%d: got %s %q, expected %s %q", i, tok, lit, e.tok, e.lit)
+		}
+	}
+	if s.ErrorCount != 0 {
+		t.Errorf("got %d errors, expected none", s.ErrorCount)
+	}
+}
+
 func BenchmarkScan(b *testing.B) {
 	b.StopTimer()
 	fset := token.NewFileSet()
@@ -416,3 +540,43 @@ func BenchmarkScan(b *testing.B) {
 		}
 	}
 }
+
+// adversarial holds pathological inputs for BenchmarkScanAdversarial, as
+// functions of the number of repetitions of their pathological part.
+var adversarial = []struct {
+	name string
+	mode Mode
+	src  func(n int) string
+}{
+	{"escapes", 0, func(n int) string { return `a="` + strings.Repeat(`\\\"`, n) + `"` }},
+	{"quoted", 0, func(n int) string { return `a="` + strings.Repeat("x", n) + `"` }},
+	{"unterminated", 0, func(n int) string { return strings.Repeat("a=\"x\n", n) }},
+	{"backslashes", 0, func(n int) string { return "a=" + strings.Repeat("x\\\n", n) }},
+	{"concatenated", ScanSegments, func(n int) string { return "a=" + strings.Repeat(`"x" `, n) }},
+	{"whitespace", ScanSegments, func(n int) string { return "a=x" + strings.Repeat(" ", n) + `"x"` }},
+	{"continuations", ScanContinuations, func(n int) string { return "a=x" + strings.Repeat("\n x", n) }},
+}
+
+// BenchmarkScanAdversarial scans pathological inputs of increasing sizes; as
+// scanning is linear, the throughput shouldn't decrease with the size.
+func BenchmarkScanAdversarial(b *testing.B) {
+	for _, a := range adversarial {
+		for _, n := range []int{1e3, 1e4, 1e5} {
+			src := []byte(a.src(n))
+			b.Run(fmt.Sprintf("%s/%d", a.name, n), func(b *testing.B) {
+				b.SetBytes(int64(len(src)))
+				for i := 0; i < b.N; i++ {
+					var s Scanner
+					fset := token.NewFileSet()
+					file := fset.AddFile("", fset.Base(), len(src))
+					s.Init(file, src, nil, a.mode)
+					for {
+						if _, tok, _ := s.Scan(); tok == token.EOF {
+							break
+						}
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gcfg
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldInfo describes a variable of a config struct; see Schema.
+type FieldInfo struct {
+	Section     string  // section name
+	Subsections bool    // whether the section has subsections
+	Variable    string  // variable name; empty for a section without variables
+	Type        string  // Go type of the variable; e.g. "[]string"
+	Multi       bool    // whether the variable is multi-valued
+	Tag         string  // "gcfg" struct tag of the variable
+	Default     *string // default value from the ",default=" tag option; or nil
+	Env         string  // environment variable from the ",env=" tag option
+	Min, Max    *string // bounds from the ",min=" and ",max=" tag options
+	Secret      bool    // whether the value is secret (",secret" tag option)
+	Required    bool    // whether the variable is required (",required" tag option)
+}
+
+// Schema returns a description of the sections and variables accepted by the
+// config struct config (or a pointer to it), in the order of the struct
+// fields, e.g. for a server to describe the configuration it accepts. Only
+// the type of config is used, not its values. Schema panics if config is not
+// a valid config struct, as the Read*Into functions would.
+func Schema(config interface{}) []FieldInfo {
+	tCfg := reflect.TypeOf(config)
+	if tCfg != nil && tCfg.Kind() == reflect.Ptr {
+		tCfg = tCfg.Elem()
+	}
+	if tCfg == nil || tCfg.Kind() != reflect.Struct {
+		panic(fmt.Errorf("config must be a struct or a pointer to a struct"))
+	}
+	var fis []FieldInfo
+	for i := 0; i < tCfg.NumField(); i++ {
+		f := tCfg.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		sect, st := fieldName(f)
+		if st.any { // not a section
+			continue
+		}
+		tSect, subs := f.Type, false
+		if tSect.Kind() == reflect.Map {
+			if tSect.Key().Kind() != reflect.String ||
+				tSect.Elem().Kind() != reflect.Ptr ||
+				tSect.Elem().Elem().Kind() != reflect.Struct {
+				panic(fmt.Errorf("map field for section must have string keys and "+
+					" pointer-to-struct values: section %q", sect))
+			}
+			tSect, subs = tSect.Elem().Elem(), true
+		} else if tSect.Kind() != reflect.Struct {
+			panic(fmt.Errorf("field for section must be a map or a struct: "+
+				"section %q", sect))
+		}
+		n := len(fis)
+		for j := 0; j < tSect.NumField(); j++ {
+			fv := tSect.Field(j)
+			if fv.PkgPath != "" {
+				continue
+			}
+			name, t := fieldName(fv)
+			if t.any { // not a variable
+				continue
+			}
+			fis = append(fis, FieldInfo{Section: sect, Subsections: subs,
+				Variable: name, Type: fv.Type.String(),
+				Multi: isMultiVal(fv.Type), Tag: fv.Tag.Get("gcfg"),
+				Default: t.dflt, Env: t.env, Min: t.min, Max: t.max,
+				Secret: t.secret, Required: t.required})
+		}
+		if len(fis) == n {
+			fis = append(fis, FieldInfo{Section: sect, Subsections: subs})
+		}
+	}
+	return fis
+}
@@ -0,0 +1,38 @@
+package gcfg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	type config struct {
+		Server struct {
+			Port     int      `gcfg:",default=80,min=1,max=65535"`
+			Hosts    []string `gcfg:"host"`
+			Password string   `gcfg:",secret,env=PASSWORD"`
+			internal string
+		}
+		Backend map[string]*struct{ URL string }
+		Empty   struct{}
+	}
+	dflt, min, max := "80", "1", "65535"
+	exp := []FieldInfo{
+		{Section: "server", Variable: "port", Type: "int",
+			Tag: ",default=80,min=1,max=65535", Default: &dflt, Min: &min,
+			Max: &max},
+		{Section: "server", Variable: "host", Type: "[]string", Multi: true,
+			Tag: "host"},
+		{Section: "server", Variable: "password", Type: "string",
+			Tag: ",secret,env=PASSWORD", Env: "PASSWORD", Secret: true},
+		{Section: "backend", Subsections: true, Variable: "url",
+			Type: "string"},
+		{Section: "empty"},
+	}
+	if got := Schema(&config{}); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %+v, wanted %+v", got, exp)
+	}
+	if got := Schema(config{}); !reflect.DeepEqual(got, exp) {
+		t.Errorf("non-pointer: got %+v, wanted %+v", got, exp)
+	}
+}
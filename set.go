@@ -5,34 +5,192 @@ import (
 	"encoding"
 	"encoding/gob"
 	"fmt"
+	"io/fs"
+	"io/ioutil"
 	"math/big"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
+	"gopkg.in/gcfg.v1/token"
 	"gopkg.in/gcfg.v1/types"
-	"gopkg.in/warnings.v0"
 )
 
 type tag struct {
-	ident   string
-	intMode string
+	ident     string
+	intMode   string
+	omitEmpty bool
+	dflt      *string  // declared default value, if any
+	env       string   // environment variable to fall back to, if any
+	fromFile  bool     // value is the name of a file containing the value
+	relTo     string   // what relative paths are resolved against, if anything
+	dotted    bool     // map variable set using dotted names (name.key)
+	any       bool     // map holding the variables not matching other fields
+	indexed   bool     // slice variable set using indexes (name.0 or name[0])
+	minVer    string   // minimum version for types.Version variables, if any
+	port      string   // default port for types.HostPort variables, if any
+	noOverlap bool     // reject overlapping prefixes in types.CIDRList variables
+	min, max  *string  // bounds for duration and integer variables, if any
+	schemes   []string // allowed schemes for url.URL variables, if restricted
+	reqHost   bool     // require a host in url.URL variables
+	secret    bool     // redact the value in error messages
+	implicit  *string  // value set by a blank value, if any
+	strict    bool     // unknown variables in the section are fatal errors
+	lenient   bool     // unknown variables in the section are ignored
+	kind      string   // variable selecting the implementation, if any
+	kindOf    string   // value of the kind variable (set by set, not parsed)
+	fsys      fs.FS    // file system being read (set by set, not parsed)
+	noScan    bool     // don't fall back to fmt.Sscanf (set by set, not parsed)
+
+	transforms []string // names of the transforms to apply to values, in order
+	setter     string   // name of the setter to use, if any
+	required   bool     // the section or variable must be defined
+
+	boolFormat string // name of the format for writing bools
 }
 
+// tags caches parsed struct tags, as struct tags are looked up for each value
+// set; the tags returned by newTag must therefore not be modified (other than
+// by modifying a copy of the non-reference fields).
+var tags sync.Map // map[string]tag
+
 func newTag(ts string) tag {
+	if t, ok := tags.Load(ts); ok {
+		return t.(tag)
+	}
+	t := parseTag(ts)
+	tags.Store(ts, t)
+	return t
+}
+
+func parseTag(ts string) tag {
 	t := tag{}
 	s := strings.Split(ts, ",")
 	t.ident = s[0]
 	for _, tse := range s[1:] {
-		if strings.HasPrefix(tse, "int=") {
+		switch {
+		case strings.HasPrefix(tse, "int="):
 			t.intMode = tse[len("int="):]
+		case tse == "omitempty":
+			t.omitEmpty = true
+		case strings.HasPrefix(tse, "bool="):
+			t.boolFormat = tse[len("bool="):]
+		case strings.HasPrefix(tse, "default="):
+			d := tse[len("default="):]
+			t.dflt = &d
+		case strings.HasPrefix(tse, "env="):
+			t.env = tse[len("env="):]
+		case tse == "fromfile":
+			t.fromFile = true
+		case strings.HasPrefix(tse, "relto="):
+			t.relTo = tse[len("relto="):]
+		case tse == "dotted":
+			t.dotted = true
+		case tse == "indexed":
+			t.indexed = true
+		case tse == "any":
+			t.any = true
+		case strings.HasPrefix(tse, "minver="):
+			t.minVer = tse[len("minver="):]
+		case strings.HasPrefix(tse, "port="):
+			t.port = tse[len("port="):]
+		case tse == "nooverlap":
+			t.noOverlap = true
+		case strings.HasPrefix(tse, "min="):
+			m := tse[len("min="):]
+			t.min = &m
+		case strings.HasPrefix(tse, "max="):
+			m := tse[len("max="):]
+			t.max = &m
+		case strings.HasPrefix(tse, "schemes="):
+			t.schemes = strings.Split(tse[len("schemes="):], "|")
+		case tse == "requirehost":
+			t.reqHost = true
+		case tse == "secret":
+			t.secret = true
+		case strings.HasPrefix(tse, "kind="):
+			t.kind = tse[len("kind="):]
+		case tse == "strict":
+			t.strict = true
+		case tse == "lenient":
+			t.lenient = true
+		case strings.HasPrefix(tse, "transform="):
+			t.transforms = strings.Split(tse[len("transform="):], "|")
+		case tse == "required":
+			t.required = true
+		case strings.HasPrefix(tse, "setter="):
+			t.setter = tse[len("setter="):]
+		case strings.HasPrefix(tse, "implicit="):
+			v := tse[len("implicit="):]
+			t.implicit = &v
 		}
 	}
 	return t
 }
 
-func fieldFold(v reflect.Value, name string) (reflect.Value, tag) {
+// fieldName returns the section or variable name corresponding to struct field
+// f, and the tag of the field.
+func fieldName(f reflect.StructField) (string, tag) {
+	t := newTag(f.Tag.Get("gcfg"))
+	if t.ident != "" {
+		return t.ident, t
+	}
+	n := f.Name
+	if strings.HasPrefix(n, "X") {
+		r1, _ := utf8.DecodeRuneInString(n[1:])
+		if unicode.IsLetter(r1) && !unicode.IsLower(r1) && !unicode.IsUpper(r1) {
+			n = n[1:]
+		}
+	}
+	return strings.ToLower(strings.Replace(n, "_", "-", -1)), t
+}
+
+// A fieldKey identifies the name of a field of a struct type, as looked up by
+// fieldFold.
+type fieldKey struct {
+	t    reflect.Type
+	name string
+}
+
+// A foundField is a field found by fieldFold: its index and tag.
+type foundField struct {
+	index []int
+	tag   tag
+}
+
+// foundFields caches the fields of settable structs found by fieldFold
+// without a fold function, as fields are looked up for each value set. Only
+// found fields are cached, so that names in the data matching no field don't
+// grow the cache.
+var foundFields sync.Map // map[fieldKey]*foundField
+
+// fieldFold returns the field of the struct v for the section or variable
+// name, and its tag; names match if fold maps them to the same string, or if
+// fold is nil, if they are equal under Unicode case folding.
+func fieldFold(v reflect.Value, name string,
+	fold func(string) string) (reflect.Value, tag) {
+	//
+	cache := fold == nil && v.CanSet()
+	if cache {
+		if ff, ok := foundFields.Load(fieldKey{v.Type(), name}); ok {
+			f := ff.(*foundField)
+			return v.FieldByIndex(f.index), f.tag
+		}
+	}
+	equal := strings.EqualFold
+	if fold != nil {
+		equal = func(a, b string) bool { return fold(a) == fold(b) }
+	}
 	var n string
 	r0, _ := utf8.DecodeRuneInString(name)
 	if unicode.IsLetter(r0) && !unicode.IsLower(r0) && !unicode.IsUpper(r0) {
@@ -45,30 +203,40 @@ func fieldFold(v reflect.Value, name string) (reflect.Value, tag) {
 		}
 		f, _ := v.Type().FieldByName(fieldName)
 		t := newTag(f.Tag.Get("gcfg"))
+		if t.any { // only holds variables matching no field
+			return false
+		}
 		if t.ident != "" {
-			return strings.EqualFold(t.ident, name)
+			return equal(t.ident, name)
 		}
-		return strings.EqualFold(n, fieldName)
+		return equal(n, fieldName)
 	})
 	if !ok {
 		return reflect.Value{}, tag{}
 	}
-	return v.FieldByName(f.Name), newTag(f.Tag.Get("gcfg"))
+	t := newTag(f.Tag.Get("gcfg"))
+	if cache {
+		foundFields.Store(fieldKey{v.Type(), name}, &foundField{f.Index, t})
+	}
+	return v.FieldByIndex(f.Index), t
 }
 
 type setter func(destp interface{}, blank bool, val string, t tag) error
 
-var errUnsupportedType = fmt.Errorf("unsupported type")
 var errBlankUnsupported = fmt.Errorf("blank value not supported for type")
 
+var errAppendUnsupported = fmt.Errorf("'+=' not supported for type")
+
+var errMissingKey = fmt.Errorf("missing key or index (name.key)")
+
 var setters = []setter{
-	typeSetter, textUnmarshalerSetter, kindSetter, scanSetter,
+	typeSetter, textUnmarshalerSetter, kindSetter,
 }
 
 func textUnmarshalerSetter(d interface{}, blank bool, val string, t tag) error {
 	dtu, ok := d.(encoding.TextUnmarshaler)
 	if !ok {
-		return errUnsupportedType
+		return ErrUnsupportedType
 	}
 	if blank {
 		return errBlankUnsupported
@@ -142,7 +310,7 @@ func stringSetter(d interface{}, blank bool, val string, t tag) error {
 	}
 	dsp, ok := d.(*string)
 	if !ok {
-		return errUnsupportedType
+		return ErrUnsupportedType
 	}
 	*dsp = val
 	return nil
@@ -165,14 +333,112 @@ var kindSetters = map[reflect.Kind]setter{
 }
 
 var typeSetters = map[reflect.Type]setter{
-	reflect.TypeOf(big.Int{}): intSetter,
+	reflect.TypeOf(big.Int{}):        intSetter,
+	reflect.TypeOf(big.Float{}):      bigFloatSetter,
+	reflect.TypeOf(mail.Address{}):   addressSetter,
+	reflect.TypeOf(types.HostPort{}): hostPortSetter,
+	reflect.TypeOf(time.Duration(0)): durationSetter,
+	reflect.TypeOf(url.URL{}):        urlSetter,
+}
+
+// urlSetter parses URLs using url.Parse, checking the restrictions set using
+// the ",schemes=scheme1|scheme2" and ",requirehost" struct tag options.
+func urlSetter(d interface{}, blank bool, val string, t tag) error {
+	if blank {
+		return errBlankUnsupported
+	}
+	u, err := url.Parse(val)
+	if err != nil {
+		return err
+	}
+	if t.schemes != nil {
+		ok := false
+		for _, s := range t.schemes {
+			ok = ok || strings.EqualFold(u.Scheme, s)
+		}
+		if !ok {
+			return fmt.Errorf("URL scheme %q not allowed: must be one of %s",
+				u.Scheme, strings.Join(t.schemes, ", "))
+		}
+	}
+	if t.reqHost && u.Host == "" {
+		return fmt.Errorf("URL %q has no host", val)
+	}
+	*d.(*url.URL) = *u
+	return nil
+}
+
+// bigFloatSetter parses big.Float values using big.Float.Parse with base 0;
+// e.g. "1.5", "-1e100" or "0x1p-2". If the variable has a precision, it is
+// kept; otherwise, the precision is 64 bits.
+func bigFloatSetter(d interface{}, blank bool, val string, t tag) error {
+	if blank {
+		return errBlankUnsupported
+	}
+	_, _, err := d.(*big.Float).Parse(val, 0)
+	return err
+}
+
+// durationSetter parses durations using time.ParseDuration (e.g. "1m30s"); for
+// compatibility, integers (nanoseconds) are also accepted.
+func durationSetter(d interface{}, blank bool, val string, t tag) error {
+	if blank {
+		return errBlankUnsupported
+	}
+	if err := intSetter(d, blank, val, t); err == nil {
+		return nil
+	}
+	dur, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	*d.(*time.Duration) = dur
+	return nil
+}
+
+func hostPortSetter(d interface{}, blank bool, val string, t tag) error {
+	if blank {
+		return errBlankUnsupported
+	}
+	hp, err := types.ParseHostPort(val, t.port)
+	if err != nil {
+		return err
+	}
+	*d.(*types.HostPort) = hp
+	return nil
+}
+
+func addressSetter(d interface{}, blank bool, val string, t tag) error {
+	if blank {
+		return errBlankUnsupported
+	}
+	a, err := mail.ParseAddress(val)
+	if err != nil {
+		return err
+	}
+	*d.(*mail.Address) = *a
+	return nil
+}
+
+// A listSetter parses val into a slice of values, which are appended to a
+// multi-valued variable.
+type listSetter func(val string) (interface{}, error)
+
+// listSetters are used for the multi-valued variables of the given types
+// instead of parsing each value as a single element.
+var listSetters = map[reflect.Type]listSetter{
+	reflect.TypeOf([]*mail.Address(nil)): addressListSetter,
+}
+
+func addressListSetter(val string) (interface{}, error) {
+	return mail.ParseAddressList(val)
 }
 
 func typeSetter(d interface{}, blank bool, val string, tt tag) error {
 	t := reflect.ValueOf(d).Type().Elem()
 	setter, ok := typeSetters[t]
 	if !ok {
-		return errUnsupportedType
+		return ErrUnsupportedType
 	}
 	return setter(d, blank, val, tt)
 }
@@ -181,50 +447,168 @@ func kindSetter(d interface{}, blank bool, val string, tt tag) error {
 	k := reflect.ValueOf(d).Type().Elem().Kind()
 	setter, ok := kindSetters[k]
 	if !ok {
-		return errUnsupportedType
+		return ErrUnsupportedType
 	}
 	return setter(d, blank, val, tt)
 }
 
+// scannable reports whether values of type t can be parsed by fmt.Sscanf.
+func scannable(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*fmt.Scanner)(nil)).Elem()) {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return scannableKind(t.Kind())
+}
+
+// scannableKind reports whether values of types of kind k can be parsed by
+// fmt.Sscanf; for slices, only byte slices can.
+func scannableKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128, reflect.Slice:
+		return true
+	}
+	return false
+}
+
 func scanSetter(d interface{}, blank bool, val string, tt tag) error {
+	if !scannable(reflect.ValueOf(d).Type().Elem()) {
+		return ErrUnsupportedType
+	}
 	if blank {
 		return errBlankUnsupported
 	}
 	return types.ScanFully(d, val, 'v')
 }
 
-func newValue(c *warnings.Collector, sect string, vCfg reflect.Value,
-	vType reflect.Type) (reflect.Value, error) {
+func newValue(c *collector, sect string, vCfg reflect.Value,
+	vType reflect.Type, l loc) (reflect.Value, error) {
 	//
 	pv := reflect.New(vType)
 	dfltName := "default-" + sect
-	dfltField, _ := fieldFold(vCfg, dfltName)
+	dfltField, _ := fieldFold(vCfg, dfltName, c.fold)
 	var err error
 	if dfltField.IsValid() {
 		b := bytes.NewBuffer(nil)
 		ge := gob.NewEncoder(b)
-		if err = c.Collect(ge.EncodeValue(dfltField)); err != nil {
-			return pv, err
+		if err = ge.EncodeValue(dfltField); err != nil {
+			return pv, c.Collect(locErr{err: err, loc: l})
 		}
 		gd := gob.NewDecoder(bytes.NewReader(b.Bytes()))
-		if err = c.Collect(gd.DecodeValue(pv.Elem())); err != nil {
-			return pv, err
+		if err = gd.DecodeValue(pv.Elem()); err != nil {
+			return pv, c.Collect(locErr{err: err, loc: l})
 		}
 	}
 	return pv, nil
 }
 
-func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
-	blank bool, value string, subsectPass bool) error {
+// setValue sets the value pointed to by d using the setter named in the tag t,
+// if any, or else the first setter that supports its type, falling back to
+// fmt.Sscanf unless disabled (see StrictTypes).
+func setValue(d interface{}, blank bool, val string, t tag) error {
+	if t.setter != "" {
+		return namedSetter(d, blank, val, t)
+	}
+	for _, s := range setters {
+		if err := s(d, blank, val, t); err != ErrUnsupportedType {
+			return err
+		}
+	}
+	if t.noScan {
+		if scannable(reflect.ValueOf(d).Type().Elem()) {
+			return errScanDisabled
+		}
+		return ErrUnsupportedType
+	}
+	return scanSetter(d, blank, val, t)
+}
+
+var errScanDisabled = fmt.Errorf("%w (parsing using fmt.Sscanf is "+
+	"disabled by StrictTypes)", ErrUnsupportedType)
+
+// isMultiVal reports whether a variable of type t is multi-valued; that is, if
+// t is an unnamed slice type or a pointer to one.
+func isMultiVal(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name() == "" && t.Kind() == reflect.Slice
+}
+
+// configs is a list of config structs that are read into together; each
+// section and variable is set in all the structs that define it.
+type configs []interface{}
+
+// setAll sets the value in all configs that define the section and variable,
+// and only reports data that can't be stored if none of them does.
+func setAll(c *collector, cfgs configs, sect, sub, name string,
+	blank bool, value string, appendSep *string, subsectPass bool, hdr header,
+	pos token.Position) error {
 	//
+	claiming := c.claiming
+	c.claiming, c.unclaimed = true, nil
+	claimed := false
+	for _, cfg := range cfgs {
+		unclaimed := c.unclaimed
+		c.unclaimed = nil
+		err := set(c, cfg, sect, sub, name, blank, value, appendSep,
+			subsectPass, hdr, pos)
+		if c.unclaimed == nil {
+			claimed = true
+		}
+		if unclaimed != nil && (c.unclaimed == nil ||
+			c.unclaimed.variable == nil && unclaimed.variable != nil) {
+			// prefer reporting a variable in a known section
+			c.unclaimed = unclaimed
+		}
+		if err != nil {
+			c.claiming = claiming
+			return err
+		}
+	}
+	c.claiming = claiming
+	// unknown sections are reported in the first pass
+	if !claimed && (!subsectPass || c.unclaimed.variable != nil) {
+		return c.Collect(*c.unclaimed)
+	}
+	return nil
+}
+
+func set(c *collector, cfg interface{}, sect, sub, name string,
+	blank bool, value string, appendSep *string, subsectPass bool, hdr header,
+	pos token.Position) error {
+	//
+	if cfgs, ok := cfg.(configs); ok {
+		return setAll(c, cfgs, sect, sub, name, blank, value, appendSep,
+			subsectPass, hdr, pos)
+	}
 	vPCfg := reflect.ValueOf(cfg)
 	if vPCfg.Kind() != reflect.Ptr || vPCfg.Elem().Kind() != reflect.Struct {
 		panic(fmt.Errorf("config must be a pointer to a struct"))
 	}
 	vCfg := vPCfg.Elem()
-	vSect, _ := fieldFold(vCfg, sect)
-	l := loc{section: sect}
+	vSect, st := fieldFold(vCfg, sect, c.fold)
+	l := loc{pos: pos, hdr: hdr, section: sect}
 	if !vSect.IsValid() {
+		if vAny, ta := anyField(vCfg); vAny.IsValid() {
+			if subsectPass { // already set in the first pass
+				return nil
+			}
+			return setAnySection(c, vAny, sect, sub, name, blank, value,
+				appendSep, ta, l)
+		}
+		if subsectPass { // already reported in the first pass
+			if c.claiming {
+				c.unclaimed = &extraData{loc: l}
+			}
+			return nil
+		}
 		err := extraData{loc: l}
 		return c.Collect(err)
 	}
@@ -249,7 +633,7 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 		if !pv.IsValid() {
 			vType := vSect.Type().Elem().Elem()
 			var err error
-			if pv, err = newValue(c, sect, vCfg, vType); err != nil {
+			if pv, err = newValue(c, sect, vCfg, vType, l); err != nil {
 				return err
 			}
 			vSect.SetMapIndex(k, pv)
@@ -259,23 +643,427 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 		panic(fmt.Errorf("field for section must be a map or a struct: "+
 			"section %q", sect))
 	} else if sub != "" {
-		return c.Collect(extraData{loc: l})
+		var match, known bool
+		if c.platform != nil {
+			match, known = c.platform.match(sub)
+		}
+		if !known {
+			if name == "" {
+				l.pos = hdr.sub
+			}
+			return c.Collect(extraData{loc: l})
+		}
+		if !match {
+			return nil
+		}
+		// platform-specific values are set into the section
+		l.subsection = &sub
 	}
 	// Empty name is a special value, meaning that only the
 	// section/subsection object is to be created, with no values set.
 	if name == "" {
 		return nil
 	}
-	vVar, t := fieldFold(vSect, name)
+	varName, key, hasKey := strings.Cut(name, ".")
+	vVar, t := fieldFold(vSect, varName, c.fold)
+	l.variable = &name
+	if !blank {
+		l.value, l.valued, l.secret = value, true, t.secret
+	}
+	if !vVar.IsValid() || hasKey && !t.dotted && !t.indexed {
+		vAny, ta := anyField(vSect)
+		if !vAny.IsValid() {
+			return c.Collect(extraData{loc: l, strict: st.strict,
+				lenient: st.lenient})
+		}
+		vmt := vAny.Type()
+		if vmt.Kind() != reflect.Map || vmt.Key().Kind() != reflect.String {
+			panic(fmt.Errorf("field for any variable must be a map with "+
+				"string keys: section %q", sect))
+		}
+		if !blank {
+			l.secret = ta.secret
+		}
+		ta.fsys, ta.noScan = c.fsys, c.strictTypes
+		return setMapKey(c, vAny, strings.ToLower(name), blank, value,
+			appendSep, ta, l)
+	}
+	if t.kind != "" {
+		t.kindOf = kindOf(vSect, t, l)
+	}
+	t.fsys, t.noScan = c.fsys, c.strictTypes
+	if t.indexed {
+		return setIndexed(c, vVar, newVarKey(sect, sub, varName), hasKey, key,
+			blank, value, appendSep, t, l)
+	}
+	if !t.dotted {
+		if c.replaceMulti && isMultiVal(vVar.Type()) {
+			p := vVar.Addr().Pointer()
+			if !c.multiSet[p] {
+				vVar.Set(reflect.Zero(vVar.Type()))
+			}
+			if c.multiSet == nil {
+				c.multiSet = map[uintptr]bool{}
+			}
+			c.multiSet[p] = true
+		}
+		return setVar(vVar, blank, value, appendSep, t, l)
+	}
+	vmt := vVar.Type()
+	if vmt.Kind() != reflect.Map || vmt.Key().Kind() != reflect.String {
+		panic(fmt.Errorf("field for dotted variable must be a map with "+
+			"string keys: section %q, variable %q", sect, varName))
+	}
+	if !hasKey {
+		if !blank {
+			return locErr{err: errMissingKey, loc: l}
+		}
+		// a blank value resets the map
+		vVar.Set(reflect.MakeMap(vmt))
+		return nil
+	}
+	return setMapKey(c, vVar, key, blank, value, appendSep, t, l)
+}
+
+// anyField returns the field of the section struct vSect with the ",any"
+// struct tag option, and its tag, if any.
+func anyField(vSect reflect.Value) (reflect.Value, tag) {
+	for i := 0; i < vSect.NumField(); i++ {
+		f := vSect.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		if t := newTag(f.Tag.Get("gcfg")); t.any {
+			return vSect.Field(i), t
+		}
+	}
+	return reflect.Value{}, tag{}
+}
+
+// setAnySection sets the variable name of the section sect (and subsection
+// sub, if not empty) in the map vAny of a config field with the ",any" struct
+// tag option, which holds the sections matching no other field; see the
+// package documentation. An empty name only creates the section.
+func setAnySection(c *collector, vAny reflect.Value, sect, sub, name string,
+	blank bool, value string, appendSep *string, t tag, l loc) error {
+	//
+	vat := vAny.Type()
+	if vat.Kind() != reflect.Map || vat.Key().Kind() != reflect.String ||
+		vat.Elem().Kind() != reflect.Map ||
+		vat.Elem().Key().Kind() != reflect.String {
+		panic(fmt.Errorf("field for any section must be a map with string " +
+			"keys and values that are maps with string keys"))
+	}
+	if vAny.IsNil() {
+		vAny.Set(reflect.MakeMap(vat))
+	}
+	key := strings.ToLower(sect)
+	if sub != "" {
+		l.subsection = &sub
+		key += "." + sub
+	}
+	k := reflect.ValueOf(key).Convert(vat.Key())
+	vSect := vAny.MapIndex(k)
+	if !vSect.IsValid() || vSect.IsNil() {
+		vSect = reflect.MakeMap(vat.Elem())
+		vAny.SetMapIndex(k, vSect)
+	}
+	if name == "" {
+		return nil
+	}
 	l.variable = &name
-	if !vVar.IsValid() {
-		return c.Collect(extraData{loc: l})
+	if !blank {
+		l.value, l.valued, l.secret = value, true, t.secret
+	}
+	t.fsys, t.noScan = c.fsys, c.strictTypes
+	return setMapKey(c, vSect, strings.ToLower(name), blank, value, appendSep,
+		t, l)
+}
+
+// setMapKey sets the element with the given key of the map vVar, which has
+// string keys, allocating the map as needed.
+func setMapKey(c *collector, vVar reflect.Value, key string, blank bool,
+	value string, appendSep *string, t tag, l loc) error {
+	//
+	vmt := vVar.Type()
+	if vVar.IsNil() {
+		vVar.Set(reflect.MakeMap(vmt))
+	}
+	k := reflect.ValueOf(key).Convert(vmt.Key())
+	if c.mapPolicy != MapKeysLastWins && !blank && appendSep == nil &&
+		!isMultiVal(vmt.Elem()) {
+		mk := mapKey{vVar.Pointer(), key}
+		if c.mapKeys[mk] {
+			switch c.mapPolicy {
+			case MapKeysFirstWins:
+				return nil
+			case MapKeysError:
+				err := fmt.Errorf("duplicate key %q", key)
+				return locErr{err: err, loc: l}
+			case MapKeysConcat:
+				appendSep = &c.mapSep
+			}
+		}
+		if c.mapKeys == nil {
+			c.mapKeys = map[mapKey]bool{}
+		}
+		c.mapKeys[mk] = true
+	}
+	// map elements are not addressable; set a copy and store it
+	vElem := reflect.New(vmt.Elem()).Elem()
+	if pv := vVar.MapIndex(k); pv.IsValid() {
+		vElem.Set(pv)
+	}
+	if err := setVar(vElem, blank, value, appendSep, t, l); err != nil {
+		return err
+	}
+	vVar.SetMapIndex(k, vElem)
+	return nil
+}
+
+// A mapKey identifies a key of the map of a dotted variable; see
+// DuplicateMapKeys.
+type mapKey struct {
+	m   uintptr // map pointer
+	key string
+}
+
+// maxIndex is the limit for indexes of indexed variables, to avoid allocating
+// huge slices.
+const maxIndex = 1 << 16
+
+// indexed holds the indexes set for an indexed variable, for detecting gaps.
+type indexed struct {
+	l   loc // location of the last definition
+	set map[int]bool
+	max int
+}
+
+// setIndexed sets the element of the indexed variable vVar (identified by k)
+// with the given key (index), growing the slice as needed. Each index can
+// only be set once, unless the elements are multi-valued. Without a key, only
+// a blank value is accepted, which resets the slice.
+func setIndexed(c *collector, vVar reflect.Value, k varKey, hasKey bool,
+	key string, blank bool, value string, appendSep *string, t tag,
+	l loc) error {
+	//
+	vst := vVar.Type()
+	if vst.Kind() != reflect.Slice || vst.Name() != "" {
+		panic(fmt.Errorf("field for indexed variable must be an unnamed "+
+			"slice: section %q, variable %q", l.section, k.name))
+	}
+	if c.indexedM == nil {
+		c.indexedM = map[varKey]*indexed{}
+	}
+	idx := c.indexedM[k]
+	if !hasKey {
+		if !blank {
+			return locErr{err: errMissingKey, loc: l}
+		}
+		vVar.Set(reflect.Zero(vst))
+		if idx != nil {
+			idx.set, idx.max = map[int]bool{}, -1
+		}
+		return nil
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || key != strconv.Itoa(i) {
+		return locErr{err: fmt.Errorf("invalid index %q", key), loc: l}
+	}
+	if i >= maxIndex {
+		return locErr{err: fmt.Errorf("index %d too large", i), loc: l}
+	}
+	if idx == nil {
+		idx = &indexed{set: map[int]bool{}, max: -1}
+		c.indexedM[k] = idx
+		c.indexed = append(c.indexed, idx)
+	}
+	if idx.set[i] && !isMultiVal(vst.Elem()) {
+		return locErr{err: fmt.Errorf("duplicate index %d", i), loc: l}
+	}
+	idx.l, idx.set[i] = l, true
+	if i > idx.max {
+		idx.max = i
+	}
+	if n := i + 1 - vVar.Len(); n > 0 {
+		vVar.Set(reflect.AppendSlice(vVar, reflect.MakeSlice(vst, n, n)))
+	}
+	return setVar(vVar.Index(i), blank, value, appendSep, t, l)
+}
+
+// checkIndexes reports the gaps in the indexes set for indexed variables.
+func checkIndexes(c *collector) error {
+	for _, idx := range c.indexed {
+		for i := 0; i < idx.max; i++ {
+			if idx.set[i] {
+				continue
+			}
+			err := locErr{err: fmt.Errorf("missing index %d", i), loc: idx.l}
+			if err := c.Collect(err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMinVersion returns an error if the version pointed to by d is lower
+// than min. It panics if d doesn't point to a types.Version, or if min is not
+// a valid version.
+func checkMinVersion(d interface{}, min string, l loc) error {
+	v, ok := d.(*types.Version)
+	if !ok {
+		panic(fmt.Errorf("minver struct tag option requires a types.Version "+
+			"variable: section %q, variable %q", l.section, *l.variable))
+	}
+	mv, err := types.ParseVersion(min)
+	if err != nil {
+		panic(fmt.Errorf("invalid minver struct tag option: %v: "+
+			"section %q, variable %q", err, l.section, *l.variable))
+	}
+	if v.Less(mv) {
+		return fmt.Errorf("version %s is lower than the minimum %s", v, mv)
+	}
+	return nil
+}
+
+// checkNoOverlap returns an error if the types.CIDRList pointed to by d has
+// overlapping prefixes. It panics if d doesn't point to a types.CIDRList.
+func checkNoOverlap(d interface{}, l loc) error {
+	cl, ok := d.(*types.CIDRList)
+	if !ok {
+		panic(fmt.Errorf("nooverlap struct tag option requires a "+
+			"types.CIDRList variable: section %q, variable %q", l.section,
+			*l.variable))
+	}
+	if a, b, ok := cl.Overlap(); ok {
+		return fmt.Errorf("prefixes %s and %s overlap", a, b)
+	}
+	return nil
+}
+
+// checkRange returns an error if the duration or number (integer,
+// floating-point, big.Int or big.Float) v is outside the range set by the
+// ",min=value" and ",max=value" struct tag options. It panics if v is of
+// another type, or if a bound can't be parsed. v must be addressable.
+func checkRange(v reflect.Value, t tag, l loc) error {
+	// bounds are parsed into a value of the same type as v
+	parse := func(s string) reflect.Value {
+		pb := reflect.New(v.Type())
+		err := setValue(pb.Interface(), false, s, tag{})
+		if err != nil || !isRangeType(v.Type()) {
+			panic(fmt.Errorf("invalid min/max struct tag option %q: "+
+				"section %q, variable %q", s, l.section, *l.variable))
+		}
+		return pb.Elem()
+	}
+	less := func(a, b reflect.Value) bool {
+		switch x := a.Addr().Interface().(type) {
+		case *big.Int:
+			return x.Cmp(b.Addr().Interface().(*big.Int)) < 0
+		case *big.Float:
+			return x.Cmp(b.Addr().Interface().(*big.Float)) < 0
+		}
+		switch a.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		}
+		return a.Int() < b.Int()
+	}
+	// big numbers are formatted using their pointer methods
+	format := func(v reflect.Value) interface{} {
+		if v.Kind() == reflect.Struct {
+			return v.Addr().Interface()
+		}
+		return v.Interface()
+	}
+	var min, max reflect.Value
+	if t.min != nil {
+		min = parse(*t.min)
+	}
+	if t.max != nil {
+		max = parse(*t.max)
+	}
+	switch {
+	case min.IsValid() && max.IsValid() && (less(v, min) || less(max, v)):
+		return fmt.Errorf("value %v out of range: must be between %v and %v",
+			format(v), format(min), format(max))
+	case min.IsValid() && less(v, min):
+		return fmt.Errorf("value %v out of range: must be at least %v",
+			format(v), format(min))
+	case max.IsValid() && less(max, v):
+		return fmt.Errorf("value %v out of range: must be at most %v",
+			format(v), format(max))
+	}
+	return nil
+}
+
+// isRangeType reports whether the range of values of type t can be
+// restricted using the ",min=value" and ",max=value" struct tag options.
+func isRangeType(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(big.Int{}), reflect.TypeOf(big.Float{}):
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32,
+		reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setVar sets the variable vVar (appending to it if multi-valued) to the value
+// parsed from value; l is the location of the definition. If appendSep is not
+// nil, a string value is appended to the current one, separated by *appendSep.
+func setVar(vVar reflect.Value, blank bool, value string, appendSep *string,
+	t tag, l loc) error {
+	//
+	if blank && t.implicit != nil {
+		blank, value = false, *t.implicit
+		l.value, l.valued, l.secret = value, true, t.secret
+	}
+	if len(t.transforms) > 0 && !blank {
+		var err error
+		if value, err = transform(value, t, l); err != nil {
+			return locErr{err: err, loc: l}
+		}
+	}
+	switch t.relTo {
+	case "":
+	case "config":
+		switch {
+		case blank || l.pos.Filename == "":
+		case t.fsys != nil:
+			value = path.Join(path.Dir(l.pos.Filename), value)
+		case !filepath.IsAbs(value):
+			value = filepath.Join(filepath.Dir(l.pos.Filename), value)
+		}
+	default:
+		panic(fmt.Errorf("invalid relto struct tag option %q: "+
+			"section %q, variable %q", t.relTo, l.section, *l.variable))
+	}
+	if t.fromFile && !blank {
+		var b []byte
+		var err error
+		if t.fsys != nil {
+			b, err = fs.ReadFile(t.fsys, value)
+		} else {
+			b, err = ioutil.ReadFile(value)
+		}
+		if err != nil {
+			return locErr{err: err, loc: l}
+		}
+		value = strings.TrimRight(string(b), "\r\n")
 	}
 	// vVal is either single-valued var, or newly allocated value within multi-valued var
 	var vVal reflect.Value
-	// multi-value if unnamed slice type
-	isMulti := vVar.Type().Name() == "" && vVar.Kind() == reflect.Slice ||
-		vVar.Type().Name() == "" && vVar.Kind() == reflect.Ptr && vVar.Type().Elem().Name() == "" && vVar.Type().Elem().Kind() == reflect.Slice
+	isMulti := isMultiVal(vVar.Type())
 	if isMulti && vVar.Kind() == reflect.Ptr {
 		if vVar.IsNil() {
 			vVar.Set(reflect.New(vVar.Type().Elem()))
@@ -286,6 +1074,14 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 		vVar.Set(reflect.Zero(vVar.Type()))
 		return nil
 	}
+	if ls, ok := listSetters[vVar.Type()]; isMulti && ok {
+		vs, err := ls(value)
+		if err != nil {
+			return locErr{err: err, loc: l}
+		}
+		vVar.Set(reflect.AppendSlice(vVar, reflect.ValueOf(vs)))
+		return nil
+	}
 	if isMulti {
 		vVal = reflect.New(vVar.Type().Elem()).Elem()
 	} else {
@@ -293,9 +1089,15 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 	}
 	isDeref := vVal.Type().Name() == "" && vVal.Type().Kind() == reflect.Ptr
 	isNew := isDeref && vVal.IsNil()
+	isKind := vVal.Kind() == reflect.Interface && t.kind != ""
 	// vAddr is address of value to set (dereferenced & allocated as needed)
 	var vAddr reflect.Value
 	switch {
+	case isKind:
+		var err error
+		if vAddr, err = newKind(vVal.Type(), t.kindOf); err != nil {
+			return locErr{err: err, loc: l}
+		}
 	case isNew:
 		vAddr = reflect.New(vVal.Type().Elem())
 	case isDeref && !isNew:
@@ -303,23 +1105,44 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 	default:
 		vAddr = vVal.Addr()
 	}
-	vAddrI := vAddr.Interface()
-	err, ok := error(nil), false
-	for _, s := range setters {
-		err = s(vAddrI, blank, value, t)
-		if err == nil {
-			ok = true
-			break
+	if appendSep != nil && !isMulti {
+		if vAddr.Elem().Kind() != reflect.String {
+			return locErr{err: errAppendUnsupported, loc: l}
 		}
-		if err != errUnsupportedType {
-			return locErr{msg: err.Error(), loc: l}
+		if cur := vAddr.Elem().String(); cur != "" {
+			value = cur + *appendSep + value
 		}
 	}
-	if !ok {
-		// in case all setters returned errUnsupportedType
-		return locErr{msg: err.Error(), loc: l}
+	if err := setValue(vAddr.Interface(), blank, value, t); err != nil {
+		if err == errBlankUnsupported {
+			err = fmt.Errorf("%w %v (only bool variables can be "+
+				"specified without '= value')", err, vAddr.Type().Elem())
+		}
+		return locErr{err: err, loc: l}
+	}
+	if t.minVer != "" {
+		if err := checkMinVersion(vAddr.Interface(), t.minVer, l); err != nil {
+			return locErr{err: err, loc: l}
+		}
 	}
-	if isNew { // set reference if it was dereferenced and newly allocated
+	if t.min != nil || t.max != nil {
+		if err := checkRange(vAddr.Elem(), t, l); err != nil {
+			return locErr{err: err, loc: l}
+		}
+	}
+	if t.noOverlap {
+		if err := checkNoOverlap(vAddr.Interface(), l); err != nil {
+			return locErr{err: err, loc: l}
+		}
+	}
+	switch {
+	case isKind: // set the implementation, or a pointer to it
+		if vAddr.Type().Implements(vVal.Type()) {
+			vVal.Set(vAddr)
+		} else {
+			vVal.Set(vAddr.Elem())
+		}
+	case isNew: // set reference if it was dereferenced and newly allocated
 		vVal.Set(vAddr)
 	}
 	if isMulti { // append if multi-valued
@@ -327,3 +1150,239 @@ func set(c *warnings.Collector, cfg interface{}, sect, sub, name string,
 	}
 	return nil
 }
+
+// checkRequired reports the sections and variables with the ",required"
+// struct tag option that are not in seen (that is, not defined in the data,
+// nor set from the environment or to a default value). Variables of sections
+// with subsections are only checked in the subsections that exist in the
+// config. All of them are reported together as an ErrorList, unless
+// continuing past errors.
+func checkRequired(c *collector, cfg interface{}, filename string,
+	seen map[varKey]bool) error {
+	//
+	var missing ErrorList
+	report := func(sect string, sub *string, name *string) {
+		l := loc{pos: token.Position{Filename: filename}, section: sect,
+			subsection: sub, variable: name}
+		missing = append(missing, locErr{err: ErrMissingRequired, loc: l})
+	}
+	cfgs, ok := cfg.(configs)
+	if !ok {
+		cfgs = configs{cfg}
+	}
+	for _, cfg := range cfgs {
+		vCfg := reflect.ValueOf(cfg).Elem()
+		for i := 0; i < vCfg.NumField(); i++ {
+			f := vCfg.Type().Field(i)
+			if f.PkgPath != "" { // unexported
+				continue
+			}
+			sect, st := fieldName(f)
+			if st.any { // not a section
+				continue
+			}
+			vSect := vCfg.Field(i)
+			switch vSect.Kind() {
+			case reflect.Struct:
+				if st.required && !seen[newVarKey(sect, "", "")] {
+					report(sect, nil, nil)
+					continue
+				}
+				names := requiredVars(vSect.Type())
+				checkRequiredSection(names, sect, nil, seen, report)
+			case reflect.Map:
+				if st.required {
+					found := false
+					for k := range seen {
+						found = found || k.sect == strings.ToLower(sect) &&
+							k.sub != "" && k.name == ""
+					}
+					if !found {
+						report(sect, nil, nil)
+						continue
+					}
+				}
+				names := requiredVars(vSect.Type().Elem().Elem())
+				if len(names) == 0 {
+					continue
+				}
+				keys := vSect.MapKeys()
+				sort.Slice(keys, func(i, j int) bool {
+					return keys[i].String() < keys[j].String()
+				})
+				for _, k := range keys {
+					sub := k.String()
+					checkRequiredSection(names, sect, &sub, seen, report)
+				}
+			}
+		}
+	}
+	if c.partial {
+		for _, err := range missing {
+			if err := c.Collect(err); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return c.Collect(missing[0])
+	}
+	return c.Collect(missing)
+}
+
+// requiredVars returns the names of the variables of the section of type
+// tSect with the ",required" struct tag option.
+func requiredVars(tSect reflect.Type) []string {
+	var names []string
+	for i := 0; i < tSect.NumField(); i++ {
+		f := tSect.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		// only required variables are named, to avoid allocating
+		if !newTag(f.Tag.Get("gcfg")).required {
+			continue
+		}
+		name, _ := fieldName(f)
+		names = append(names, name)
+	}
+	return names
+}
+
+// checkRequiredSection reports the required variables of a section, with the
+// given names (see requiredVars), that are not in seen.
+func checkRequiredSection(names []string, sect string, sub *string,
+	seen map[varKey]bool, report func(sect string, sub, name *string)) {
+	//
+	s := ""
+	if sub != nil {
+		s = *sub
+	}
+	for i := range names {
+		if !seen[newVarKey(sect, s, names[i])] {
+			report(sect, sub, &names[i])
+		}
+	}
+}
+
+// checkDefault panics if the ",default=value" struct tag option in t can't be
+// parsed as a value of a variable of type vt.
+func checkDefault(vt reflect.Type, t tag, sect, name string) {
+	if isMultiVal(vt) {
+		if vt.Kind() == reflect.Ptr {
+			vt = vt.Elem()
+		}
+		vt = vt.Elem()
+	}
+	if vt.Name() == "" && vt.Kind() == reflect.Ptr {
+		vt = vt.Elem()
+	}
+	if vt.Kind() == reflect.Interface || t.fromFile {
+		return // depends on the kind or the file; checked when set
+	}
+	if err := setValue(reflect.New(vt).Interface(), false, *t.dflt,
+		t); err != nil {
+		panic(fmt.Errorf("invalid default value %q: %v: section %q, "+
+			"variable %q", *t.dflt, err, sect, name))
+	}
+}
+
+// A varKey identifies a variable by its section, subsection, and name;
+// section and variable names are lowercased, as they are case-insensitive.
+type varKey struct{ sect, sub, name string }
+
+func newVarKey(sect, sub, name string) varKey {
+	return varKey{strings.ToLower(sect), sub, strings.ToLower(name)}
+}
+
+// setFallbacks sets the variables that are not in seen from the environment
+// variable NAME of the ",env=NAME" struct tag option, if it is set, or else
+// to the value of the ",default=value" struct tag option, if the field has
+// its zero value. Subsections are only considered if they exist in the
+// config.
+func setFallbacks(c *collector, cfg interface{}, filename string,
+	seen map[varKey]bool) error {
+	//
+	if cfgs, ok := cfg.(configs); ok {
+		for _, cfg := range cfgs {
+			if err := setFallbacks(c, cfg, filename, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	vPCfg := reflect.ValueOf(cfg)
+	if vPCfg.Kind() != reflect.Ptr || vPCfg.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("config must be a pointer to a struct"))
+	}
+	vCfg := vPCfg.Elem()
+	for i := 0; i < vCfg.NumField(); i++ {
+		f := vCfg.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		sect, st := fieldName(f)
+		if st.any { // not a section
+			continue
+		}
+		vSect := vCfg.Field(i)
+		switch vSect.Kind() {
+		case reflect.Struct:
+			if err := setFallbacksSection(c, cfg, filename, sect, "",
+				vSect, false, seen); err != nil {
+				return err
+			}
+		case reflect.Map:
+			for _, k := range vSect.MapKeys() {
+				pv := vSect.MapIndex(k)
+				if pv.IsNil() {
+					continue
+				}
+				if err := setFallbacksSection(c, cfg, filename, sect,
+					k.String(), pv.Elem(), true, seen); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func setFallbacksSection(c *collector, cfg interface{}, filename, sect,
+	sub string, vSect reflect.Value, isSubsect bool,
+	seen map[varKey]bool) error {
+	//
+	tSect := vSect.Type()
+	for i := 0; i < tSect.NumField(); i++ {
+		f := tSect.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		name, t := fieldName(f)
+		if t.env == "" && t.dflt == nil || seen[newVarKey(sect, sub, name)] {
+			continue
+		}
+		v, ok := "", false
+		if t.env != "" {
+			v, ok = os.LookupEnv(t.env)
+		}
+		if !ok && t.dflt != nil && vSect.Field(i).IsZero() {
+			checkDefault(f.Type, t, sect, name)
+			v, ok = *t.dflt, true
+		}
+		if !ok {
+			continue
+		}
+		seen[newVarKey(sect, sub, name)] = true
+		err := set(c, cfg, sect, sub, name, false, v, nil, isSubsect, header{},
+			token.Position{Filename: filename})
+		if err = c.Collect(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package gcfg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// namedSetters holds the setters registered for the ",setter=name" struct tag
+// option.
+var namedSetters = struct {
+	sync.RWMutex
+	m map[string]func(d interface{}, value string) error
+}{m: map[string]func(d interface{}, value string) error{}}
+
+// RegisterSetter registers fn as the setter named name, for variables with
+// the ",setter=name" struct tag option; this makes it possible to parse values
+// of a type differently for individual variables without defining a new
+// type, e.g. `gcfg:",setter=millis"` for an int64 holding milliseconds. fn is
+// called with a pointer to the variable (or to the element, for multi-valued
+// variables) and the value, and returns an error if the value is invalid;
+// "blank" values are only supported if the variable also has an ",implicit="
+// value. fn should panic if the type of the variable is not supported, as
+// this is a programmer error.
+//
+// RegisterSetter is typically called from an init function; it panics if name
+// is empty, contains ',', or is already registered.
+func RegisterSetter(name string, fn func(d interface{}, value string) error) {
+	if name == "" || strings.Contains(name, ",") {
+		panic(fmt.Errorf("invalid setter name %q", name))
+	}
+	namedSetters.Lock()
+	defer namedSetters.Unlock()
+	if _, ok := namedSetters.m[name]; ok {
+		panic(fmt.Errorf("setter already registered: setter %q", name))
+	}
+	namedSetters.m[name] = fn
+}
+
+// namedSetter sets the value pointed to by d using the setter named in the tag
+// t. It panics if the setter is not registered.
+func namedSetter(d interface{}, blank bool, val string, t tag) error {
+	namedSetters.RLock()
+	fn, ok := namedSetters.m[t.setter]
+	namedSetters.RUnlock()
+	if !ok {
+		panic(fmt.Errorf("unknown setter %q", t.setter))
+	}
+	if blank {
+		return errBlankUnsupported
+	}
+	return fn(d, val)
+}
@@ -0,0 +1,168 @@
+package gcfg
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// Snapshot returns a snapshot of config (a struct or a pointer to a struct):
+// its canonical encoding, as returned by Marshal without options, which can
+// be stored (e.g. in a golden file; see CompareGolden) and read back using
+// Restore.
+//
+// Snapshots are meant to be used in tests, to lock down the configs produced
+// or modified by the code under test, in a compact and readable form.
+func Snapshot(config interface{}) ([]byte, error) {
+	b, err := Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("gcfg: snapshot: %w", err)
+	}
+	return b, nil
+}
+
+// Restore sets config (a pointer to a struct) to the values in snapshot, as
+// returned by Snapshot; fields that are not in the snapshot are set to their
+// zero values. If the snapshot can't be read, config is left unchanged and
+// the error is returned.
+func Restore(config interface{}, snapshot []byte) error {
+	vCfg := reflect.ValueOf(config).Elem()
+	res := reflect.New(vCfg.Type())
+	if err := ReadBytesInto(res.Interface(), snapshot); err != nil {
+		return fmt.Errorf("gcfg: restore: %w", err)
+	}
+	vCfg.Set(res.Elem())
+	return nil
+}
+
+// CompareGolden compares the snapshot of config (see Snapshot) with the
+// contents of the golden file filename, and returns an error showing the
+// differing lines if they are not equal. If update is true, the golden file
+// is instead (over)written with the snapshot; this is typically controlled by
+// an -update flag of the test binary, so that golden files can be regenerated
+// after an intended change, and the change reviewed in version control.
+func CompareGolden(config interface{}, filename string, update bool) error {
+	got, err := Snapshot(config)
+	if err != nil {
+		return err
+	}
+	if update {
+		return ioutil.WriteFile(filename, got, 0644)
+	}
+	exp, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(got, exp) {
+		return nil
+	}
+	return fmt.Errorf("gcfg: snapshot differs from golden file %s "+
+		"(-golden +got):\n%s", filename, diffLines(string(exp), string(got)))
+}
+
+// diffLines returns the lines that differ between exp and got, prefixed with
+// '-' and '+' respectively, with a line of unchanged context around them
+// (prefixed with ' '); other unchanged lines are elided as "...". It returns
+// the empty string if there are no differences.
+func diffLines(exp, got string) string {
+	x, y := splitLines(exp), splitLines(got)
+	// only the lines between the common prefix and suffix need comparing
+	pre, suf := 0, 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	for suf < len(x)-pre && suf < len(y)-pre &&
+		x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	var lines []string
+	for _, l := range x[:pre] {
+		lines = append(lines, " "+l)
+	}
+	lines = append(lines, lcsLines(x[pre:len(x)-suf], y[pre:len(y)-suf])...)
+	for _, l := range x[len(x)-suf:] {
+		lines = append(lines, " "+l)
+	}
+	changed := func(k int) bool {
+		return k >= 0 && k < len(lines) && lines[k][0] != ' '
+	}
+	var b strings.Builder
+	elided := false
+	for k, l := range lines {
+		if !changed(k-1) && !changed(k) && !changed(k+1) {
+			elided = true
+			continue
+		}
+		if elided {
+			b.WriteString("...\n")
+			elided = false
+		}
+		b.WriteString(l + "\n")
+	}
+	if elided && b.Len() > 0 {
+		b.WriteString("...\n")
+	}
+	return b.String()
+}
+
+// maxDiffCells bounds the size of the table used by lcsLines, which is the
+// product of the numbers of lines compared.
+const maxDiffCells = 1 << 20
+
+// lcsLines returns the lines of x and y prefixed as for diffLines, keeping a
+// longest common subsequence unchanged. If the table needed for that would be
+// too large, it returns all of x as removed and all of y as added instead.
+func lcsLines(x, y []string) []string {
+	var lines []string
+	if len(x) > 0 && len(y) > maxDiffCells/len(x) {
+		for _, l := range x {
+			lines = append(lines, "-"+l)
+		}
+		for _, l := range y {
+			lines = append(lines, "+"+l)
+		}
+		return lines
+	}
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, " "+x[i])
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+x[i])
+			i++
+		default:
+			lines = append(lines, "+"+y[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// splitLines splits s into lines, without the line terminators.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
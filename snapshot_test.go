@@ -0,0 +1,79 @@
+package gcfg
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	cfg := &cSubs{map[string]*cSubsS1{"a": {"x"}, "b": {"y"}}}
+	b, err := Snapshot(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &cSubs{map[string]*cSubsS1{"c": {"z"}}}
+	if err := Restore(res, b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("got %+v, wanted %+v", res, cfg)
+	}
+	if err := Restore(res, []byte("[sub")); err == nil {
+		t.Error("got no error for invalid snapshot")
+	} else if len(res.Sub) != 2 {
+		t.Errorf("got %+v, wanted config unchanged", res)
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "golden.gcfg")
+	cfg := &cBasic{Section: cBasicS1{Name: "a", Int: 1}}
+	if err := CompareGolden(cfg, filename, false); err == nil {
+		t.Error("got no error for missing golden file")
+	}
+	if err := CompareGolden(cfg, filename, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareGolden(cfg, filename, false); err != nil {
+		t.Errorf("got error %v for unchanged config", err)
+	}
+	cfg.Section.Int = 2
+	err := CompareGolden(cfg, filename, false)
+	exp := "-\tint = 1\n+\tint = 2\n"
+	if err == nil || !strings.Contains(err.Error(), exp) {
+		t.Errorf("got error %v, wanted diff containing %q", err, exp)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, tt := range []struct {
+		exp, got, diff string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\nd\ne\n", "a\nb\nx\nd\ne\n", "...\n b\n-c\n+x\n d\n...\n"},
+		{"", "a\n", "+a\n"},
+		{"a\nb\n", "b\n", "-a\n b\n"},
+	} {
+		if diff := diffLines(tt.exp, tt.got); diff != tt.diff {
+			t.Errorf("%q, %q: got %q, wanted %q", tt.exp, tt.got, diff, tt.diff)
+		}
+	}
+	// too many differing lines to compare; all are reported as changed
+	var exp, got, diff strings.Builder
+	for i := 0; i < 1100; i++ {
+		exp.WriteString([]string{"0\n", "1\n"}[i%2])
+		got.WriteString([]string{"1\n", "0\n"}[i%2])
+	}
+	diff.WriteString(" x\n")
+	for _, l := range splitLines(exp.String()) {
+		diff.WriteString("-" + l + "\n")
+	}
+	for _, l := range splitLines(got.String()) {
+		diff.WriteString("+" + l + "\n")
+	}
+	if d := diffLines("x\n"+exp.String(), "x\n"+got.String()); d != diff.String() {
+		t.Errorf("got diff of %d bytes, wanted %d", len(d), diff.Len())
+	}
+}
@@ -0,0 +1,80 @@
+package gcfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+var errInvalidName = errors.New("invalid section or variable name")
+
+// BeginSection starts writing the section named section (with the subsection
+// named subsection, if not empty) to the stream, for tools that generate large
+// configurations without holding them in a config struct: each section is
+// written using BeginSection, WriteVar for each variable, and EndSection, and
+// its data is written directly to the underlying writer (which should
+// therefore be buffered as needed). The encoder options apply as for Encode,
+// where relevant; sections written this way can be freely mixed with Encode
+// calls.
+//
+// BeginSection panics if the previous section has not been ended.
+func (e *Encoder) BeginSection(section, subsection string) error {
+	if e.open != nil {
+		panic(fmt.Errorf("section not ended: section %q", e.open.section))
+	}
+	l := loc{section: section}
+	if subsection != "" {
+		l.subsection = &subsection
+	}
+	if !isName(section) {
+		return locErr{err: errInvalidName, loc: l}
+	}
+	if err := e.writeHeader(section, l.subsection, false); err != nil {
+		return err
+	}
+	e.open = &l
+	return nil
+}
+
+// WriteVar writes the variable name with value in the section begun by
+// BeginSection. The value is formatted as the value of a field of the same
+// type in a config struct (see Marshal); in particular, a slice is written as
+// one line per element. If value is nil, a "blank" value (the variable name
+// only) is written.
+//
+// WriteVar panics if no section has been begun.
+func (e *Encoder) WriteVar(name string, value interface{}) error {
+	if e.open == nil {
+		panic(fmt.Errorf("no section begun: variable %q", name))
+	}
+	l := *e.open
+	l.variable = &name
+	if !isName(name) {
+		return locErr{err: errInvalidName, loc: l}
+	}
+	if value == nil {
+		return e.encodeBlank(name, false)
+	}
+	return e.encodeVar(name, reflect.ValueOf(value), tag{}, false, false, l)
+}
+
+// EndSection ends the section begun by BeginSection. It panics if no section
+// has been begun.
+func (e *Encoder) EndSection() {
+	if e.open == nil {
+		panic(fmt.Errorf("no section begun"))
+	}
+	e.open = nil
+}
+
+// isName reports whether s is a valid section or variable name; that is, if
+// it is a letter followed by letters, digits and hyphens.
+func isName(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r) && r != '-') {
+			return false
+		}
+	}
+	return s != ""
+}
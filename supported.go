@@ -0,0 +1,87 @@
+package gcfg
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+)
+
+// SupportedTypes returns the types (other than those of the supported kinds;
+// see SupportedKinds) that variables can have for their values to be parsed
+// by a dedicated setter, sorted by name; e.g. time.Duration and url.URL. In
+// addition, any type whose pointer implements encoding.TextUnmarshaler or
+// fmt.Scanner is supported; see Supported.
+func SupportedTypes() []reflect.Type {
+	ts := make([]reflect.Type, 0, len(typeSetters))
+	for t := range typeSetters {
+		ts = append(ts, t)
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].String() < ts[j].String()
+	})
+	return ts
+}
+
+// SupportedKinds returns the kinds of the types that variables can have for
+// their values to be parsed (by a dedicated setter or using fmt.Sscanf), in
+// increasing order; e.g. reflect.String and reflect.Int.
+func SupportedKinds() []reflect.Kind {
+	var ks []reflect.Kind
+	for k := reflect.Invalid; k <= reflect.UnsafePointer; k++ {
+		_, ok := kindSetters[k]
+		if ok || k != reflect.Slice && scannableKind(k) {
+			ks = append(ks, k)
+		}
+	}
+	return ks
+}
+
+// Setters returns the names of the setters registered using RegisterSetter,
+// in increasing order.
+func Setters() []string {
+	namedSetters.RLock()
+	defer namedSetters.RUnlock()
+	names := make([]string, 0, len(namedSetters.m))
+	for name := range namedSetters.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Supported reports whether the values of a variable of type t can be parsed
+// (without the ",setter=name" struct tag option, which makes any type
+// supported by the setter); e.g. for linters to check config structs before
+// they are used. Multi-valued variables are supported if their elements are,
+// and unnamed pointer types if the types they point to are; interface types
+// are supported if any kinds are registered for them (see RegisterKind).
+func Supported(t reflect.Type) bool {
+	if isMultiVal(t) {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if _, ok := listSetters[t]; ok {
+			return true
+		}
+		t = t.Elem()
+	}
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		kinds.RLock()
+		defer kinds.RUnlock()
+		return len(kinds.m[t]) > 0
+	}
+	if _, ok := typeSetters[t]; ok {
+		return true
+	}
+	tu := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	if reflect.PtrTo(t).Implements(tu) {
+		return true
+	}
+	if _, ok := kindSetters[t.Kind()]; ok {
+		return true
+	}
+	return scannable(t)
+}
@@ -0,0 +1,61 @@
+package gcfg
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/gcfg.v1/types"
+)
+
+func TestSupported(t *testing.T) {
+	for _, tt := range []struct {
+		v   interface{}
+		exp bool
+	}{
+		{"", true},
+		{0, true},
+		{3.5, true},
+		{time.Duration(0), true},
+		{&url.URL{}, true},
+		{[]*url.URL{}, true},
+		{&[]int{}, true},
+		{types.Version{}, true},
+		{unmarshalable(""), true},
+		{nonMulti{}, false},
+		{(*storage)(nil), true},
+		{(*interface{ x() })(nil), false},
+		{struct{}{}, false},
+		{map[string]string{}, false},
+		{[]chan int{}, false},
+		{func() {}, false},
+	} {
+		typ := reflect.TypeOf(tt.v)
+		if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Interface {
+			typ = typ.Elem()
+		}
+		if got := Supported(typ); got != tt.exp {
+			t.Errorf("%v: got %v, wanted %v", typ, got, tt.exp)
+		}
+	}
+}
+
+func TestSupportedTypesAndKinds(t *testing.T) {
+	ts := SupportedTypes()
+	if len(ts) != len(typeSetters) || ts[len(ts)-1] != reflect.TypeOf(url.URL{}) {
+		t.Errorf("got types %v", ts)
+	}
+	ks := SupportedKinds()
+	if ks[0] != reflect.Bool || ks[len(ks)-1] != reflect.String {
+		t.Errorf("got kinds %v", ks)
+	}
+	for _, k := range ks {
+		if k == reflect.Slice || k == reflect.Struct {
+			t.Errorf("got unsupported kind %v", k)
+		}
+	}
+	if s := Setters(); len(s) == 0 || s[0] != "test-millis" {
+		t.Errorf("got setters %v", s)
+	}
+}
@@ -0,0 +1,89 @@
+package gcfg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/gcfg.v1/scanner"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// Throughput holds the results of MeasureThroughput.
+type Throughput struct {
+	Files   int           // number of files processed
+	Bytes   int64         // number of bytes processed (over all iterations)
+	Lines   int64         // number of lines processed (over all iterations)
+	Elapsed time.Duration // time spent processing, excluding file reading
+}
+
+// BytesPerSecond returns the number of bytes processed per second.
+func (t Throughput) BytesPerSecond() float64 {
+	if t.Elapsed <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Elapsed.Seconds()
+}
+
+// MeasureThroughput measures the parse throughput on a corpus of configuration
+// files: the files in dir matching "*.gcfg" are each processed n times, after
+// being read into memory. If newConfig is nil, the files are only scanned;
+// otherwise they are read into the config returned by newConfig (called for
+// each file and iteration), as with ReadFileInto.
+//
+// This makes it possible to catch performance regressions in gcfg on
+// realistic data, e.g. from a benchmark in the calling package. Warnings
+// (see FatalOnly) are ignored, but a fatal error reading any file is
+// returned, along with the results up to that point.
+func MeasureThroughput(dir string, newConfig func() interface{},
+	n int) (Throughput, error) {
+	//
+	var res Throughput
+	files, err := filepath.Glob(filepath.Join(dir, "*.gcfg"))
+	if err != nil {
+		return res, err
+	}
+	sort.Strings(files)
+	for _, filename := range files {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return res, err
+		}
+		src = skipLeadingUtf8Bom(src)
+		res.Files++
+		for i := 0; i < n; i++ {
+			var lines int
+			start := time.Now()
+			if newConfig == nil {
+				lines, err = scanAll(filename, src)
+			} else {
+				o := &options{statsHandler: func(st Stats) { lines = st.Lines }}
+				err = FatalOnly(readInto(newConfig(), filename, src, o))
+			}
+			res.Elapsed += time.Since(start)
+			if err != nil {
+				return res, err
+			}
+			res.Bytes += int64(len(src))
+			res.Lines += int64(lines)
+		}
+	}
+	return res, nil
+}
+
+// scanAll scans src, and returns the number of lines and the errors, if any.
+func scanAll(filename string, src []byte) (int, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile(filename, fset.Base(), len(src))
+	var s scanner.Scanner
+	var errs scanner.ErrorList
+	s.Init(file, src, func(p token.Position, m string) { errs.Add(p, m) },
+		scanner.ScanComments)
+	for {
+		if _, tok, _ := s.Scan(); tok == token.EOF {
+			break
+		}
+	}
+	return file.LineCount(), errs.Err()
+}
@@ -0,0 +1,58 @@
+package gcfg
+
+import (
+	"testing"
+	"time"
+)
+
+type cFleet struct {
+	Core struct {
+		Name     string
+		LogLevel string
+		Workers  int
+		Debug    bool
+	}
+	Node map[string]*struct {
+		Address     string
+		Weight      int
+		Enabled     bool
+		Description string
+		Tag         []string
+		Timeout     time.Duration
+	}
+	Route map[string]*struct {
+		Backend string
+		Retries int
+		Methods string
+	}
+}
+
+func TestMeasureThroughput(t *testing.T) {
+	for _, newConfig := range []func() interface{}{
+		nil,
+		func() interface{} { return &cFleet{} },
+	} {
+		res, err := MeasureThroughput("testdata/corpus", newConfig, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Files != 1 || res.Bytes < 2*50000 || res.Lines < 2*3000 ||
+			res.BytesPerSecond() <= 0 {
+			t.Errorf("got %+v", res)
+		}
+	}
+	if _, err := MeasureThroughput("testdata/corpus", func() interface{} {
+		return &struct{ Core struct{ Workers bool } }{}
+	}, 1); err == nil {
+		t.Errorf("got no error for mismatched config")
+	}
+}
+
+func BenchmarkCorpus(b *testing.B) {
+	res, err := MeasureThroughput("testdata/corpus",
+		func() interface{} { return &cFleet{} }, b.N)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(res.Bytes / int64(b.N))
+}
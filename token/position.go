@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"unicode/utf8"
 )
 
 // -----------------------------------------------------------------------------
@@ -23,7 +24,7 @@ type Position struct {
 	Filename string // filename, if any
 	Offset   int    // offset, starting at 0
 	Line     int    // line number, starting at 1
-	Column   int    // column number, starting at 1 (character count)
+	Column   int    // column number, starting at 1 (see ColumnMode)
 }
 
 // IsValid returns true if the position is valid.
@@ -96,9 +97,58 @@ type File struct {
 	base int    // Pos value range for this file is [base...base+size]
 	size int    // file size as provided to AddFile
 
-	// lines and infos are protected by set.mutex
+	// lines, infos, cols and src are protected by set.mutex
 	lines []int
 	infos []lineInfo
+	cols  ColumnMode
+	src   []byte // file content; only needed for non-default cols
+}
+
+// A ColumnMode specifies how column numbers of Positions are computed.
+// The zero value counts columns in bytes, with tabs counting as a single
+// byte.
+//
+type ColumnMode struct {
+	Runes    bool // count columns in characters (runes) rather than bytes
+	TabWidth int  // if > 0, tabs advance the column to the next tab stop
+}
+
+// column returns the column number of the character following line, where
+// line holds the contents of a line up to that character.
+func (m ColumnMode) column(line []byte) int {
+	col := 0
+	for len(line) > 0 {
+		w := 1
+		if m.Runes {
+			_, w = utf8.DecodeRune(line)
+		}
+		if m.TabWidth > 0 && line[0] == '\t' {
+			col += m.TabWidth - col%m.TabWidth
+		} else if m.Runes {
+			col++
+		} else {
+			col += w
+		}
+		line = line[w:]
+	}
+	return col + 1
+}
+
+// SetColumnMode sets the mode for computing column numbers of positions in
+// file f. As columns in other than the default mode depend on the contents of
+// the file, the content src is required; its size must match f.Size().
+//
+func (f *File) SetColumnMode(mode ColumnMode, src []byte) {
+	if len(src) != f.size {
+		panic(fmt.Sprintf("file size (%d) does not match src len (%d)", f.size, len(src)))
+	}
+	f.set.mutex.Lock()
+	f.cols = mode
+	f.src = src
+	if mode == (ColumnMode{}) {
+		f.src = nil
+	}
+	f.set.mutex.Unlock()
 }
 
 // Name returns the file name of file f as registered with AddFile.
@@ -245,6 +295,9 @@ func (f *File) info(offset int) (filename string, line, column int) {
 	filename = f.name
 	if i := searchInts(f.lines, offset); i >= 0 {
 		line, column = i+1, offset-f.lines[i]+1
+		if f.src != nil && offset <= len(f.src) {
+			column = f.cols.column(f.src[f.lines[i]:offset])
+		}
 	}
 	if len(f.infos) > 0 {
 		// almost no files have extra line infos
@@ -333,7 +386,7 @@ func (s *FileSet) AddFile(filename string, base, size int) *File {
 		panic("illegal base or size")
 	}
 	// base >= s.base && size >= 0
-	f := &File{s, filename, base, size, []int{0}, nil}
+	f := &File{set: s, name: filename, base: base, size: size, lines: []int{0}}
 	base += size + 1 // +1 because EOF also has a position
 	if base < 0 {
 		panic("token.Pos offset overflow (> 2G of source code in file set)")
@@ -179,3 +179,34 @@ func TestFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestColumnMode(t *testing.T) {
+	src := []byte("[sect]\n\tname=x\n  \tname=x\n甲=x\n\t甲\t=x")
+	for _, tt := range []struct {
+		mode ColumnMode
+		offs int
+		col  int
+	}{
+		{ColumnMode{}, 8, 2},
+		{ColumnMode{}, 18, 4},
+		{ColumnMode{}, 28, 4},
+		{ColumnMode{}, 36, 6},
+		{ColumnMode{TabWidth: 8}, 8, 9},
+		{ColumnMode{TabWidth: 8}, 18, 9},
+		{ColumnMode{TabWidth: 4}, 18, 5},
+		{ColumnMode{TabWidth: 4}, 36, 9},
+		{ColumnMode{Runes: true}, 28, 2},
+		{ColumnMode{Runes: true}, 35, 3},
+		{ColumnMode{Runes: true, TabWidth: 4}, 35, 6},
+		{ColumnMode{Runes: true, TabWidth: 4}, 36, 9},
+	} {
+		fset := NewFileSet()
+		f := fset.AddFile("", fset.Base(), len(src))
+		f.SetLinesForContent(src)
+		f.SetColumnMode(tt.mode, src)
+		if col := fset.Position(f.Pos(tt.offs)).Column; col != tt.col {
+			t.Errorf("%+v, offset %d: got column %d, want %d",
+				tt.mode, tt.offs, col, tt.col)
+		}
+	}
+}
@@ -30,7 +30,8 @@ func (s *FileSet) Read(decode func(interface{}) error) error {
 	files := make([]*File, len(ss.Files))
 	for i := 0; i < len(ss.Files); i++ {
 		f := &ss.Files[i]
-		files[i] = &File{s, f.Name, f.Base, f.Size, f.Lines, f.Infos}
+		files[i] = &File{set: s, name: f.Name, base: f.Base, size: f.Size,
+			lines: f.Lines, infos: f.Infos}
 	}
 	s.files = files
 	s.last = nil
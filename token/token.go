@@ -32,8 +32,10 @@ const (
 	operator_beg
 	// Operators and delimiters
 	ASSIGN // =
+	ADD    // +=
 	LBRACK // [
 	RBRACK // ]
+	PERIOD // .
 	EOL    // \n
 	operator_end
 )
@@ -48,8 +50,10 @@ var tokens = [...]string{
 	STRING: "STRING",
 
 	ASSIGN: "=",
+	ADD:    "+=",
 	LBRACK: "[",
 	RBRACK: "]",
+	PERIOD: ".",
 	EOL:    "\n",
 }
 
@@ -0,0 +1,57 @@
+package gcfg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// transforms holds the named transforms for the ",transform=list" struct tag
+// option, including the predefined ones.
+var transforms = struct {
+	sync.RWMutex
+	m map[string]func(string) (string, error)
+}{m: map[string]func(string) (string, error){
+	"trim":  func(s string) (string, error) { return strings.TrimSpace(s), nil },
+	"lower": func(s string) (string, error) { return strings.ToLower(s), nil },
+	"upper": func(s string) (string, error) { return strings.ToUpper(s), nil },
+}}
+
+// RegisterTransform registers fn as the transform named name, for use with the
+// ",transform=list" struct tag option; fn returns the transformed value, or an
+// error if the value is invalid. The predefined transforms are "trim" (which
+// removes leading and trailing white space, e.g. from within quotes), "lower"
+// and "upper". RegisterTransform is typically called from an init function;
+// it panics if name is empty, contains '|' or ',', or is already registered.
+func RegisterTransform(name string, fn func(string) (string, error)) {
+	if name == "" || strings.ContainsAny(name, "|,") {
+		panic(fmt.Errorf("invalid transform name %q", name))
+	}
+	transforms.Lock()
+	defer transforms.Unlock()
+	if _, ok := transforms.m[name]; ok {
+		panic(fmt.Errorf("transform already registered: transform %q", name))
+	}
+	transforms.m[name] = fn
+}
+
+// transform applies the transforms in the tag t to value, in order; l is the
+// location of the definition. It panics if a transform is not registered.
+func transform(value string, t tag, l loc) (string, error) {
+	for _, name := range t.transforms {
+		// don't hold the lock while calling fn, which may register
+		// transforms itself
+		transforms.RLock()
+		fn, ok := transforms.m[name]
+		transforms.RUnlock()
+		if !ok {
+			panic(fmt.Errorf("unknown transform %q: section %q, variable %q",
+				name, l.section, *l.variable))
+		}
+		var err error
+		if value, err = fn(value); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
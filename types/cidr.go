@@ -0,0 +1,93 @@
+package types
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// CIDRList is a list of IP address prefixes (in CIDR notation), such as an
+// allowlist or a denylist. It implements encoding.TextUnmarshaler, so it can
+// be used directly in gcfg config structs.
+//
+// Each value is a list of prefixes separated by commas and/or spaces; e.g.
+// "10.0.0.0/8, 192.168.1.0/24". A single address is treated as a prefix
+// containing only that address (e.g. "10.1.2.3" as "10.1.2.3/32"). As
+// UnmarshalText appends to the list, a variable of type CIDRList can be
+// defined multiple times to specify the prefixes one per line, like a
+// multi-valued variable.
+type CIDRList []netip.Prefix
+
+// ParseCIDRList parses a list of prefixes separated by commas and/or spaces.
+func ParseCIDRList(s string) (CIDRList, error) {
+	var l CIDRList
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		var p netip.Prefix
+		var err error
+		if strings.Contains(f, "/") {
+			p, err = netip.ParsePrefix(f)
+		} else {
+			var a netip.Addr
+			if a, err = netip.ParseAddr(f); err == nil {
+				p = netip.PrefixFrom(a, a.BitLen())
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CIDRList %#q: %v", s, err)
+		}
+		l = append(l, p.Masked())
+	}
+	return l, nil
+}
+
+// Contains reports whether ip is in any of the prefixes in l.
+func (l CIDRList) Contains(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range l {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlap returns the first pair of prefixes in l that overlap (that is, that
+// have any addresses in common), if any.
+func (l CIDRList) Overlap() (a, b netip.Prefix, ok bool) {
+	for i, p := range l {
+		for _, q := range l[i+1:] {
+			if p.Overlaps(q) {
+				return p, q, true
+			}
+		}
+	}
+	return netip.Prefix{}, netip.Prefix{}, false
+}
+
+// String returns the prefixes in l separated by commas; e.g.
+// "10.0.0.0/8, 192.168.1.0/24".
+func (l CIDRList) String() string {
+	s := make([]string, len(l))
+	for i, p := range l {
+		s[i] = p.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler; the prefixes parsed from
+// text are appended to l.
+func (l *CIDRList) UnmarshalText(text []byte) error {
+	pl, err := ParseCIDRList(string(text))
+	if err != nil {
+		return err
+	}
+	*l = append(*l, pl...)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l CIDRList) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
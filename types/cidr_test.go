@@ -0,0 +1,79 @@
+package types
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseCIDRList(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		exp string
+		ok  bool
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8", true},
+		{"10.0.0.0/8, 192.168.1.0/24", "10.0.0.0/8, 192.168.1.0/24", true},
+		{"10.0.0.0/8 192.168.1.0/24,fd00::/8", "10.0.0.0/8, 192.168.1.0/24, fd00::/8", true},
+		{"10.1.2.3", "10.1.2.3/32", true},
+		{"::1", "::1/128", true},
+		{"10.1.2.3/8", "10.0.0.0/8", true},
+		{"", "", true},
+		{"10.0.0.0/33", "", false},
+		{"10.0.0", "", false},
+		{"example.com", "", false},
+	} {
+		l, err := ParseCIDRList(tt.val)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseCIDRList(%q): got error %v, wanted ok %v",
+				tt.val, err, tt.ok)
+			continue
+		}
+		if tt.ok && l.String() != tt.exp {
+			t.Errorf("ParseCIDRList(%q): got %q, wanted %q", tt.val, l, tt.exp)
+		}
+	}
+}
+
+func TestCIDRListContains(t *testing.T) {
+	l, _ := ParseCIDRList("10.0.0.0/8, fd00::/8, 192.168.1.1")
+	for _, tt := range []struct {
+		ip  string
+		exp bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"fd12::1", true},
+		{"fe80::1", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+	} {
+		if c := l.Contains(netip.MustParseAddr(tt.ip)); c != tt.exp {
+			t.Errorf("Contains(%s): got %v, wanted %v", tt.ip, c, tt.exp)
+		}
+	}
+}
+
+func TestCIDRListOverlap(t *testing.T) {
+	l, _ := ParseCIDRList("10.0.0.0/8, 192.168.0.0/16")
+	if _, _, ok := l.Overlap(); ok {
+		t.Errorf("%s: got overlap", l)
+	}
+	l, _ = ParseCIDRList("10.0.0.0/8, 192.168.0.0/16, 10.1.0.0/16")
+	a, b, ok := l.Overlap()
+	if !ok || a.String() != "10.0.0.0/8" || b.String() != "10.1.0.0/16" {
+		t.Errorf("%s: got %v, %v, %v", l, a, b, ok)
+	}
+}
+
+func TestCIDRListUnmarshalText(t *testing.T) {
+	var l CIDRList
+	for _, s := range []string{"10.0.0.0/8", "192.168.0.0/16"} {
+		if err := l.UnmarshalText([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp := "10.0.0.0/8, 192.168.0.0/16"; l.String() != exp {
+		t.Errorf("got %q, wanted %q", l, exp)
+	}
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FileMode holds file permission bits, as in fs.FileMode (and os.FileMode).
+// Unlike fs.FileMode, whose values are parsed as decimal by default, it is
+// parsed as octal, with or without a leading zero (e.g. "644" or "0644"), or
+// as a symbolic mode, as accepted by chmod (e.g. "u=rw,go=r"). It implements
+// encoding.TextUnmarshaler, so it can be used directly in gcfg config structs.
+type FileMode fs.FileMode
+
+// ParseFileMode parses the permission bits in s, either as an octal number or
+// as a symbolic mode. A symbolic mode is a comma-separated list of clauses,
+// each consisting of zero or more of the letters 'u', 'g', 'o' and 'a' (user,
+// group, others and all, the default), an operator ('+', '-' or '='), and zero
+// or more of the letters 'r', 'w' and 'x'; the clauses are applied in order,
+// starting from no permissions.
+func ParseFileMode(s string) (FileMode, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		n, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || n > 0777 {
+			return 0, fmt.Errorf("failed to parse FileMode %#q", s)
+		}
+		return FileMode(n), nil
+	}
+	m, err := parseSymbolicMode(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse FileMode %#q: %v", s, err)
+	}
+	return m, nil
+}
+
+func parseSymbolicMode(s string) (FileMode, error) {
+	var m FileMode
+	for _, clause := range strings.Split(s, ",") {
+		i := strings.IndexAny(clause, "+-=")
+		if i < 0 {
+			return 0, fmt.Errorf("missing operator in %#q", clause)
+		}
+		var who FileMode
+		for _, c := range clause[:i] {
+			switch c {
+			case 'u':
+				who |= 0700
+			case 'g':
+				who |= 0070
+			case 'o':
+				who |= 0007
+			case 'a':
+				who |= 0777
+			default:
+				return 0, fmt.Errorf("invalid user class %q", c)
+			}
+		}
+		if who == 0 {
+			who = 0777
+		}
+		var perm FileMode
+		for _, c := range clause[i+1:] {
+			switch c {
+			case 'r':
+				perm |= 0444
+			case 'w':
+				perm |= 0222
+			case 'x':
+				perm |= 0111
+			default:
+				return 0, fmt.Errorf("invalid permission %q", c)
+			}
+		}
+		switch clause[i] {
+		case '+':
+			m |= who & perm
+		case '-':
+			m &^= who & perm
+		case '=':
+			m = m&^who | who&perm
+		}
+	}
+	return m, nil
+}
+
+// Mode returns m as an fs.FileMode.
+func (m FileMode) Mode() fs.FileMode { return fs.FileMode(m) }
+
+// String returns m in octal, with a leading zero; e.g. "0644".
+func (m FileMode) String() string {
+	return fmt.Sprintf("%#o", uint32(m))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *FileMode) UnmarshalText(text []byte) error {
+	pm, err := ParseFileMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = pm
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m FileMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
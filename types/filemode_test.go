@@ -0,0 +1,44 @@
+package types
+
+import "testing"
+
+func TestParseFileMode(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		exp FileMode
+		ok  bool
+	}{
+		{"644", 0644, true},
+		{"0644", 0644, true},
+		{"0", 0, true},
+		{"777", 0777, true},
+		{"1777", 0, false},
+		{"8", 0, false},
+		{"u=rw,go=r", 0644, true},
+		{"a=rx,u+w", 0755, true},
+		{"+x", 0111, true},
+		{"ug=rwx,o=", 0770, true},
+		{"a=rwx,g-w,o-rwx", 0750, true},
+		{"u=rw,a-w", 0400, true},
+		{"rw", 0, false},
+		{"u=q", 0, false},
+		{"z=r", 0, false},
+		{"", 0, false},
+	} {
+		m, err := ParseFileMode(tt.val)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseFileMode(%q): got error %v, wanted ok %v",
+				tt.val, err, tt.ok)
+			continue
+		}
+		if tt.ok && m != tt.exp {
+			t.Errorf("ParseFileMode(%q): got %v, wanted %v", tt.val, m, tt.exp)
+		}
+	}
+}
+
+func TestFileModeString(t *testing.T) {
+	if s := FileMode(0644).String(); s != "0644" {
+		t.Errorf("got %q, wanted %q", s, "0644")
+	}
+}
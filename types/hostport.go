@@ -0,0 +1,67 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostPort is a network address consisting of a host and a port, such as
+// "example.com:80" or "[::1]:8080". It implements encoding.TextUnmarshaler, so
+// it can be used directly in gcfg config structs, including in multi-valued
+// variables (e.g. for a list of cluster members).
+type HostPort struct {
+	Host string // host name or IP address; may be empty
+	Port uint16
+}
+
+// ParseHostPort parses a network address of the form "host:port",
+// "[host]:port" or ":port", as accepted by net.SplitHostPort; the port must
+// be numeric. If defaultPort is not empty, the port may be omitted (e.g.
+// "example.com", "::1" or "[::1]"), in which case defaultPort is used.
+func ParseHostPort(s, defaultPort string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil && defaultPort != "" {
+		h := s
+		if strings.HasPrefix(h, "[") && strings.HasSuffix(h, "]") {
+			h = h[1 : len(h)-1]
+		}
+		host, port, err = net.SplitHostPort(net.JoinHostPort(h, defaultPort))
+	}
+	if err != nil {
+		return HostPort{}, fmt.Errorf("failed to parse HostPort %#q: %v", s, err)
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("failed to parse HostPort %#q: "+
+			"invalid port %#q", s, port)
+	}
+	return HostPort{Host: host, Port: uint16(n)}, nil
+}
+
+// String returns the address in the form accepted by ParseHostPort, as
+// returned by net.JoinHostPort; e.g. "example.com:80" or "[::1]:8080".
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The port is required;
+// in gcfg config structs, a default port can be set using the ",port=number"
+// struct tag option.
+func (hp *HostPort) UnmarshalText(text []byte) error {
+	php, err := ParseHostPort(string(text), "")
+	if err != nil {
+		return err
+	}
+	*hp = php
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (hp HostPort) MarshalText() ([]byte, error) {
+	return []byte(hp.String()), nil
+}
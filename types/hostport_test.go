@@ -0,0 +1,52 @@
+package types
+
+import "testing"
+
+func TestParseHostPort(t *testing.T) {
+	for _, tt := range []struct {
+		val, dflt string
+		exp       HostPort
+		ok        bool
+	}{
+		{"example.com:80", "", HostPort{"example.com", 80}, true},
+		{"[::1]:8080", "", HostPort{"::1", 8080}, true},
+		{":53", "", HostPort{"", 53}, true},
+		{"example.com", "", HostPort{}, false},
+		{"example.com:http", "", HostPort{}, false},
+		{"example.com:65536", "", HostPort{}, false},
+		{"::1", "", HostPort{}, false},
+		{"example.com", "80", HostPort{"example.com", 80}, true},
+		{"example.com:", "80", HostPort{"example.com", 80}, true},
+		{"example.com:81", "80", HostPort{"example.com", 81}, true},
+		{"::1", "80", HostPort{"::1", 80}, true},
+		{"[::1]", "80", HostPort{"::1", 80}, true},
+		{"[::1]:81", "80", HostPort{"::1", 81}, true},
+		{"a:b:c", "x", HostPort{}, false},
+	} {
+		hp, err := ParseHostPort(tt.val, tt.dflt)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseHostPort(%q, %q): got error %v, wanted ok %v",
+				tt.val, tt.dflt, err, tt.ok)
+			continue
+		}
+		if tt.ok && hp != tt.exp {
+			t.Errorf("ParseHostPort(%q, %q): got %+v, wanted %+v",
+				tt.val, tt.dflt, hp, tt.exp)
+		}
+	}
+}
+
+func TestHostPortString(t *testing.T) {
+	for _, tt := range []struct {
+		hp  HostPort
+		exp string
+	}{
+		{HostPort{"example.com", 80}, "example.com:80"},
+		{HostPort{"::1", 8080}, "[::1]:8080"},
+		{HostPort{"", 53}, ":53"},
+	} {
+		if s := tt.hp.String(); s != tt.exp {
+			t.Errorf("%+v: got %q, wanted %q", tt.hp, s, tt.exp)
+		}
+	}
+}
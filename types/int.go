@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"math/big"
+	"reflect"
 	"strings"
 )
 
@@ -82,5 +84,41 @@ func ParseInt(intptr interface{}, val string, mode IntMode) error {
 	if verb == 0 {
 		panic("unsupported mode")
 	}
-	return ScanFully(intptr, val, verb)
+	err := ScanFully(intptr, val, verb)
+	if err != nil {
+		if rerr := rangeError(intptr, val, verb); rerr != nil {
+			return rerr
+		}
+	}
+	return err
+}
+
+// rangeError returns an error describing the valid range if val is a valid
+// integer that is out of range for the fixed-size integer type intptr points
+// to, or nil otherwise.
+func rangeError(intptr interface{}, val string, verb byte) error {
+	t := reflect.TypeOf(intptr).Elem()
+	var min, max big.Int
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		max.Lsh(big.NewInt(1), uint(t.Bits()-1))
+		min.Neg(&max)
+		max.Sub(&max, big.NewInt(1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		max.Lsh(big.NewInt(1), uint(t.Bits()))
+		max.Sub(&max, big.NewInt(1))
+	default:
+		return nil
+	}
+	var v big.Int
+	if ScanFully(&v, val, verb) != nil {
+		return nil // not a valid integer
+	}
+	if v.Cmp(&min) >= 0 && v.Cmp(&max) <= 0 {
+		return nil
+	}
+	return fmt.Errorf("value %q out of range for %v: must be between %s and %s",
+		val, t, &min, &max)
 }
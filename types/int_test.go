@@ -2,6 +2,7 @@ package types
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -65,3 +66,27 @@ func TestParseInt(t *testing.T) {
 		}
 	}
 }
+
+func TestParseIntRange(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		ptr interface{}
+		exp string
+	}{
+		{"128", new(int8), `value "128" out of range for int8: must be between -128 and 127`},
+		{"-129", new(int8), `value "-129" out of range for int8: must be between -128 and 127`},
+		{"0x10000", new(uint16), `value "0x10000" out of range for uint16: must be between 0 and 65535`},
+		{"-1", new(uint8), `value "-1" out of range for uint8: must be between 0 and 255`},
+		{"18446744073709551616", new(uint64), `value "18446744073709551616" out of range for uint64: must be between 0 and 18446744073709551615`},
+	} {
+		err := ParseInt(tt.ptr, tt.val, Dec|Hex)
+		if err == nil || err.Error() != tt.exp {
+			t.Errorf("ParseInt(%T, %q): got error %v, wanted %q", tt.ptr,
+				tt.val, err, tt.exp)
+		}
+	}
+	if err := ParseInt(new(int8), "x", Dec); err == nil ||
+		strings.Contains(err.Error(), "out of range") {
+		t.Errorf("ParseInt(int8, \"x\"): got error %v", err)
+	}
+}
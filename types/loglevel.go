@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LogLevel is a logging level; its values are the same as those of
+// slog.Level, so that it can be converted to (and from) slog.Level directly.
+// It implements slog.Leveler, and encoding.TextUnmarshaler so it can be used
+// directly in gcfg config structs.
+type LogLevel slog.Level
+
+// LogLevel values.
+const (
+	LogDebug = LogLevel(slog.LevelDebug)
+	LogInfo  = LogLevel(slog.LevelInfo)
+	LogWarn  = LogLevel(slog.LevelWarn)
+	LogError = LogLevel(slog.LevelError)
+)
+
+// LogLevelValues defines the name and value mappings for ParseLogLevel,
+// including aliases.
+var LogLevelValues = map[string]interface{}{
+	"debug": LogDebug,
+	"info":  LogInfo,
+	"warn":  LogWarn, "warning": LogWarn,
+	"error": LogError, "err": LogError,
+}
+
+var logLevelParser = func() *EnumParser {
+	ep := &EnumParser{}
+	ep.AddVals(LogLevelValues)
+	return ep
+}()
+
+// ParseLogLevel parses a logging level according to the definitions in
+// LogLevelValues. Parsing is case-insensitive. Levels between the named ones
+// can be specified as offsets, as accepted by slog.Level.UnmarshalText; e.g.
+// "info+2".
+func ParseLogLevel(s string) (LogLevel, error) {
+	if v, err := logLevelParser.Parse(s); err == nil {
+		return v.(LogLevel), nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("failed to parse LogLevel %#q", s)
+	}
+	return LogLevel(l), nil
+}
+
+// Level returns l as a slog.Level; it implements slog.Leveler.
+func (l LogLevel) Level() slog.Level { return slog.Level(l) }
+
+// String returns the name of the level in lower case; e.g. "info", or
+// "info+2" for levels between the named ones.
+func (l LogLevel) String() string {
+	b, _ := l.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	pl, err := ParseLogLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = pl
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(slog.Level(l).String())), nil
+}
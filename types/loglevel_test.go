@@ -0,0 +1,55 @@
+package types
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		exp slog.Level
+		ok  bool
+	}{
+		{"debug", slog.LevelDebug, true},
+		{"Info", slog.LevelInfo, true},
+		{"WARN", slog.LevelWarn, true},
+		{"warning", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"err", slog.LevelError, true},
+		{"info+2", slog.LevelInfo + 2, true},
+		{"ERROR-1", slog.LevelError - 1, true},
+		{"verbose", 0, false},
+		{"", 0, false},
+	} {
+		l, err := ParseLogLevel(tt.val)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseLogLevel(%q): got error %v, wanted ok %v",
+				tt.val, err, tt.ok)
+			continue
+		}
+		if tt.ok && l.Level() != tt.exp {
+			t.Errorf("ParseLogLevel(%q): got %v, wanted %v", tt.val, l, tt.exp)
+		}
+	}
+}
+
+func TestLogLevelString(t *testing.T) {
+	for _, tt := range []struct {
+		l   LogLevel
+		exp string
+	}{
+		{LogDebug, "debug"},
+		{LogInfo, "info"},
+		{LogWarn, "warn"},
+		{LogError, "error"},
+		{LogInfo + 2, "info+2"},
+	} {
+		if s := tt.l.String(); s != tt.exp {
+			t.Errorf("%d: got %q, wanted %q", tt.l, s, tt.exp)
+		}
+		if l, err := ParseLogLevel(tt.l.String()); err != nil || l != tt.l {
+			t.Errorf("%d: parsing back: got %d, %v", tt.l, l, err)
+		}
+	}
+}
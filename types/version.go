@@ -0,0 +1,179 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version (see https://semver.org), such as
+// "1.2.3-rc.1+build.5". It implements encoding.TextUnmarshaler, so it can be
+// used directly in gcfg config structs.
+type Version struct {
+	Major, Minor, Patch uint64
+	Pre                 []string // pre-release identifiers; e.g. ["rc", "1"]
+	Build               []string // build metadata identifiers; e.g. ["build", "5"]
+}
+
+// ParseVersion parses a semantic version string. A leading "v" is allowed and
+// ignored; e.g. "v1.2.3" is the same as "1.2.3".
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	str := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		build := str[i+1:]
+		str = str[:i]
+		var err error
+		if v.Build, err = parseIdents(build, false); err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: build %v", s, err)
+		}
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		pre := str[i+1:]
+		str = str[:i]
+		var err error
+		if v.Pre, err = parseIdents(pre, true); err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: pre-release %v",
+				s, err)
+		}
+	}
+	nums := strings.Split(str, ".")
+	if len(nums) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: must have the form "+
+			"MAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		if !isNum(nums[i]) || len(nums[i]) > 1 && nums[i][0] == '0' {
+			return Version{}, fmt.Errorf("invalid version %q: invalid number %q",
+				s, nums[i])
+		}
+		n, err := strconv.ParseUint(nums[i], 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %v", s, err)
+		}
+		*p = n
+	}
+	return v, nil
+}
+
+// parseIdents parses the dot-separated identifiers of a pre-release or build
+// metadata; numeric identifiers in pre-releases must not have leading zeros.
+func parseIdents(s string, pre bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("has empty identifier")
+		}
+		for _, r := range id {
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' ||
+				'A' <= r && r <= 'Z' || r == '-') {
+				return nil, fmt.Errorf("identifier %q has invalid character %q",
+					id, r)
+			}
+		}
+		if pre && isNum(id) && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("identifier %q has leading zero", id)
+		}
+	}
+	return ids, nil
+}
+
+func isNum(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// String returns the version in its canonical form (without a "v" prefix).
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is lower than, equal to,
+// or higher than w, according to semantic versioning precedence rules. Build
+// metadata is ignored.
+func (v Version) Compare(w Version) int {
+	for _, c := range [...]struct{ a, b uint64 }{
+		{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch},
+	} {
+		switch {
+		case c.a < c.b:
+			return -1
+		case c.a > c.b:
+			return +1
+		}
+	}
+	// a version without pre-release has higher precedence
+	switch {
+	case len(v.Pre) == 0 && len(w.Pre) == 0:
+		return 0
+	case len(v.Pre) == 0:
+		return +1
+	case len(w.Pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Pre) && i < len(w.Pre); i++ {
+		if c := compareIdent(v.Pre[i], w.Pre[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.Pre) < len(w.Pre):
+		return -1
+	case len(v.Pre) > len(w.Pre):
+		return +1
+	}
+	return 0
+}
+
+// compareIdent compares pre-release identifiers: numeric identifiers are
+// compared numerically and have lower precedence than alphanumeric ones,
+// which are compared lexically.
+func compareIdent(a, b string) int {
+	an, bn := isNum(a), isNum(b)
+	switch {
+	case an && bn:
+		if len(a) != len(b) { // no leading zeros
+			if len(a) < len(b) {
+				return -1
+			}
+			return +1
+		}
+	case an:
+		return -1
+	case bn:
+		return +1
+	}
+	return strings.Compare(a, b)
+}
+
+// Less reports whether v has lower precedence than w.
+func (v Version) Less(w Version) bool { return v.Compare(w) < 0 }
+
+// AtLeast reports whether v has the same or higher precedence than w.
+func (v Version) AtLeast(w Version) bool { return v.Compare(w) >= 0 }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Version) UnmarshalText(text []byte) error {
+	pv, err := ParseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = pv
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
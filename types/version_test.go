@@ -0,0 +1,74 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		exp Version
+		ok  bool
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+		{"0.0.0", Version{}, true},
+		{"1.2.3-rc.1", Version{1, 2, 3, []string{"rc", "1"}, nil}, true},
+		{"1.2.3+build.05", Version{1, 2, 3, nil, []string{"build", "05"}}, true},
+		{"1.2.3-x-y.0+z", Version{1, 2, 3, []string{"x-y", "0"}, []string{"z"}}, true},
+		{"1.2", Version{}, false},
+		{"1.2.3.4", Version{}, false},
+		{"1.02.3", Version{}, false},
+		{"1.2.x", Version{}, false},
+		{"1.2.-3", Version{}, false},
+		{"1.2.3-", Version{}, false},
+		{"1.2.3-rc..1", Version{}, false},
+		{"1.2.3-01", Version{}, false},
+		{"1.2.3+b_1", Version{}, false},
+		{"", Version{}, false},
+	} {
+		v, err := ParseVersion(tt.val)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseVersion(%q): got error %v, wanted ok %v",
+				tt.val, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(v, tt.exp) {
+			t.Errorf("ParseVersion(%q): got %#v, wanted %#v", tt.val, v, tt.exp)
+		}
+		if s := v.String(); tt.ok && s != tt.val && "v"+s != tt.val {
+			t.Errorf("ParseVersion(%q).String(): got %q", tt.val, s)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// in increasing order of precedence
+	vs := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1",
+		"1.1.0", "2.0.0", "10.0.0",
+	}
+	for i, a := range vs {
+		for j, b := range vs {
+			va, _ := ParseVersion(a)
+			vb, _ := ParseVersion(b)
+			exp := 0
+			switch {
+			case i < j:
+				exp = -1
+			case i > j:
+				exp = +1
+			}
+			if c := va.Compare(vb); c != exp {
+				t.Errorf("%s.Compare(%s): got %d, wanted %d", a, b, c, exp)
+			}
+		}
+	}
+	va, _ := ParseVersion("1.0.0+a")
+	vb, _ := ParseVersion("1.0.0+b")
+	if c := va.Compare(vb); c != 0 {
+		t.Errorf("build metadata: got %d, wanted 0", c)
+	}
+}
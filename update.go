@@ -0,0 +1,274 @@
+package gcfg
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/gcfg.v1/ast"
+	"gopkg.in/gcfg.v1/parser"
+	"gopkg.in/gcfg.v1/token"
+)
+
+// UpdateFile sets the variable name in the section and subsection sub ("" for
+// none) of the gcfg file filename to value, like `git config --file`: only
+// the value of the last definition of the variable is rewritten, leaving all
+// other bytes of the file (comments, whitespace and other definitions)
+// untouched. If the variable is not defined, a definition is added after the
+// last variable (or the header) of the last occurrence of the section, which
+// is added at the end of the file if it doesn't exist.
+//
+// The name of a dotted or indexed variable includes the key (e.g.
+// "labels.team"; see the package documentation). The value is quoted as
+// needed. If the file can't be parsed, it is left unchanged and the syntax
+// errors are returned (as for Parse). The file is replaced atomically, keeping
+// its permissions, so that it is never seen partially written.
+func UpdateFile(filename, section, sub, name, value string) error {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	res, err := update(filename, src, section, sub, name, value)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, res, fi.Mode().Perm())
+}
+
+// writeFile replaces the file filename with data atomically, by writing it to
+// a temporary file in the same directory and renaming that over filename.
+func writeFile(filename string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// update returns src with the variable name in the section and subsection set
+// to value; see UpdateFile.
+func update(filename string, src []byte, section, sub, name,
+	value string) ([]byte, error) {
+	//
+	l := loc{section: section, variable: &name}
+	if sub != "" {
+		l.subsection = &sub
+	}
+	if !isName(section) || !isVarName(name) {
+		return nil, locErr{err: errInvalidName, loc: l}
+	}
+	qv, err := quoteValue(value)
+	if err != nil {
+		return nil, locErr{err: err, loc: l}
+	}
+	f, off, err := parseSrc(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	sect, defs := findDefs(f, section, sub, name)
+	var def *ast.Variable
+	if len(defs) > 0 {
+		def = defs[len(defs)-1]
+	}
+	var b bytes.Buffer
+	switch {
+	case def != nil && def.Value != nil:
+		b.Write(src[:off(def.Value.Pos())])
+		b.WriteString(qv)
+		b.Write(src[off(def.Value.End()):])
+	case def != nil:
+		// "blank" value
+		b.Write(src[:off(def.End())])
+		b.WriteString(" = " + qv)
+		b.Write(src[off(def.End()):])
+	case sect != nil:
+		// add after the line of the end of the section, with the
+		// indentation of its last variable (if any)
+		end := lineEnd(src, off(sect.End()))
+		indent := "\t"
+		if n := len(sect.Vars); n > 0 {
+			start := lineStart(src, off(sect.Vars[n-1].Pos()))
+			indent = string(src[start:off(sect.Vars[n-1].Pos())])
+		}
+		b.Write(src[:end])
+		if end == len(src) && (end == 0 || src[end-1] != '\n') {
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + name + " = " + qv + "\n")
+		b.Write(src[end:])
+	default:
+		b.Write(src)
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+		if len(f.Sections) > 0 && f.Blank == 0 {
+			b.WriteByte('\n')
+		}
+		e := NewEncoder(&b)
+		if err := e.writeHeader(section, l.subsection, false); err != nil {
+			return nil, err
+		}
+		b.WriteString("\t" + name + " = " + qv + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+// UnsetInFile removes all the definitions of the variable key in the gcfg file
+// filename, where key is as for SetInFile, like `git config --file
+// --unset-all`: the lines of the definitions are removed, leaving all other
+// bytes of the file (including the section, even if it becomes empty)
+// untouched. The file is not written if the variable is not defined, and is
+// otherwise replaced atomically as by UpdateFile.
+func UnsetInFile(filename, key string) error {
+	section, sub, name, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	f, off, err := parseSrc(filename, src)
+	if err != nil {
+		return err
+	}
+	_, defs := findDefs(f, section, sub, name)
+	if len(defs) == 0 {
+		return nil
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	start := 0
+	for _, v := range defs {
+		b.Write(src[start:lineStart(src, off(v.Pos()))])
+		start = lineEnd(src, off(v.End()))
+	}
+	b.Write(src[start:])
+	return writeFile(filename, b.Bytes(), fi.Mode().Perm())
+}
+
+// SetInFile is like UpdateFile, with the variable given by a key as used by
+// git config: "section.name", or "section.subsection.name" for a section with
+// a subsection (which may contain dots); e.g. "remote.origin.url". As the
+// variable name is the part after the last dot, dotted and indexed variables
+// can only be set using UpdateFile.
+func SetInFile(filename, key, value string) error {
+	section, sub, name, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	return UpdateFile(filename, section, sub, name, value)
+}
+
+// splitKey splits a key as used by SetInFile into the section, subsection and
+// variable names.
+func splitKey(key string) (section, sub, name string, err error) {
+	i, j := strings.IndexByte(key, '.'), strings.LastIndexByte(key, '.')
+	if i < 0 {
+		return "", "", "", fmt.Errorf("invalid key %q (expected "+
+			"section.name or section.subsection.name)", key)
+	}
+	section, name = key[:i], key[j+1:]
+	if i < j {
+		sub = key[i+1 : j]
+	}
+	return section, sub, name, nil
+}
+
+// parseSrc parses src (the contents of the file filename) for editing it,
+// and returns the syntax tree and a function returning the offsets in src of
+// the positions in it.
+func parseSrc(filename string, src []byte) (*ast.File, func(token.Pos) int,
+	error) {
+	//
+	// positions are offsets in src from base, after the BOM if any
+	fset := token.NewFileSet()
+	base := fset.Base() - (len(src) - len(skipLeadingUtf8Bom(src)))
+	f, err := parser.ParseFile(fset, filename, skipLeadingUtf8Bom(src), 0)
+	if err != nil {
+		return nil, nil, syntaxErrs(err)
+	}
+	return f, func(p token.Pos) int { return int(p) - base }, nil
+}
+
+// findDefs returns the last occurrence of the section and subsection in f (nil
+// if none), and the definitions of the variable name in all its occurrences.
+func findDefs(f *ast.File, section, sub, name string) (*ast.Section,
+	[]*ast.Variable) {
+	//
+	var sect *ast.Section
+	var defs []*ast.Variable
+	for _, s := range f.Sections {
+		if !strings.EqualFold(s.Name.Name, section) ||
+			s.Sub == nil && sub != "" ||
+			s.Sub != nil && unquote(s.Sub.Value) != sub {
+			continue
+		}
+		sect = s
+		for _, v := range s.Vars {
+			n := v.Name.Name
+			if v.Key != nil {
+				n += "." + v.Key.Name
+			}
+			if varNameEqual(n, name) {
+				defs = append(defs, v)
+			}
+		}
+	}
+	return sect, defs
+}
+
+// lineStart returns the offset of the start of the line containing offs.
+func lineStart(src []byte, offs int) int {
+	return bytes.LastIndexByte(src[:offs], '\n') + 1
+}
+
+// lineEnd returns the offset after the end of the line (including the
+// newline) containing offs; len(src) on the last line.
+func lineEnd(src []byte, offs int) int {
+	if i := bytes.IndexByte(src[offs:], '\n'); i >= 0 {
+		return offs + i + 1
+	}
+	return len(src)
+}
+
+// varNameEqual reports whether the variable names a and b (including their
+// keys, if any) are equal: the names case-insensitively, and the keys
+// case-sensitively.
+func varNameEqual(a, b string) bool {
+	an, ak, _ := strings.Cut(a, ".")
+	bn, bk, _ := strings.Cut(b, ".")
+	return strings.EqualFold(an, bn) && ak == bk
+}
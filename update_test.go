@@ -0,0 +1,167 @@
+package gcfg
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateFile(t *testing.T) {
+	const src = `; config
+[core]
+  name = old ; comment
+  multi = 1
+  multi = 2
+  flag
+  idx[0] = x
+
+[remote "origin"]
+	url = "a b"
+
+[core]
+	; trailing
+`
+	for _, tt := range []struct {
+		desc            string
+		sect, sub, name string
+		value           string
+		old, new        string // the change to src
+	}{
+		{"replace", "Core", "", "NAME", "new value",
+			"name = old", "name = new value"},
+		{"replace last", "core", "", "multi", "3",
+			"multi = 2", "multi = 3"},
+		{"blank", "core", "", "flag", "true", "flag\n", "flag = true\n"},
+		{"key", "core", "", "idx.0", "y", "idx[0] = x", "idx[0] = y"},
+		{"quote", "remote", "origin", "url", "x;y", `"a b"`, `"x;y"`},
+		{"add to last section", "core", "", "new", "1",
+			"[core]\n\t;", "[core]\n\tnew = 1\n\t;"},
+		{"add to section", "remote", "origin", "push", "z",
+			"url = \"a b\"\n", "url = \"a b\"\n\tpush = z\n"},
+		{"add section", "remote", "Upstream", "url", "c",
+			"; trailing\n", "; trailing\n\n[remote \"Upstream\"]\n\turl = c\n"},
+	} {
+		fn := filepath.Join(t.TempDir(), "config")
+		if err := ioutil.WriteFile(fn, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateFile(fn, tt.sect, tt.sub, tt.name, tt.value); err != nil {
+			t.Errorf("%s: %v", tt.desc, err)
+			continue
+		}
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := strings.Replace(src, tt.old, tt.new, 1); string(b) != exp {
+			t.Errorf("%s: got\n%s\nexpected\n%s", tt.desc, b, exp)
+		}
+		f, err := Parse(b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if v, _ := f.Get(tt.sect, tt.sub, tt.name); v != tt.value {
+			t.Errorf("%s: got value %q, expected %q", tt.desc, v, tt.value)
+		}
+	}
+
+	fn := filepath.Join(t.TempDir(), "config")
+	for _, tt := range []struct {
+		src string
+		err error
+		exp string
+	}{
+		{"", nil, "[a]\n\tb = c\n"},
+		{"[a]", nil, "[a]\n\tb = c\n"},
+		{"[x]\n  y = z", nil, "[x]\n  y = z\n\n[a]\n\tb = c\n"},
+		{"[a\n", ErrSyntax, "[a\n"},
+		{"[a]\r\nb = x \\\r\n  y\r\nd = 1\r\n", nil, "[a]\r\nb = c\r\nd = 1\r\n"},
+		{"[a]\r\nb = x \\\r\n  \"y\" ; z\r\n", nil, "[a]\r\nb = c ; z\r\n"},
+	} {
+		if err := ioutil.WriteFile(fn, []byte(tt.src), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateFile(fn, "a", "", "b", "c"); !errors.Is(err, tt.err) {
+			t.Errorf("%q: got error %v, expected %v", tt.src, err, tt.err)
+		}
+		if b, _ := ioutil.ReadFile(fn); string(b) != tt.exp {
+			t.Errorf("%q: got %q, expected %q", tt.src, b, tt.exp)
+		}
+	}
+	if err := UpdateFile(fn, "a", "", "bad name", "c"); !errors.Is(err, errInvalidName) {
+		t.Errorf("got error %v for invalid name", err)
+	}
+}
+
+func TestUpdateFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(fn, []byte("[a]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(fn, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateFile(fn, "a", "", "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(fn); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("got file info %v, %v; expected mode 0640", fi, err)
+	}
+	if fis, err := ioutil.ReadDir(dir); err != nil || len(fis) != 1 {
+		t.Errorf("got %d files, %v; expected only the config", len(fis), err)
+	}
+}
+
+func TestSetInFile(t *testing.T) {
+	const src = `[core]
+	name = a ; comment
+	; doc
+	multi = 1
+	other = x
+	multi = 2
+[remote "my.origin"]
+	url = b
+`
+	fn := filepath.Join(t.TempDir(), "config")
+	for _, tt := range []struct {
+		key, value string // value "" to unset
+		old, new   string // the change to src
+		err        bool
+	}{
+		{"core.name", "c", "name = a", "name = c", false},
+		{"remote.my.origin.url", "d", "url = b", "url = d", false},
+		{"remote.my.origin.push", "e", "url = b\n", "url = b\n\tpush = e\n", false},
+		{"core.multi", "", "\tmulti = 1\n", "", false},
+		{"remote.my.origin.url", "", "\turl = b\n", "", false},
+		{"core.none", "", "", "", false},
+		{"core", "x", "", "", true},
+	} {
+		if err := ioutil.WriteFile(fn, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if tt.value == "" {
+			err = UnsetInFile(fn, tt.key)
+		} else {
+			err = SetInFile(fn, tt.key, tt.value)
+		}
+		if (err != nil) != tt.err {
+			t.Errorf("%q: got error %v", tt.key, err)
+		}
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := strings.Replace(src, tt.old, tt.new, 1)
+		if tt.key == "core.multi" {
+			exp = strings.Replace(exp, "\tmulti = 2\n", "", 1)
+		}
+		if string(b) != exp {
+			t.Errorf("%q: got\n%s\nexpected\n%s", tt.key, b, exp)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gcfg
+
+import (
+	"reflect"
+	"sort"
+
+	"gopkg.in/gcfg.v1/token"
+)
+
+// A Validator is a section struct or a config struct that validates its
+// values after reading; see the package documentation.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validate calls the Validate methods of the sections and subsections of cfg,
+// and then of cfg, that implement Validator, and collects the errors.
+func validate(c *collector, cfg interface{}, filename string) error {
+	cfgs, ok := cfg.(configs)
+	if !ok {
+		cfgs = configs{cfg}
+	}
+	for _, cfg := range cfgs {
+		vCfg := reflect.ValueOf(cfg).Elem()
+		for i := 0; i < vCfg.NumField(); i++ {
+			f := vCfg.Type().Field(i)
+			if f.PkgPath != "" { // unexported
+				continue
+			}
+			vSect := vCfg.Field(i)
+			isSubsect := vSect.Kind() == reflect.Map
+			pt := reflect.PtrTo(vSect.Type()) // section pointer type
+			if isSubsect {
+				pt = vSect.Type().Elem()
+			}
+			if !pt.Implements(validatorType) {
+				continue
+			}
+			sect, st := fieldName(f)
+			if st.any { // not a section
+				continue
+			}
+			l := loc{pos: token.Position{Filename: filename}, section: sect}
+			if !isSubsect {
+				if err := validateSection(c, vSect.Addr(), l); err != nil {
+					return err
+				}
+				continue
+			}
+			keys := vSect.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			for _, k := range keys {
+				sub := k.String()
+				l.subsection = &sub
+				err := validateSection(c, vSect.MapIndex(k), l)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if v, ok := cfg.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				if err := c.Collect(err); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateSection calls the Validate method of the section pointed to by
+// pSect at l, if it implements Validator, and collects the error.
+func validateSection(c *collector, pSect reflect.Value, l loc) error {
+	if pSect.IsNil() {
+		return nil
+	}
+	v, ok := pSect.Interface().(Validator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		return c.Collect(locErr{err: err, loc: l})
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package gcfg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedVersion is wrapped by the errors returned by Versions.Read for
+// data with a version that is not registered.
+var ErrUnsupportedVersion = errors.New("unsupported config version")
+
+// Versions selects the config struct to read into based on a version variable
+// in the configuration data, to ease the evolution of long-lived config files.
+// Each supported version is registered with a function returning a new config
+// struct for it, and optionally a migration to the next registered version;
+// data of an older version is then read into its struct and migrated to the
+// latest version.
+type Versions struct {
+	section, variable string
+	versions          []*configVersion
+}
+
+type configVersion struct {
+	name      string
+	newConfig func() interface{}
+	migrate   func(cfg interface{}) (interface{}, error)
+}
+
+// NewVersions returns a Versions that takes the version from the given
+// variable of the given section (without subsection); e.g. with section
+// "config" and variable "version", from
+//
+//	[config]
+//	version = 2
+//
+// The version variable is read by Versions itself, so the config structs don't
+// need to (but may) define it. Data without the version variable has version
+// "", which can be registered to support legacy unversioned data.
+func NewVersions(section, variable string) *Versions {
+	return &Versions{section: section, variable: variable}
+}
+
+// Register registers version, with newConfig returning a pointer to a new
+// config struct to read data of that version into, and migrate converting such
+// a config (possibly after other migrations) to a config of the version
+// registered next. Versions must be registered from oldest to latest; migrate
+// may be nil for the latest version, or for a version that is not migrated.
+// Register panics if the version is already registered.
+func (vs *Versions) Register(version string, newConfig func() interface{},
+	migrate func(cfg interface{}) (interface{}, error)) {
+	//
+	for _, v := range vs.versions {
+		if v.name == version {
+			panic(fmt.Errorf("version already registered: version %q",
+				version))
+		}
+	}
+	vs.versions = append(vs.versions, &configVersion{version, newConfig, migrate})
+}
+
+// Read reads gcfg formatted data from reader, with the behavior modified by
+// opts as for ReadWithOptions, into a new config struct for the version of the
+// data. It then applies the migrations registered for that and subsequent
+// versions, and returns the resulting config together with the version of the
+// data. If the version is not registered, the returned error wraps
+// ErrUnsupportedVersion.
+//
+// As for ReadWithOptions, the config is returned (without migrations applied)
+// along with the error if reading fails, and the returned error can be a list
+// of warnings (see FatalOnly).
+func (vs *Versions) Read(reader io.Reader, opts ...Option) (cfg interface{},
+	version string, err error) {
+	//
+	src, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	vHolder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Section",
+		Type: reflect.StructOf([]reflect.StructField{{
+			Name: "Version",
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag("gcfg:" + strconv.Quote(vs.variable)),
+		}}),
+		Tag: reflect.StructTag("gcfg:" + strconv.Quote(vs.section)),
+	}}))
+	// only the version is of interest; other errors are reported below,
+	// but options affecting the syntax apply
+	o := newOptions(opts)
+	vo := &options{appendSep: o.appendSep, lenientUTF8: o.lenientUTF8,
+		iniDialect: o.iniDialect}
+	if err := FatalOnly(readInto(vHolder.Interface(), "", src,
+		vo)); err != nil {
+		return nil, "", err
+	}
+	version = vHolder.Elem().Field(0).Field(0).String()
+	i := vs.index(version)
+	if i < 0 {
+		supported := make([]string, len(vs.versions))
+		for i, v := range vs.versions {
+			supported[i] = strconv.Quote(v.name)
+		}
+		return nil, version, fmt.Errorf("%w %q (supported: %s)",
+			ErrUnsupportedVersion, version, strings.Join(supported, ", "))
+	}
+	cfg = vs.versions[i].newConfig()
+	err = readInto(configs{vHolder.Interface(), cfg}, "", src, o)
+	if FatalOnly(err) != nil {
+		return cfg, version, err
+	}
+	for _, v := range vs.versions[i:] {
+		if v.migrate == nil {
+			break
+		}
+		var merr error
+		if cfg, merr = v.migrate(cfg); merr != nil {
+			return nil, version, fmt.Errorf("failed to migrate config "+
+				"from version %q: %w", v.name, merr)
+		}
+	}
+	return cfg, version, err
+}
+
+// ReadString is like Read, but reads from str.
+func (vs *Versions) ReadString(str string, opts ...Option) (cfg interface{},
+	version string, err error) {
+	//
+	return vs.Read(strings.NewReader(str), opts...)
+}
+
+func (vs *Versions) index(version string) int {
+	for i, v := range vs.versions {
+		if v.name == version {
+			return i
+		}
+	}
+	return -1
+}
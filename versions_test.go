@@ -0,0 +1,75 @@
+package gcfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type cVersion1 struct {
+	Server struct{ Addr string }
+}
+
+type cVersion2 struct {
+	Config struct{ Version int }
+	Server struct {
+		Host string
+		Port int
+	}
+}
+
+func newVersions() *Versions {
+	vs := NewVersions("config", "version")
+	vs.Register("", func() interface{} { return &cVersion1{} },
+		func(cfg interface{}) (interface{}, error) {
+			c1, c2 := cfg.(*cVersion1), &cVersion2{}
+			c2.Config.Version = 2
+			_, err := fmt.Sscanf(c1.Server.Addr, "%s %d", &c2.Server.Host,
+				&c2.Server.Port)
+			return c2, err
+		})
+	vs.Register("2", func() interface{} { return &cVersion2{} }, nil)
+	return vs
+}
+
+func TestVersionsRead(t *testing.T) {
+	exp := &cVersion2{}
+	exp.Config.Version = 2
+	exp.Server.Host, exp.Server.Port = "example.com", 80
+	for _, tt := range []struct {
+		gcfg    string
+		version string
+	}{
+		{"[server]\naddr=example.com 80", ""},
+		{"[config]\nversion=2\n[server]\nhost=example.com\nport=80", "2"},
+	} {
+		cfg, version, err := newVersions().ReadString(tt.gcfg)
+		if err != nil {
+			t.Errorf("%q: got error %v", tt.gcfg, err)
+			continue
+		}
+		if version != tt.version || !reflect.DeepEqual(cfg, exp) {
+			t.Errorf("%q: got %q %+v, wanted %q %+v", tt.gcfg, version, cfg,
+				tt.version, exp)
+		}
+	}
+}
+
+func TestVersionsReadErrors(t *testing.T) {
+	_, version, err := newVersions().ReadString("[config]\nversion=3\n")
+	if version != "3" || !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("got %q, error %v, wanted %v", version, err,
+			ErrUnsupportedVersion)
+	} else if exp := `unsupported config version "3" (supported: "", "2")`; err.Error() != exp {
+		t.Errorf("got error %q, wanted %q", err, exp)
+	}
+	_, _, err = newVersions().ReadString("[server]\naddr=example.com\n")
+	if err == nil {
+		t.Errorf("got no error for failed migration")
+	}
+	_, _, err = newVersions().ReadString("[config]\nversion=2\n[server]\nport=x\n")
+	if err == nil {
+		t.Errorf("got no error for invalid value")
+	}
+}
@@ -0,0 +1,850 @@
+package gcfg
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/gcfg.v1/types"
+)
+
+// Marshal returns the gcfg encoding of config, which must be a struct or a
+// pointer to a struct of the same form as accepted by the Read*Into
+// functions.
+//
+// Sections and variables are written in the order of the fields in the config
+// and section structs (or sorted by name; see SortNames). For a section with
+// subsections, the base section (the "" map key) is written first, followed by
+// the named subsections, sorted by name (see SubsectionOrder).
+// Section and variable names are taken from the "gcfg" struct tag or derived
+// from the field name as described in the package documentation.
+// Nil pointers are omitted, as are zero values of variables whose field has
+// the ",omitempty" struct tag option.
+//
+// Multi-valued variables are written as one line per value, repeating the
+// variable name (e.g. `multi = a` and `multi = b` for []string{"a", "b"}), so
+// that the output is read back to the same slice. Where necessary to get the
+// same result, the values are preceded by a "blank" line (the variable name
+// only) that resets the slice when read; e.g. for a non-nil pointer to an
+// empty slice, or for a subsection variable whose default value (see below) is
+// not empty.
+//
+// Values are written in a form that is read back to the same value: types
+// implementing encoding.TextMarshaler are encoded using MarshalText, and other
+// values using their default format (as produced by fmt.Sprint). String values
+// are quoted and escaped as needed.
+// Integers are written in decimal, unless the field has the ",int=mode" struct
+// tag option, in which case the base is chosen by the first character of mode;
+// e.g. ",int=hd" writes hexadecimal values (with the "0x" prefix), while
+// accepting decimal or hexadecimal values when reading.
+//
+// The encoding can be customized using opts; see Encoder.
+func Marshal(config interface{}, opts ...EncoderOption) ([]byte, error) {
+	var b bytes.Buffer
+	if err := NewEncoder(&b, opts...).Encode(config); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteInto writes the gcfg encoding of config to w, as returned by Marshal
+// with opts.
+func WriteInto(config interface{}, w io.Writer, opts ...EncoderOption) error {
+	return NewEncoder(w, opts...).Encode(config)
+}
+
+// An Encoder writes gcfg formatted data to an output stream.
+type Encoder struct {
+	w        io.Writer
+	sections int // number of sections written
+
+	commentDefaults bool
+	bools           BoolFormat
+	maxWidth        int
+	sortNames       bool
+	subLess         func(a, b string) bool
+	emptySections   EmptySectionMode
+	git             bool
+	redact          bool       // see Redact
+	redactFunc      RedactFunc // see Redact; or nil
+
+	open *loc // section begun by BeginSection; nil if none
+}
+
+// An EncoderOption configures the behavior of an Encoder.
+type EncoderOption func(*Encoder)
+
+// CommentDefaults returns an EncoderOption that makes the Encoder write
+// variables that are equal to their declared default value as comments; e.g.
+// `; port = 8080`. The resulting output documents the default values, and can
+// be edited by uncommenting and changing the lines as needed.
+//
+// The default value of a variable is declared using the ",default=value"
+// struct tag option. For subsections, the corresponding values in the
+// "default-<sectionname>" section (see the package documentation) are also
+// considered default values.
+func CommentDefaults() EncoderOption {
+	return func(e *Encoder) {
+		e.commentDefaults = true
+	}
+}
+
+// A BoolFormat specifies the pair of strings used for writing bool values.
+type BoolFormat int
+
+// BoolFormat values.
+const (
+	TrueFalse BoolFormat = iota // true / false
+	YesNo                       // yes / no
+	OnOff                       // on / off
+	OneZero                     // 1 / 0
+)
+
+var boolFormats = [...]struct {
+	name        string
+	true, false string
+}{
+	TrueFalse: {"truefalse", "true", "false"},
+	YesNo:     {"yesno", "yes", "no"},
+	OnOff:     {"onoff", "on", "off"},
+	OneZero:   {"10", "1", "0"},
+}
+
+// parseBoolFormat returns the BoolFormat with the name used in struct tags;
+// e.g. "yesno" for YesNo.
+func parseBoolFormat(name string) BoolFormat {
+	for f, bf := range boolFormats {
+		if bf.name == name {
+			return BoolFormat(f)
+		}
+	}
+	panic(fmt.Errorf("invalid bool format %q", name))
+}
+
+func (f BoolFormat) format(b bool) string {
+	if b {
+		return boolFormats[f].true
+	}
+	return boolFormats[f].false
+}
+
+// Bools returns an EncoderOption that sets the format for writing bool values;
+// the default is TrueFalse. The format can also be set for individual
+// variables using the struct tag option ",bool=format" where format is one of
+// "truefalse", "yesno", "onoff" or "10"; the struct tag takes precedence.
+func Bools(f BoolFormat) EncoderOption {
+	if f < 0 || int(f) >= len(boolFormats) {
+		panic(fmt.Errorf("invalid bool format %d", f))
+	}
+	return func(e *Encoder) {
+		e.bools = f
+	}
+}
+
+// MaxLineWidth returns an EncoderOption that makes the Encoder wrap values
+// longer than fit on a line of width characters (counting tabs as 8), using
+// backslash-newline continuations. Values are only wrapped after spaces, so
+// lines may still exceed width if a value has no suitable break points.
+// Values written as comments (see CommentDefaults) are not wrapped.
+func MaxLineWidth(width int) EncoderOption {
+	return func(e *Encoder) {
+		e.maxWidth = width
+	}
+}
+
+// SortNames returns an EncoderOption that makes the Encoder write sections
+// and variables sorted by name, rather than in the order of the struct
+// fields.
+func SortNames() EncoderOption {
+	return func(e *Encoder) {
+		e.sortNames = true
+	}
+}
+
+// SubsectionOrder returns an EncoderOption that makes the Encoder write the
+// named subsections of each section in the order defined by less, which
+// reports whether subsection a should be written before subsection b. The base
+// section is always written first.
+func SubsectionOrder(less func(a, b string) bool) EncoderOption {
+	return func(e *Encoder) {
+		e.subLess = less
+	}
+}
+
+// GitConfig returns an EncoderOption that makes the Encoder produce output
+// accepted by `git config --file`, e.g. for generating .gitconfig fragments.
+// In addition to the usual encoding, values containing tabs (or other
+// whitespace that git would convert to spaces) are quoted, and slices are
+// not reset using blank values, as a blank value means true for git. Names
+// that git doesn't accept (those containing non-ASCII letters or digits) and
+// dotted or indexed variables (see the package documentation) result in an
+// error wrapping ErrNotGitConfig.
+func GitConfig() EncoderOption {
+	return func(e *Encoder) {
+		e.git = true
+	}
+}
+
+// ErrNotGitConfig is wrapped by the errors returned when writing with the
+// GitConfig option data that git doesn't accept.
+var ErrNotGitConfig = errors.New("can't be represented in git config")
+
+// isGitName reports whether git accepts s as a section or variable name; that
+// is, if it consists of ASCII letters, digits and hyphens, and starts with a
+// letter.
+func isGitName(s string) bool {
+	for i, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9', r == '-':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// An EmptySectionMode specifies how sections whose values are all zero are
+// written.
+type EmptySectionMode int
+
+// EmptySectionMode values.
+const (
+	EmptyFull    EmptySectionMode = iota // header and all variables
+	EmptyHeader                          // header only
+	EmptySkip                            // nothing
+	EmptyComment                         // header as a comment; e.g. `; [section]`
+)
+
+// EmptySections returns an EncoderOption that sets how sections (and
+// subsections) whose struct fields all have zero values are written; the
+// default is EmptyFull, which writes them like any other section. Note that
+// with EmptySkip or EmptyComment, such subsections are not created when the
+// output is read back.
+func EmptySections(mode EmptySectionMode) EncoderOption {
+	if mode < EmptyFull || mode > EmptyComment {
+		panic(fmt.Errorf("invalid empty section mode %d", mode))
+	}
+	return func(e *Encoder) {
+		e.emptySections = mode
+	}
+}
+
+// NewEncoder returns a new Encoder that writes to w, configured by opts.
+func NewEncoder(w io.Writer, opts ...EncoderOption) *Encoder {
+	e := &Encoder{w: w}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// fields returns the indices of the exported fields of struct type t, in
+// the order in which they are written, and their names and tags.
+func (e *Encoder) fields(t reflect.Type) ([]int, []string, []tag) {
+	var idx []int
+	var names []string
+	var tags []tag
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		n, ft := fieldName(f)
+		idx, names, tags = append(idx, i), append(names, n), append(tags, ft)
+	}
+	if e.sortNames {
+		sort.Stable(byName{idx, names, tags})
+	}
+	return idx, names, tags
+}
+
+// byName sorts the fields returned by fields by name.
+type byName struct {
+	idx   []int
+	names []string
+	tags  []tag
+}
+
+func (s byName) Len() int           { return len(s.idx) }
+func (s byName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s byName) Swap(i, j int) {
+	s.idx[i], s.idx[j] = s.idx[j], s.idx[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.tags[i], s.tags[j] = s.tags[j], s.tags[i]
+}
+
+// Encode writes the gcfg encoding of config to the stream; see Marshal for
+// details.
+func (e *Encoder) Encode(config interface{}) error {
+	vCfg := reflect.ValueOf(config)
+	if vCfg.Kind() == reflect.Ptr {
+		vCfg = vCfg.Elem()
+	}
+	if vCfg.Kind() != reflect.Struct {
+		panic(fmt.Errorf("config must be a struct or a pointer to a struct"))
+	}
+	idx, names, tags := e.fields(vCfg.Type())
+	for n, i := range idx {
+		sect := names[n]
+		vSect := vCfg.Field(i)
+		if tags[n].any {
+			if err := e.encodeAnySections(vSect, tags[n]); err != nil {
+				return err
+			}
+			continue
+		}
+		switch vSect.Kind() {
+		case reflect.Struct:
+			if err := e.encodeSection(sect, nil, vSect, reflect.Value{}); err != nil {
+				return err
+			}
+		case reflect.Map:
+			vst := vSect.Type()
+			if vst.Key().Kind() != reflect.String ||
+				vst.Elem().Kind() != reflect.Ptr ||
+				vst.Elem().Elem().Kind() != reflect.Struct {
+				panic(fmt.Errorf("map field for section must have string keys and "+
+					" pointer-to-struct values: section %q", sect))
+			}
+			dflt, _ := fieldFold(vCfg, "default-"+sect, nil)
+			keys := vSect.MapKeys()
+			less := e.subLess
+			if less == nil {
+				less = func(a, b string) bool { return a < b }
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				a, b := keys[i].String(), keys[j].String()
+				if a == "" || b == "" {
+					return b != ""
+				}
+				return less(a, b)
+			})
+			for _, k := range keys {
+				pv := vSect.MapIndex(k)
+				if pv.IsNil() {
+					continue
+				}
+				var sub *string
+				if s := k.String(); s != "" {
+					sub = &s
+				}
+				if err := e.encodeSection(sect, sub, pv.Elem(), dflt); err != nil {
+					return err
+				}
+			}
+		default:
+			panic(fmt.Errorf("field for section must be a map or a struct: "+
+				"section %q", sect))
+		}
+	}
+	return nil
+}
+
+// encodeAnySections writes the entries of the map vAny of a config field with
+// the ",any" struct tag option as sections, sorted by name; the entries of
+// each section are written as with encodeAny.
+func (e *Encoder) encodeAnySections(vAny reflect.Value, t tag) error {
+	keys := vAny.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	t.any = false
+	for _, k := range keys {
+		sect, sub, hasSub := strings.Cut(k.String(), ".")
+		l := loc{section: sect}
+		if hasSub {
+			l.subsection = &sub
+		}
+		if !isName(sect) {
+			return locErr{err: errInvalidName, loc: l}
+		}
+		if err := e.writeHeader(sect, l.subsection, false); err != nil {
+			return err
+		}
+		if err := e.encodeAny(vAny.MapIndex(k), t, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSection writes the section sect (with the subsection sub, if not nil)
+// with the values in vSect. If valid, vDflt holds the default values for the
+// section.
+func (e *Encoder) encodeSection(sect string, sub *string, vSect,
+	vDflt reflect.Value) error {
+	//
+	mode := EmptyFull
+	if vSect.IsZero() {
+		mode = e.emptySections
+	}
+	if mode == EmptySkip {
+		return nil
+	}
+	if err := e.writeHeader(sect, sub, mode == EmptyComment); err != nil {
+		return err
+	}
+	if mode != EmptyFull {
+		return nil
+	}
+	idx, names, tags := e.fields(vSect.Type())
+	for n, i := range idx {
+		name, t := names[n], tags[n]
+		l := loc{section: sect, subsection: sub, variable: &name}
+		vVar := vSect.Field(i)
+		if t.any {
+			if err := e.encodeAny(vVar, t, l); err != nil {
+				return err
+			}
+			continue
+		}
+		if t.omitEmpty && isEmptyValue(vVar) {
+			continue
+		}
+		var vVarDflt reflect.Value
+		if vDflt.IsValid() {
+			vVarDflt = vDflt.Field(i)
+		}
+		comment := e.commentDefaults && (isDefault(vVar, t) ||
+			vVarDflt.IsValid() && reflect.DeepEqual(vVar.Interface(),
+				vVarDflt.Interface()))
+		// reset multi-valued variable if not empty by default
+		reset := vVarDflt.IsValid() && isMultiVal(vVar.Type()) &&
+			!isEmptyValue(reflect.Indirect(vVarDflt))
+		if err := e.encodeVar(name, vVar, t, comment, reset, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the header of the section sect (with the subsection
+// sub, if not nil), as a comment if comment is set.
+func (e *Encoder) writeHeader(sect string, sub *string, comment bool) error {
+	if e.git && !isGitName(sect) {
+		return locErr{err: fmt.Errorf("section name %w", ErrNotGitConfig),
+			loc: loc{section: sect, subsection: sub}}
+	}
+	h := "[" + sect
+	if sub != nil {
+		qs, err := quoteSubsection(*sub)
+		if err != nil {
+			return locErr{err: err, loc: loc{section: sect, subsection: sub}}
+		}
+		h += " " + qs
+	}
+	if e.sections > 0 {
+		if _, err := io.WriteString(e.w, "\n"); err != nil {
+			return err
+		}
+	}
+	e.sections++
+	if comment {
+		h = "; " + h
+	}
+	_, err := io.WriteString(e.w, h+"]\n")
+	return err
+}
+
+func (e *Encoder) encodeVar(name string, vVar reflect.Value, t tag, comment,
+	reset bool, l loc) error {
+	//
+	switch {
+	case e.git && (t.dotted || t.indexed):
+		return locErr{err: fmt.Errorf("dotted or indexed variable %w",
+			ErrNotGitConfig), loc: l}
+	case e.git && !isGitName(name):
+		return locErr{err: fmt.Errorf("variable name %w", ErrNotGitConfig),
+			loc: l}
+	}
+	if t.dotted {
+		return e.encodeDotted(name, vVar, t, comment, l)
+	}
+	if t.indexed {
+		t.indexed = false
+		for i := 0; i < vVar.Len(); i++ {
+			n := name + "." + strconv.Itoa(i)
+			li := l
+			li.variable = &n
+			if err := e.encodeVar(n, vVar.Index(i), t, comment, false, li); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	isMulti := isMultiVal(vVar.Type())
+	if isMulti && vVar.Kind() == reflect.Ptr {
+		if vVar.IsNil() {
+			return nil
+		}
+		vVar = vVar.Elem()
+		// a blank value allocates the slice
+		reset = reset || vVar.Len() == 0
+	}
+	if !isMulti {
+		return e.encodeValue(name, vVar, t, comment, l)
+	}
+	if reset && !e.git {
+		if err := e.encodeBlank(name, comment); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < vVar.Len(); i++ {
+		if err := e.encodeValue(name, vVar.Index(i), t, comment, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeDotted writes the entries of the map vMap as dotted variables
+// (name.key), sorted by key.
+func (e *Encoder) encodeDotted(name string, vMap reflect.Value, t tag,
+	comment bool, l loc) error {
+	//
+	keys := vMap.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	t.dotted = false
+	for _, k := range keys {
+		if !isKey(k.String()) {
+			return locErr{err: fmt.Errorf("invalid key %q", k.String()), loc: l}
+		}
+		n := name + "." + k.String()
+		lk := l
+		lk.variable = &n
+		if err := e.encodeVar(n, vMap.MapIndex(k), t, comment, false, lk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeAny writes the entries of the map vMap of a field with the ",any"
+// struct tag option as variables named by their keys, sorted by key.
+func (e *Encoder) encodeAny(vMap reflect.Value, t tag, l loc) error {
+	keys := vMap.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	t.any = false
+	for _, k := range keys {
+		n := k.String()
+		lk := l
+		lk.variable = &n
+		if !isVarName(n) {
+			return locErr{err: fmt.Errorf("invalid variable name %q", n), loc: lk}
+		}
+		if err := e.encodeVar(n, vMap.MapIndex(k), t, false, false, lk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isVarName reports whether s is a valid variable name, including the key of a
+// dotted or indexed variable, if any.
+func isVarName(s string) bool {
+	name, key, ok := strings.Cut(s, ".")
+	return isName(name) && (!ok || isKey(key))
+}
+
+// isKey reports whether s can be used as the key of a dotted variable; that
+// is, if it is a non-empty sequence of letters, digits and hyphens, starting
+// with a letter or digit.
+func isKey(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && (r != '-' || i == 0) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (e *Encoder) encodeValue(name string, vVal reflect.Value, t tag,
+	comment bool, l loc) error {
+	//
+	if vVal.Kind() == reflect.Interface && t.kind != "" {
+		if vVal.IsNil() {
+			return nil
+		}
+		vVal = vVal.Elem()
+	}
+	if vVal.Type().Name() == "" && vVal.Kind() == reflect.Ptr {
+		if vVal.IsNil() {
+			return nil
+		}
+		vVal = vVal.Elem()
+	}
+	s := redacted
+	if !e.redacts(l, t) {
+		var err error
+		if s, err = e.formatValue(vVal, t); err != nil {
+			return locErr{err: err, loc: l}
+		}
+	}
+	line := "\t" + name + " ="
+	if comment {
+		line = "\t; " + name + " ="
+	}
+	if s != "" {
+		line += " "
+		if e.maxWidth > 0 && !comment {
+			s = wrapValue(s, lineWidth(line), e.maxWidth)
+		}
+		line += s
+	}
+	_, err := io.WriteString(e.w, line+"\n")
+	return err
+}
+
+// lineWidth returns the display width of s, counting tabs as 8 columns.
+func lineWidth(s string) int {
+	return utf8.RuneCountInString(s) + 7*strings.Count(s, "\t")
+}
+
+// wrapValue wraps the (quoted as needed) value string v using backslash
+// continuations so that lines don't exceed width, if possible; start is the
+// width of the first line before the value. Lines are only broken after
+// spaces; where this happens within a quoted part of the value, the quotes are
+// closed before the break and reopened after it.
+func wrapValue(v string, start, width int) string {
+	var b strings.Builder
+	w, from := start, 0 // width of the current line, offset of its start in v
+	reopen := false     // whether the current line starts with a reopened quote
+	inQuote, esc := false, false
+	// offset in v and quote state after the last space on the line, if any
+	brk, brkQuote := -1, false
+	for i, r := range v {
+		switch {
+		case esc:
+			esc = false
+		case r == '\\':
+			esc = true
+		case r == '"':
+			inQuote = !inQuote
+		}
+		w++
+		// room needed at the end of the line for the continuation
+		cont := 1
+		if inQuote {
+			cont = 2
+		}
+		if w+cont > width && brk > from {
+			if reopen {
+				b.WriteString(`"`)
+			}
+			b.WriteString(v[from:brk])
+			if brkQuote {
+				b.WriteString(`"`)
+			}
+			b.WriteString("\\\n")
+			from, reopen, brk = brk, brkQuote, -1
+			w = utf8.RuneCountInString(v[from:i]) + 1
+			if reopen {
+				w++
+			}
+		}
+		if r == ' ' {
+			brk, brkQuote = i+1, inQuote
+		}
+	}
+	if reopen {
+		b.WriteString(`"`)
+	}
+	b.WriteString(v[from:])
+	return b.String()
+}
+
+// encodeBlank writes a "blank" value (the variable name only).
+func (e *Encoder) encodeBlank(name string, comment bool) error {
+	line := "\t" + name + "\n"
+	if comment {
+		line = "\t; " + name + "\n"
+	}
+	_, err := io.WriteString(e.w, line)
+	return err
+}
+
+// isDefault reports whether the value of single-valued variable vVar is equal
+// to the default value declared in its tag t.
+func isDefault(vVar reflect.Value, t tag) bool {
+	if t.dflt == nil || isMultiVal(vVar.Type()) {
+		return false
+	}
+	vt := vVar.Type()
+	if vt.Name() == "" && vt.Kind() == reflect.Ptr {
+		if vVar.IsNil() {
+			return false
+		}
+		vVar, vt = vVar.Elem(), vt.Elem()
+	}
+	pv := reflect.New(vt)
+	if err := setValue(pv.Interface(), false, *t.dflt, t); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(vVar.Interface(), pv.Elem().Interface())
+}
+
+// formatValue returns the value of v formatted as a (quoted if necessary)
+// value string, using the format options for the variable in its tag t.
+func (e *Encoder) formatValue(v reflect.Value, t tag) (string, error) {
+	var pv reflect.Value
+	if v.CanAddr() {
+		pv = v.Addr()
+	} else {
+		pv = reflect.New(v.Type())
+		pv.Elem().Set(v)
+	}
+	if b, ok := pv.Interface().(*big.Int); ok {
+		return formatInt(b, intBase(t.intMode)), nil
+	}
+	if a, ok := pv.Interface().(*mail.Address); ok {
+		return e.quoteValue(a.String())
+	}
+	if d, ok := pv.Interface().(*time.Duration); ok {
+		return d.String(), nil
+	}
+	if u, ok := pv.Interface().(*url.URL); ok {
+		return e.quoteValue(u.String())
+	}
+	if tm, ok := pv.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return e.quoteValue(string(b))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return e.quoteValue(v.String())
+	case reflect.Bool:
+		f := e.bools
+		if t.boolFormat != "" {
+			f = parseBoolFormat(t.boolFormat)
+		}
+		return f.format(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return formatInt(big.NewInt(v.Int()), intBase(t.intMode)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		b := new(big.Int).SetUint64(v.Uint())
+		return formatInt(b, intBase(t.intMode)), nil
+	}
+	if !scannable(v.Type()) {
+		return "", ErrUnsupportedType
+	}
+	return e.quoteValue(fmt.Sprint(v.Interface()))
+}
+
+// intBase returns the base for writing integers with the parsing mode in the
+// ",int=mode" struct tag option: the base corresponding to the first character
+// of mode, or 10 if mode is empty.
+func intBase(mode string) int {
+	if mode == "" {
+		return 10
+	}
+	switch intMode(mode[:1]) {
+	case types.Hex:
+		return 16
+	case types.Oct:
+		return 8
+	}
+	return 10
+}
+
+// formatInt returns b formatted in base, with the prefix required for parsing
+// it back with types.ParseInt: "0x" for hexadecimal and "0" for octal.
+func formatInt(b *big.Int, base int) string {
+	sign, abs := "", new(big.Int).Abs(b)
+	if b.Sign() < 0 {
+		sign = "-"
+	}
+	switch {
+	case base == 16:
+		return sign + "0x" + abs.Text(16)
+	case base == 8 && abs.Sign() != 0:
+		return sign + "0" + abs.Text(8)
+	}
+	return b.String()
+}
+
+// isEmptyValue reports whether v is a zero value, an empty slice or map, or a
+// nil pointer.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+var errNotRepresentable = errors.New("value contains CR, NUL or invalid " +
+	"UTF-8, which can't be represented")
+
+var valueEscapes = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`,
+	"\t", `\t`)
+
+// quoteValue returns s as a value string that is read back as s, quoted and
+// escaped if (and only if) necessary; that is, if s has leading or trailing
+// whitespace, or contains comment characters, double quotes, backslashes or
+// newlines. Strings containing carriage returns or NUL characters, or invalid
+// UTF-8 sequences can't be represented, and an error is returned.
+func quoteValue(s string) (string, error) {
+	if !utf8.ValidString(s) || strings.ContainsAny(s, "\r\x00") {
+		return "", errNotRepresentable
+	}
+	if s == "" {
+		return "", nil
+	}
+	first, last := s[0], s[len(s)-1]
+	if first != ' ' && first != '\t' && last != ' ' && last != '\t' &&
+		!strings.ContainsAny(s, ";#\"\\\n") {
+		return s, nil
+	}
+	return `"` + valueEscapes.Replace(s) + `"`, nil
+}
+
+// quoteValue is like the quoteValue function, also quoting values that git
+// would modify if the GitConfig option is set.
+func (e *Encoder) quoteValue(s string) (string, error) {
+	q, err := quoteValue(s)
+	if err != nil || !e.git || strings.HasPrefix(q, `"`) ||
+		!strings.ContainsAny(s, "\t\v\f") {
+		//
+		return q, err
+	}
+	return `"` + valueEscapes.Replace(s) + `"`, nil
+}
+
+var errSubsectionNotRepresentable = errors.New("subsection name contains " +
+	"newline, CR, NUL or invalid UTF-8, which can't be represented")
+
+var subsectionEscapes = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quoteSubsection returns the subsection name s quoted and escaped. Names
+// containing newlines, carriage returns or NUL characters, or invalid UTF-8
+// sequences can't be represented, and an error is returned.
+func quoteSubsection(s string) (string, error) {
+	if !utf8.ValidString(s) || strings.ContainsAny(s, "\n\r\x00") {
+		return "", errSubsectionNotRepresentable
+	}
+	return `"` + subsectionEscapes.Replace(s) + `"`, nil
+}
@@ -0,0 +1,653 @@
+package gcfg
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"net/mail"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+type cOmit struct{ Section cOmitS1 }
+type cOmitS1 struct {
+	Name  string   `gcfg:",omitempty"`
+	Int   int      `gcfg:",omitempty"`
+	Multi []string `gcfg:",omitempty"`
+	PName *string  `gcfg:",omitempty"`
+	Big   *big.Int `gcfg:",omitempty"`
+	PInt  *int     `gcfg:"p-int,omitempty"`
+	Bool  bool     `gcfg:",omitempty"`
+	Zero  int
+}
+
+var marshaltests = []struct {
+	id     string
+	config interface{}
+	exp    string
+}{
+	{"basic", &cBasic{Section: cBasicS1{Name: "value", Int: 1}},
+		"[section]\n\tname = value\n\tint = 1\n\n" +
+			"[hyphen-in-section]\n\thyphen-in-name =\n\n" +
+			"[exported]\n\n" +
+			"[tag-name]\n\tname =\n\tint = 0\n"},
+	{"unicode", cUni{X甲: cUniS1{X乙: "丙"}},
+		"[甲]\n\t乙 = 丙\n\n[xsection]\n\txname =\n"},
+	{"subsections", &cSubs{map[string]*cSubsS1{"b": {"x"}, "a": {"y"}, "": {"z"}}},
+		"[sub]\n\tname = z\n\n[sub \"a\"]\n\tname = y\n\n[sub \"b\"]\n\tname = x\n"},
+	{"subsections:escaped", &cSubs{map[string]*cSubsS1{`a "b"`: {"x"},
+		`c\d`: {"y"}, " ; #": {"z"}}},
+		"[sub \" ; #\"]\n\tname = z\n\n[sub \"a \\\"b\\\"\"]\n\tname = x\n\n" +
+			"[sub \"c\\\\d\"]\n\tname = y\n"},
+	{"omitempty:empty", &cOmit{}, "[section]\n\tzero = 0\n"},
+	{"omitempty:set", &cOmit{cOmitS1{"n", 1, []string{"a"}, newString(""),
+		big.NewInt(0), new(int), true, 0}},
+		"[section]\n\tname = n\n\tint = 1\n\tmulti = a\n\tpname =\n" +
+			"\tbig = 0\n\tp-int = 0\n\tbool = true\n\tzero = 0\n"},
+}
+
+func TestMarshal(t *testing.T) {
+	for _, tt := range marshaltests {
+		b, err := Marshal(tt.config)
+		if err != nil {
+			t.Errorf("%s: got error %v", tt.id, err)
+			continue
+		}
+		if string(b) != tt.exp {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tt.id, b, tt.exp)
+			continue
+		}
+		res := reflect.New(reflect.Indirect(reflect.ValueOf(tt.config)).Type())
+		if err := ReadStringInto(res.Interface(), string(b)); err != nil {
+			t.Errorf("%s: reading back: got error %v", tt.id, err)
+		}
+	}
+}
+
+func TestWriteInto(t *testing.T) {
+	for _, tt := range marshaltests {
+		var b bytes.Buffer
+		if err := WriteInto(tt.config, &b); err != nil {
+			t.Errorf("%s: got error %v", tt.id, err)
+			continue
+		}
+		if b.String() != tt.exp {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tt.id, b.String(), tt.exp)
+		}
+	}
+}
+
+func TestMarshalSubsectionNotRepresentable(t *testing.T) {
+	for _, sub := range []string{"a\nb", "a\rb", "a\x00b", "a\xffb"} {
+		cfg := &cSubs{map[string]*cSubsS1{sub: {"x"}}}
+		if _, err := Marshal(cfg); !errors.Is(err, errSubsectionNotRepresentable) {
+			t.Errorf("%q: got error %v, wanted %v", sub, err,
+				errSubsectionNotRepresentable)
+		}
+	}
+}
+
+func TestMarshalSortNames(t *testing.T) {
+	cfg := &cBasic{Section: cBasicS1{Name: "value", Int: 1}}
+	exp := "[exported]\n\n" +
+		"[hyphen-in-section]\n\thyphen-in-name =\n\n" +
+		"[section]\n\tint = 1\n\tname = value\n\n" +
+		"[tag-name]\n\tint = 0\n\tname =\n"
+	b, err := Marshal(cfg, SortNames())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+}
+
+func TestMarshalSubsectionOrder(t *testing.T) {
+	cfg := &cSubs{map[string]*cSubsS1{"a": {"y"}, "b": {"x"}, "": {"z"}}}
+	exp := "[sub]\n\tname = z\n\n[sub \"b\"]\n\tname = x\n\n" +
+		"[sub \"a\"]\n\tname = y\n"
+	b, err := Marshal(cfg, SubsectionOrder(func(a, b string) bool {
+		return a > b
+	}))
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+}
+
+var emptysectiontests = []struct {
+	mode EmptySectionMode
+	exp  string
+}{
+	{EmptyFull, "[section]\n\tname =\n\tint = 0\n\n[hyphen-in-section]\n" +
+		"\thyphen-in-name = x\n\n[exported]\n\n[tag-name]\n\tname =\n\tint = 0\n"},
+	{EmptyHeader, "[section]\n\n[hyphen-in-section]\n\thyphen-in-name = x\n\n" +
+		"[exported]\n\n[tag-name]\n"},
+	{EmptySkip, "[hyphen-in-section]\n\thyphen-in-name = x\n"},
+	{EmptyComment, "; [section]\n\n[hyphen-in-section]\n\thyphen-in-name = x\n\n" +
+		"; [exported]\n\n; [tag-name]\n"},
+}
+
+func TestMarshalEmptySections(t *testing.T) {
+	cfg := &cBasic{}
+	cfg.Hyphen_In_Section.Hyphen_In_Name = "x"
+	for _, tt := range emptysectiontests {
+		b, err := Marshal(cfg, EmptySections(tt.mode))
+		if err != nil {
+			t.Errorf("%d: got error %v", tt.mode, err)
+			continue
+		}
+		if string(b) != tt.exp {
+			t.Errorf("%d: got\n%s\nwanted\n%s", tt.mode, b, tt.exp)
+		}
+	}
+}
+
+func TestMarshalDotted(t *testing.T) {
+	cfg := &cDotted{}
+	cfg.Section.Labels = map[string]string{"team": "infra", "env": "prod"}
+	cfg.Section.Ports = map[string][]int{"http": {80, 8080}}
+	exp := "[section]\n\tlabels.env = prod\n\tlabels.team = infra\n" +
+		"\tports.http = 80\n\tports.http = 8080\n\tname =\n"
+	b, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	res := &cDotted{}
+	if err := ReadStringInto(res, string(b)); err != nil {
+		t.Fatalf("reading back: got error %v", err)
+	}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("reading back: got %+v, wanted %+v", res, cfg)
+	}
+	cfg.Section.Labels["not a key"] = ""
+	if _, err := Marshal(cfg); err == nil {
+		t.Errorf("invalid key: no error")
+	}
+}
+
+func TestMarshalIndexed(t *testing.T) {
+	cfg := &cIndexed{}
+	cfg.Section.Server = []string{"a", "b"}
+	cfg.Section.Multi = [][]string{{"x"}, {"y", "z"}}
+	exp := "[section]\n\tserver.0 = a\n\tserver.1 = b\n" +
+		"\tmulti.0 = x\n\tmulti.1 = y\n\tmulti.1 = z\n"
+	b, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	res := &cIndexed{}
+	if err := ReadStringInto(res, string(b)); err != nil {
+		t.Fatalf("reading back: got error %v", err)
+	}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("reading back: got %+v, wanted %+v", res, cfg)
+	}
+}
+
+func TestMarshalMailAddress(t *testing.T) {
+	cfg := &cMail{}
+	cfg.Section.From = &mail.Address{Name: "Alerts", Address: "alerts@example.com"}
+	cfg.Section.To = []*mail.Address{{Address: "a@example.com"},
+		{Name: "B. User", Address: "b@example.com"}}
+	exp := "[section]\n\tfrom = \"\\\"Alerts\\\" <alerts@example.com>\"\n" +
+		"\tto = <a@example.com>\n\tto = \"\\\"B. User\\\" <b@example.com>\"\n"
+	b, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	res := &cMail{}
+	if err := ReadStringInto(res, string(b)); err != nil {
+		t.Fatalf("reading back: got error %v", err)
+	}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("reading back: got %+v, wanted %+v", res, cfg)
+	}
+}
+
+type cDflt struct {
+	Server struct {
+		Host string `gcfg:",default=localhost"`
+		Port int    `gcfg:",default=8080"`
+		Dir  *string
+	}
+	Default_Profile cDfltS1
+	Profile         map[string]*cDfltS1
+}
+type cDfltS1 struct{ Color, Size string }
+
+func TestMarshalCommentDefaults(t *testing.T) {
+	cfg := &cDflt{Default_Profile: cDfltS1{"green", "large"},
+		Profile: map[string]*cDfltS1{"a": {"green", "small"}}}
+	cfg.Server.Host = "example.com"
+	cfg.Server.Port = 8080
+	exp := "[server]\n\thost = example.com\n\t; port = 8080\n\n" +
+		"[default-profile]\n\tcolor = green\n\tsize = large\n\n" +
+		"[profile \"a\"]\n\t; color = green\n\tsize = small\n"
+	b, err := Marshal(cfg, CommentDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+}
+
+type cMultiW struct {
+	M1 cMultiS1
+	M3 cMultiS3
+}
+
+type cMultiSub struct {
+	Default_Sub cMultiS1
+	Sub         map[string]*cMultiS1
+}
+
+var marshalmultitests = []struct {
+	id     string
+	config interface{}
+	exp    string
+	res    interface{} // expected result of reading back, if not config
+}{
+	{"nil", &cMultiW{},
+		"[m1]\n\n[m3]\n", nil},
+	{"values", &cMultiW{M1: cMultiS1{[]string{"a", "b"}},
+		M3: cMultiS3{newStringSlice("c", "")}},
+		"[m1]\n\tmulti = a\n\tmulti = b\n\n" +
+			"[m3]\n\tpmulti = c\n\tpmulti =\n", nil},
+	// empty slices are read back as nil slices
+	{"empty", &cMultiW{M1: cMultiS1{[]string{}}, M3: cMultiS3{newStringSlice()}},
+		"[m1]\n\n[m3]\n\tpmulti\n",
+		&cMultiW{M3: cMultiS3{new([]string)}}},
+	{"numeric", &cNum{N2: cNumS2{[]int{1, -2}, []*big.Int{big.NewInt(3)}}},
+		"[n1]\n\tint = 0\n\tintdho = 0\n\n" +
+			"[n2]\n\tmultiint = 1\n\tmultiint = -2\n\tmultibig = 3\n\n" +
+			"[n3]\n\tfilemode = 0\n", nil},
+	{"defaults", &cMultiSub{cMultiS1{[]string{"d"}},
+		map[string]*cMultiS1{"a": {[]string{"a"}}, "b": {}}},
+		"[default-sub]\n\tmulti = d\n\n" +
+			"[sub \"a\"]\n\tmulti\n\tmulti = a\n\n[sub \"b\"]\n\tmulti\n", nil},
+}
+
+func TestMarshalMultiValue(t *testing.T) {
+	for _, tt := range marshalmultitests {
+		b, err := Marshal(tt.config)
+		if err != nil {
+			t.Errorf("%s: got error %v", tt.id, err)
+			continue
+		}
+		if string(b) != tt.exp {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tt.id, b, tt.exp)
+			continue
+		}
+		res := reflect.New(reflect.TypeOf(tt.config).Elem()).Interface()
+		if err := ReadStringInto(res, string(b)); err != nil {
+			t.Errorf("%s: reading back: got error %v", tt.id, err)
+			continue
+		}
+		exp := tt.res
+		if exp == nil {
+			exp = tt.config
+		}
+		if !reflect.DeepEqual(res, exp) {
+			t.Errorf("%s: read back %#v, wanted %#v", tt.id, res, exp)
+		}
+	}
+}
+
+type cBoolFmt struct {
+	Section struct {
+		Default bool
+		YesNo   bool `gcfg:",bool=yesno"`
+		OnOff   bool `gcfg:",bool=onoff"`
+		OneZero bool `gcfg:",bool=10"`
+	}
+}
+
+func TestMarshalBools(t *testing.T) {
+	var cfg cBoolFmt
+	cfg.Section.Default, cfg.Section.OnOff = true, true
+	for _, tt := range []struct {
+		opts []EncoderOption
+		exp  string
+	}{
+		{nil, "[section]\n\tdefault = true\n\tyesno = no\n\tonoff = on\n\tonezero = 0\n"},
+		{[]EncoderOption{Bools(OneZero)}, "[section]\n\tdefault = 1\n\tyesno = no\n\tonoff = on\n\tonezero = 0\n"},
+		{[]EncoderOption{Bools(YesNo)}, "[section]\n\tdefault = yes\n\tyesno = no\n\tonoff = on\n\tonezero = 0\n"},
+	} {
+		b, err := Marshal(&cfg, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.exp {
+			t.Errorf("got\n%s\nwanted\n%s", b, tt.exp)
+		}
+		var res cBoolFmt
+		if err := ReadStringInto(&res, string(b)); err != nil || res != cfg {
+			t.Errorf("reading back: got %+v, %v; wanted %+v", res, err, cfg)
+		}
+	}
+}
+
+type cIntBase struct {
+	Section struct {
+		Dec   int
+		Hex   uint32   `gcfg:",int=hd"`
+		Oct   int      `gcfg:",int=o"`
+		NHex  int8     `gcfg:",int=h"`
+		Mode  uint32   `gcfg:",int=od"`
+		Big   *big.Int `gcfg:",int=h"`
+		Multi []int    `gcfg:",int=hdo"`
+	}
+}
+
+func TestMarshalIntBase(t *testing.T) {
+	var cfg cIntBase
+	cfg.Section.Dec, cfg.Section.Hex, cfg.Section.Oct = 10, 0xff, 0644
+	cfg.Section.NHex, cfg.Section.Mode = -0x10, 0
+	cfg.Section.Big = big.NewInt(-0xabc)
+	cfg.Section.Multi = []int{0x1, 0}
+	exp := "[section]\n\tdec = 10\n\thex = 0xff\n\toct = 0644\n\tnhex = -0x10\n" +
+		"\tmode = 0\n\tbig = -0xabc\n\tmulti = 0x1\n\tmulti = 0x0\n"
+	b, err := Marshal(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	var res cIntBase
+	if err := ReadStringInto(&res, string(b)); err != nil ||
+		!reflect.DeepEqual(res, cfg) {
+		t.Errorf("reading back: got %+v, %v; wanted %+v", res, err, cfg)
+	}
+}
+
+var quotetests = []struct {
+	val string
+	exp string
+	ok  bool
+}{
+	{"", "", true},
+	{"value", "value", true},
+	{"va lue", "va lue", true},
+	{"va\tlue", "va\tlue", true},
+	{"a=b", "a=b", true},
+	{"[x]", "[x]", true},
+	{" value", `" value"`, true},
+	{"value\t", `"value\t"`, true},
+	{"va;lue", `"va;lue"`, true},
+	{"va#lue", `"va#lue"`, true},
+	{`va"lue`, `"va\"lue"`, true},
+	{`va\lue`, `"va\\lue"`, true},
+	{"va\nlue", `"va\nlue"`, true},
+	{"va\rlue", "", false},
+	{"va\x00lue", "", false},
+	{"va\xfflue", "", false},
+}
+
+func TestQuoteValue(t *testing.T) {
+	for _, tt := range quotetests {
+		q, err := quoteValue(tt.val)
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("%q: got error %v, wanted %q", tt.val, err, tt.exp)
+		case !tt.ok && err == nil:
+			t.Errorf("%q: got %q, wanted error", tt.val, q)
+		case tt.ok && q != tt.exp:
+			t.Errorf("%q: got %q, wanted %q", tt.val, q, tt.exp)
+		}
+	}
+}
+
+// quoteTestString is a string generated from an alphabet of characters that
+// are significant for quoting.
+type quoteTestString string
+
+func (quoteTestString) Generate(r *rand.Rand, size int) reflect.Value {
+	alphabet := []rune(" \t\n\r\x00\"\\;#=[]ab甲")
+	s := make([]rune, r.Intn(size+1))
+	for i := range s {
+		s[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return reflect.ValueOf(quoteTestString(s))
+}
+
+// Marshal followed by read must return the original string for all strings
+// that can be represented, and Marshal must fail for all other strings.
+func TestMarshalStringRoundTrip(t *testing.T) {
+	roundTrip := func(s string) bool {
+		var cfg, res cBasic
+		cfg.Section.Name = s
+		b, err := Marshal(&cfg)
+		representable := utf8.ValidString(s) && !strings.ContainsAny(s, "\r\x00")
+		if err != nil {
+			return !representable
+		}
+		if err := ReadStringInto(&res, string(b)); err != nil {
+			t.Logf("%q: reading back %q: %v", s, b, err)
+			return false
+		}
+		return res.Section.Name == s
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+	err := quick.Check(func(s quoteTestString) bool {
+		return roundTrip(string(s))
+	}, &quick.Config{MaxCount: 10000})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarshalMaxLineWidth(t *testing.T) {
+	for _, tt := range []struct {
+		val string
+		exp string
+	}{
+		{"short value", "\tname = short value\n"},
+		{"the quick brown fox jumps over the lazy dog",
+			"\tname = the quick brown \\\nfox jumps over the lazy dog\n"},
+		{"the quick brown fox; jumps over the lazy dog",
+			"\tname = \"the quick \"\\\n\"brown fox; jumps over the \"\\\n" +
+				"\"lazy dog\"\n"},
+		{"unbreakable_value_longer_than_the_line_width",
+			"\tname = unbreakable_value_longer_than_the_line_width\n"},
+	} {
+		var cfg, res cBasic
+		cfg.Section.Name = tt.val
+		b, err := Marshal(&cfg, MaxLineWidth(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := "[section]\n" + tt.exp + "\tint = 0\n"
+		if !strings.HasPrefix(string(b), exp) {
+			t.Errorf("got\n%s\nwanted\n%s", b, exp)
+		}
+		if err := ReadStringInto(&res, string(b)); err != nil ||
+			res.Section.Name != tt.val {
+			t.Errorf("reading back: got %q, %v; wanted %q",
+				res.Section.Name, err, tt.val)
+		}
+	}
+}
+
+func TestMarshalGitConfig(t *testing.T) {
+	type sub struct {
+		URL   string
+		Fetch []string
+	}
+	cfg := &struct {
+		Core struct {
+			Editor     string
+			Whitespace string
+			Bare       bool
+		}
+		Remote         map[string]*sub
+		Default_Remote sub
+	}{}
+	cfg.Core.Editor = `vim -c "set tw=72"`
+	cfg.Core.Whitespace = "a\tb ; c"
+	cfg.Remote = map[string]*sub{`my "origin"\x`: {URL: "https://example.com/r",
+		Fetch: []string{"+refs/heads/*:refs/remotes/origin/*"}}}
+	cfg.Default_Remote.Fetch = []string{"x"}
+	b, err := Marshal(cfg, GitConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "[core]\n\teditor = \"vim -c \\\"set tw=72\\\"\"\n" +
+		"\twhitespace = \"a\\tb ; c\"\n\tbare = false\n" +
+		"\n[remote \"my \\\"origin\\\"\\\\x\"]\n" +
+		"\turl = https://example.com/r\n" +
+		"\tfetch = +refs/heads/*:refs/remotes/origin/*\n" +
+		"\n[default-remote]\n\turl =\n\tfetch = x\n"
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	fn := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(fn, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("git", "config", "--file", fn, "--list").Output()
+	if err != nil {
+		t.Fatalf("git config failed: %v", err)
+	}
+	expGit := "core.editor=vim -c \"set tw=72\"\ncore.whitespace=a\tb ; c\n" +
+		"core.bare=false\nremote.my \"origin\"\\x.url=https://example.com/r\n" +
+		"remote.my \"origin\"\\x.fetch=+refs/heads/*:refs/remotes/origin/*\n" +
+		"default-remote.url=\ndefault-remote.fetch=x\n"
+	if string(out) != expGit {
+		t.Errorf("git config read\n%s\nwanted\n%s", out, expGit)
+	}
+}
+
+func TestMarshalGitConfigErrors(t *testing.T) {
+	for _, cfg := range []interface{}{
+		&struct{ Séction struct{ Name string } }{},
+		&struct{ Section struct{ Nämé string } }{},
+		&struct {
+			Section struct {
+				Map map[string]string `gcfg:",dotted"`
+			}
+		}{},
+	} {
+		_, err := Marshal(cfg, GitConfig())
+		if !errors.Is(err, ErrNotGitConfig) {
+			t.Errorf("%T: got error %v, wanted %v", cfg, err, ErrNotGitConfig)
+		}
+	}
+}
+
+func init() {
+	RegisterRedactFunc(func(section, name string) bool {
+		return section == "api" && strings.HasPrefix(name, "token")
+	})
+}
+
+func TestMarshalRedact(t *testing.T) {
+	cfg := &struct {
+		API struct {
+			URL      string
+			Token    map[string]string `gcfg:",dotted"`
+			Password string            `gcfg:",secret"`
+			Keys     []string
+		}
+	}{}
+	cfg.API.URL = "https://example.com"
+	cfg.API.Token = map[string]string{"a": "t0k3n"}
+	cfg.API.Password = "s3cr3t"
+	cfg.API.Keys = []string{"k1", "k2"}
+	b, err := Marshal(cfg, Redact(func(section, name string) bool {
+		return name == "keys"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "[api]\n\turl = https://example.com\n\ttoken.a = <redacted>\n" +
+		"\tpassword = <redacted>\n\tkeys = <redacted>\n\tkeys = <redacted>\n"
+	if string(b) != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b, exp)
+	}
+	exp = "[api]\n\turl = https://example.com\n\ttoken.a = <redacted>\n" +
+		"\tpassword = <redacted>\n\tkeys = k1\n\tkeys = k2\n"
+	if got := Dump(cfg); got != exp {
+		t.Errorf("got\n%s\nwanted\n%s", got, exp)
+	}
+	// values are only redacted with the option
+	if b, _ := Marshal(cfg); !bytes.Contains(b, []byte("s3cr3t")) {
+		t.Errorf("got\n%s\nwanted unredacted values", b)
+	}
+}
+
+func TestEncoderStream(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for _, sub := range []string{"a", "b"} {
+		if err := e.BeginSection("sub", sub); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.WriteVar("name", "value "+sub); err != nil {
+			t.Fatal(err)
+		}
+		e.EndSection()
+	}
+	if err := e.BeginSection("m1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WriteVar("multi", []string{"x", "y"}); err != nil {
+		t.Fatal(err)
+	}
+	e.EndSection()
+	exp := "[sub \"a\"]\n\tname = value a\n\n" +
+		"[sub \"b\"]\n\tname = value b\n\n" +
+		"[m1]\n\tmulti = x\n\tmulti = y\n"
+	if b.String() != exp {
+		t.Errorf("got\n%s\nwanted\n%s", b.String(), exp)
+	}
+	res := &struct {
+		Sub map[string]*cSubsS1
+		M1  cMultiS1
+	}{}
+	if err := ReadStringInto(res, b.String()); err != nil {
+		t.Fatal(err)
+	}
+	if res.Sub["b"] == nil || res.Sub["b"].Name != "value b" ||
+		len(res.M1.Multi) != 2 {
+		t.Errorf("got %+v %+v", res.Sub, res.M1)
+	}
+}
+
+func TestEncoderStreamInvalidName(t *testing.T) {
+	e := NewEncoder(ioutil.Discard)
+	if err := e.BeginSection("1sect", ""); !errors.Is(err, errInvalidName) {
+		t.Errorf("section: got error %v, wanted %v", err, errInvalidName)
+	}
+	if err := e.BeginSection("sect", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WriteVar("a_b", 1); !errors.Is(err, errInvalidName) {
+		t.Errorf("variable: got error %v, wanted %v", err, errInvalidName)
+	}
+}